@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/4ndew/terminal-history-navigator/internal/backup"
+	"github.com/4ndew/terminal-history-navigator/internal/session"
+)
+
+// runBackup bundles the config, templates and state into a tar.gz archive
+func runBackup(args []string) int {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	configFlag := fs.String("config", "", "path to an alternate config file")
+	force := fs.Bool("force", false, "overwrite FILE if it exists")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: terminal-history-navigator backup [flags] FILE.tar.gz")
+		fmt.Fprintln(fs.Output(), "\nWrites the config, templates and saved state to an archive for restore.")
+		fs.PrintDefaults()
+	}
+	files := parseInterspersed(fs, args)
+	if len(files) != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	cfg, err := loadConfig(*configFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load config: %v\n", err)
+		return exitUsage
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if *force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	out, err := os.OpenFile(files[0], flags, 0600)
+	if os.IsExist(err) {
+		fmt.Fprintf(os.Stderr, "Error: %s already exists (use --force to overwrite)\n", files[0])
+		return exitError
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	names, err := backup.Write(out, backup.Locations{
+		Config:    cfg.Path(),
+		Templates: cfg.TemplatesPath,
+		State:     session.Dir(),
+	})
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(files[0])
+		fmt.Fprintf(os.Stderr, "Error: failed to write backup: %v\n", err)
+		return exitError
+	}
+
+	fmt.Printf("%d files written to %s\n", len(names), files[0])
+	for _, name := range names {
+		fmt.Printf("  %s\n", name)
+	}
+	return 0
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestBackupRestore backs up a config and restores it to another path
+func TestBackupRestore(t *testing.T) {
+	cfg := writeConfig(t, "exclude_patterns: []\n")
+	t.Setenv("XDG_STATE_HOME", "")
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	if code := runBackup([]string{"--config", cfg, archive}); code != 0 {
+		t.Fatalf("backup exited with %d", code)
+	}
+
+	restored := filepath.Join(t.TempDir(), "config.yaml")
+	if code := runRestore([]string{"--config", restored, archive}); code != 0 {
+		t.Fatalf("restore exited with %d", code)
+	}
+	want, err := os.ReadFile(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(restored); err != nil || string(got) != string(want) {
+		t.Errorf("restored config = %q, %v; want %q", got, err, want)
+	}
+
+	if code := runRestore([]string{"--config", restored, archive}); code != exitError {
+		t.Errorf("restore over existing files exited with %d, want %d", code, exitError)
+	}
+}
+
+func TestBackupRestoreExitCodes(t *testing.T) {
+	cfg := writeConfig(t, "exclude_patterns: []\n")
+	existing := filepath.Join(t.TempDir(), "backup.tar.gz")
+	if err := os.WriteFile(existing, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		run  func() int
+		want int
+	}{
+		{"backup without a file", func() int { return runBackup([]string{"--config", cfg}) }, exitUsage},
+		{"backup over an existing file", func() int { return runBackup([]string{"--config", cfg, existing}) }, exitError},
+		{"restore without a file", func() int { return runRestore(nil) }, exitUsage},
+		{"restore of a missing archive", func() int { return runRestore([]string{filepath.Join(t.TempDir(), "missing.tar.gz")}) }, exitError},
+		{"restore of an invalid archive", func() int { return runRestore([]string{existing}) }, exitError},
+	}
+	for _, tt := range tests {
+		if code := tt.run(); code != tt.want {
+			t.Errorf("%s exited with %d, want %d", tt.name, code, tt.want)
+		}
+	}
+}
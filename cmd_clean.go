@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/4ndew/terminal-history-navigator/internal/config"
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+	"github.com/4ndew/terminal-history-navigator/internal/logging"
+)
+
+// cleanSampleSize is the number of removed entries shown in the summary
+const cleanSampleSize = 10
+
+// removal is a history entry dropped by clean, with the reason
+type removal struct {
+	entry  history.ZshEntry
+	reason string
+}
+
+// runClean rewrites a zsh history file without excluded entries and,
+// optionally, duplicates
+func runClean(args []string) int {
+	fs := flag.NewFlagSet("clean", flag.ExitOnError)
+	configFlag := fs.String("config", "", "path to an alternate config file")
+	verbose := fs.Bool("verbose", false, "also print warnings to stderr")
+	file := fs.String("file", "", "zsh history file to clean")
+	dedupe := fs.Bool("dedupe", false, "collapse duplicate commands, keeping the newest")
+	applyExcludes := fs.Bool("apply-excludes", false, "drop commands matching exclude_patterns and clipboard.sensitive_patterns")
+	backup := fs.Bool("backup", false, "copy the file to FILE.bak-TIMESTAMP before writing")
+	dryRun := fs.Bool("dry-run", false, "only print what would be removed")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: terminal-history-navigator clean --file FILE [flags]")
+		fmt.Fprintln(fs.Output(), "\nRewrites a zsh history file in place. Close other shells first, or they")
+		fmt.Fprintln(fs.Output(), "may write their history back over the cleaned file.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	logging.SetVerbose(*verbose)
+
+	if *file == "" || (!*dedupe && !*applyExcludes) {
+		fs.Usage()
+		return 2
+	}
+	format, err := history.DetectFileFormat(*file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if format == "histdb" {
+		fmt.Fprintf(os.Stderr, "Error: %s is a zsh-histdb database; clean only supports zsh history files\n", *file)
+		return 2
+	}
+	if format == "bash" || format == "fish" || format == "nushell" {
+		fmt.Fprintf(os.Stderr, "Error: %s looks like a %s history file; clean only supports zsh\n", *file, format)
+		return 2
+	}
+
+	cfg, err := loadConfig(*configFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load config: %v\n", err)
+		return 2
+	}
+	logWarnings(cfg.Validate())
+
+	f, err := os.Open(*file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	entries, err := history.ReadZshEntries(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read %s: %v\n", *file, err)
+		return 2
+	}
+
+	var patterns []*regexp.Regexp
+	if *applyExcludes {
+		patterns = cleanPatterns(cfg)
+	}
+	kept, removed := cleanEntries(entries, patterns, *dedupe)
+
+	fmt.Printf("%d entries kept, %d removed\n", len(kept), len(removed))
+	for i, r := range removed {
+		if i == cleanSampleSize {
+			fmt.Printf("  ... and %d more\n", len(removed)-cleanSampleSize)
+			break
+		}
+		fmt.Printf("  - %s (%s)\n", truncate(flattenCommand(r.entry.Command), 60), r.reason)
+	}
+
+	if *dryRun || len(removed) == 0 {
+		return 0
+	}
+
+	if *backup {
+		path, err := backupFile(*file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to back up %s: %v\n", *file, err)
+			return 2
+		}
+		fmt.Printf("Backup written to %s\n", path)
+	}
+
+	var buf bytes.Buffer
+	if err := history.WriteZshEntries(&buf, kept); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if err := history.WriteFileAtomic(*file, buf.Bytes()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write %s: %v\n", *file, err)
+		return 2
+	}
+	return 0
+}
+
+// cleanPatterns compiles the exclude and sensitive patterns, skipping
+// invalid ones (config validation already warned about them)
+func cleanPatterns(cfg *config.Config) []*regexp.Regexp {
+	var patterns []*regexp.Regexp
+	for _, list := range [][]string{cfg.ExcludePatterns, cfg.Clipboard.SensitivePatterns} {
+		for _, pattern := range list {
+			if regex, err := regexp.Compile(pattern); err == nil {
+				patterns = append(patterns, regex)
+			}
+		}
+	}
+	return patterns
+}
+
+// cleanEntries drops entries matching any pattern and, with dedupe, all but
+// the newest occurrence of each command. Kept entries stay in file order.
+func cleanEntries(entries []history.ZshEntry, patterns []*regexp.Regexp, dedupe bool) (kept []history.ZshEntry, removed []removal) {
+	// Later entries are newer, so the last occurrence of a command wins
+	last := make(map[string]int)
+	if dedupe {
+		for i, entry := range entries {
+			last[entry.Command] = i
+		}
+	}
+
+	for i, entry := range entries {
+		if reason := matchPattern(entry.Command, patterns); reason != "" {
+			removed = append(removed, removal{entry, reason})
+			continue
+		}
+		if dedupe && last[entry.Command] != i {
+			removed = append(removed, removal{entry, "duplicate"})
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	return kept, removed
+}
+
+// matchPattern returns a description of the first pattern matching text,
+// or "" if none does
+func matchPattern(text string, patterns []*regexp.Regexp) string {
+	for _, pattern := range patterns {
+		if pattern.MatchString(text) {
+			return fmt.Sprintf("matches %q", pattern.String())
+		}
+	}
+	return ""
+}
+
+// truncate shortens s to at most maxLen runes with an ellipsis
+func truncate(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	return string(runes[:maxLen-3]) + "..."
+}
+
+// backupFile copies path to path.bak-TIMESTAMP and returns the copy's path
+func backupFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	backup := path + ".bak-" + time.Now().Format("20060102-150405")
+	return backup, os.WriteFile(backup, data, info.Mode().Perm())
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+)
+
+// writeConfig writes a config file into a temporary home directory, which
+// HOME points to for the rest of the test, and returns its path
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("HISTFILE", "")
+	t.Setenv("ZDOTDIR", "")
+	path := filepath.Join(home, "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeZshHistory writes entries to a zsh history file in a temporary
+// directory and returns its path
+func writeZshHistory(t *testing.T, entries []history.ZshEntry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := history.WriteZshEntries(&buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), ".zsh_history")
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readZshHistory reads the entries of a zsh history file
+func readZshHistory(t *testing.T, path string) []history.ZshEntry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entries, err := history.ReadZshEntries(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestCleanEntries(t *testing.T) {
+	entries := []history.ZshEntry{
+		{Command: "ls", Timestamp: 1, Extended: true},
+		{Command: "export TOKEN=abc", Timestamp: 2, Extended: true},
+		{Command: "ls", Timestamp: 3, Extended: true},
+		{Command: "git status", Timestamp: 4, Extended: true},
+	}
+	patterns := []*regexp.Regexp{regexp.MustCompile("TOKEN")}
+
+	kept, removed := cleanEntries(entries, patterns, true)
+	want := []history.ZshEntry{entries[2], entries[3]}
+	if !reflect.DeepEqual(kept, want) {
+		t.Errorf("kept = %+v, want %+v", kept, want)
+	}
+	if len(removed) != 2 || removed[0].entry != entries[0] || removed[0].reason != "duplicate" || removed[1].entry != entries[1] {
+		t.Errorf("removed = %+v, want the older ls and the token", removed)
+	}
+
+	kept, _ = cleanEntries(entries, nil, false)
+	if !reflect.DeepEqual(kept, entries) {
+		t.Errorf("kept without dedupe or patterns = %+v, want every entry", kept)
+	}
+}
+
+// TestCleanRoundTrip cleans a file with multibyte and multi-line entries
+// and checks the rest are written back unchanged
+func TestCleanRoundTrip(t *testing.T) {
+	cfg := writeConfig(t, "exclude_patterns:\n  - \"password\"\n")
+	entries := []history.ZshEntry{
+		{Command: "echo 日本語 🎉", Timestamp: 1700000000, Extended: true},
+		{Command: "mysql --password=hunter2", Timestamp: 1700000001, Extended: true},
+		{Command: "for f in *; do\n  echo \"ü $f\"\ndone", Timestamp: 1700000002, Duration: 3, Extended: true},
+		{Command: "echo 日本語 🎉", Timestamp: 1700000003, Duration: 1, Extended: true},
+		{Command: "cat <<EOF\nпривет\nEOF", Timestamp: 1700000004, Extended: true},
+	}
+	path := writeZshHistory(t, entries)
+
+	if code := runClean([]string{"--config", cfg, "--file", path, "--dedupe", "--apply-excludes", "--backup"}); code != 0 {
+		t.Fatalf("clean exited with %d", code)
+	}
+
+	want := []history.ZshEntry{entries[2], entries[3], entries[4]}
+	if got := readZshHistory(t, path); !reflect.DeepEqual(got, want) {
+		t.Errorf("cleaned entries = %+v, want %+v", got, want)
+	}
+
+	backups, err := filepath.Glob(path + ".bak-*")
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("backups = %q, %v; want one", backups, err)
+	}
+	if got := readZshHistory(t, backups[0]); !reflect.DeepEqual(got, entries) {
+		t.Errorf("backup entries = %+v, want the original %+v", got, entries)
+	}
+}
+
+func TestCleanDryRun(t *testing.T) {
+	cfg := writeConfig(t, "exclude_patterns: []\n")
+	entries := []history.ZshEntry{
+		{Command: "ls", Timestamp: 1700000000, Extended: true},
+		{Command: "ls", Timestamp: 1700000001, Extended: true},
+	}
+	path := writeZshHistory(t, entries)
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code := runClean([]string{"--config", cfg, "--file", path, "--dedupe", "--dry-run"}); code != 0 {
+		t.Fatalf("clean exited with %d", code)
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("dry run changed the file")
+	}
+}
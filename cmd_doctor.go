@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/4ndew/terminal-history-navigator/internal/config"
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+	"github.com/4ndew/terminal-history-navigator/internal/templates"
+	"github.com/4ndew/terminal-history-navigator/pkg/clipboard"
+)
+
+// doctor collects the results of environment checks
+type doctor struct {
+	problems int
+}
+
+// section prints a section heading
+func (d *doctor) section(title string) {
+	fmt.Printf("\n%s\n", title)
+}
+
+// ok reports a passed check
+func (d *doctor) ok(format string, args ...any) {
+	fmt.Printf("  ✓ %s\n", fmt.Sprintf(format, args...))
+}
+
+// warn reports a problem that doesn't stop the navigator from working
+func (d *doctor) warn(format string, args ...any) {
+	fmt.Printf("  ! %s\n", fmt.Sprintf(format, args...))
+}
+
+// fail reports a blocking problem
+func (d *doctor) fail(format string, args ...any) {
+	d.problems++
+	fmt.Printf("  ✗ %s\n", fmt.Sprintf(format, args...))
+}
+
+// info reports a detail without judging it
+func (d *doctor) info(format string, args ...any) {
+	fmt.Printf("    %s\n", fmt.Sprintf(format, args...))
+}
+
+// runDoctor checks the configuration and environment and reports problems.
+// It exits non-zero if any blocking problem was found.
+func runDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	configFlag := fs.String("config", "", "path to an alternate config file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: terminal-history-navigator doctor [flags]")
+		fmt.Fprintln(fs.Output(), "\nChecks history sources, templates, clipboard and terminal setup.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	d := &doctor{}
+
+	d.section("Config")
+	cfg, err := loadConfig(*configFlag)
+	if err != nil {
+		d.fail("failed to load config: %v", err)
+		return 1
+	}
+	d.ok("%s", cfg.Path())
+	for _, warning := range cfg.Validate() {
+		d.warn("%s", warning)
+	}
+
+	d.checkSources(cfg)
+	d.checkTemplates(cfg)
+	d.checkClipboard(cfg)
+	d.checkTerminal()
+
+	fmt.Println()
+	if d.problems > 0 {
+		fmt.Printf("%d problem(s) found\n", d.problems)
+		return 1
+	}
+	fmt.Println("No problems found")
+	return 0
+}
+
+// checkSources reports whether each history source can be read and how
+// the navigator will parse it
+func (d *doctor) checkSources(cfg *config.Config) {
+	d.section("History sources")
+
+	// Patterns are checked through the files they match
+	for _, source := range cfg.Sources {
+		if history.IsGlob(source) && len(history.ExpandSources([]string{source})) == 0 {
+			d.warn("%s: no matching files", source)
+		}
+	}
+
+	readable := 0
+	for _, source := range history.ExpandSources(cfg.Sources) {
+		if history.IsRemote(source) {
+			d.info("%s: read over SSH, not checked", source)
+			continue
+		}
+		lines, err := countLines(source)
+		if os.IsNotExist(err) {
+			d.warn("%s: not found", source)
+			continue
+		}
+		if err != nil {
+			d.warn("%s: %v", source, err)
+			continue
+		}
+
+		format, err := history.DetectFileFormat(source)
+		if err != nil {
+			d.warn("%s: %v", source, err)
+			continue
+		}
+		if format == "fish" {
+			d.warn("%s: fish history is not supported", source)
+			continue
+		}
+		if format == "histdb" {
+			if _, err := exec.LookPath(history.SQLiteCommand); err != nil {
+				d.warn("%s: zsh-histdb databases are read with %s, which is not installed", source, history.SQLiteCommand)
+				continue
+			}
+			readable++
+			d.ok("%s (zsh-histdb database)", source)
+			continue
+		}
+
+		readable++
+		d.ok("%s (format %s, %d lines)", source, format, lines)
+		if maxLines := cfg.Performance.MaxHistoryLines; lines > maxLines {
+			d.info("only the last %d lines are read (performance.max_history_lines)", maxLines)
+		}
+	}
+
+	if readable == 0 {
+		d.fail("no readable history sources, nothing will be shown")
+	}
+}
+
+// countLines counts the lines in a file
+func countLines(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines++
+	}
+	return lines, scanner.Err()
+}
+
+// checkTemplates reports whether the templates file parses
+func (d *doctor) checkTemplates(cfg *config.Config) {
+	d.section("Templates")
+
+	// Loading would create the defaults, which a diagnosis shouldn't do
+	if _, err := os.Stat(cfg.TemplatesPath); os.IsNotExist(err) {
+		d.warn("%s: not found, defaults will be created on the next run", cfg.TemplatesPath)
+		return
+	}
+
+	loaded, err := templates.NewLoader(cfg.TemplatesPath).Load()
+	if err != nil {
+		d.fail("%s: %v", cfg.TemplatesPath, err)
+		return
+	}
+	d.ok("%s (%d templates)", cfg.TemplatesPath, len(loaded))
+}
+
+// checkClipboard reports the detected clipboard backends and which one
+// Copy will use
+func (d *doctor) checkClipboard(cfg *config.Config) {
+	d.section("Clipboard")
+
+	if err := clipboard.SetBackend(cfg.Clipboard.Backend); err != nil {
+		d.fail("%v", err)
+		return
+	}
+	clipboard.SetFallbackFile(cfg.Clipboard.FallbackFile)
+
+	available := clipboard.AvailableBackends()
+	if len(available) == 0 {
+		d.fail("no clipboard backend available (install wl-clipboard, xclip or xsel, or set clipboard.fallback_file)")
+		return
+	}
+	d.info("detected: %s", strings.Join(available, ", "))
+	if runtime.GOOS != "windows" {
+		d.info("native: Windows only, not available on %s", runtime.GOOS)
+	}
+
+	selected := clipboard.Backend()
+	if cfg.Clipboard.Backend != "" && cfg.Clipboard.Backend != "auto" && !slices.Contains(available, selected) {
+		d.fail("configured backend %s is not available", selected)
+		return
+	}
+	if selected == "file" {
+		d.warn("no system clipboard, copies are written to %s", clipboard.FallbackFile())
+		return
+	}
+	d.ok("copying with %s", selected)
+}
+
+// checkTerminal reports terminal details that affect rendering and the
+// clipboard
+func (d *doctor) checkTerminal() {
+	d.section("Terminal")
+
+	if term := os.Getenv("TERM"); term == "" || term == "dumb" {
+		d.warn("TERM is %q, the TUI may not render correctly", term)
+	} else {
+		d.ok("TERM=%s", term)
+	}
+
+	switch {
+	case os.Getenv("WAYLAND_DISPLAY") != "":
+		d.info("display: Wayland (%s)", os.Getenv("WAYLAND_DISPLAY"))
+	case os.Getenv("DISPLAY") != "":
+		d.info("display: X11 (%s)", os.Getenv("DISPLAY"))
+	default:
+		d.info("display: none")
+	}
+	if os.Getenv("TMUX") != "" {
+		d.info("inside tmux")
+	}
+	if os.Getenv("SSH_TTY") != "" {
+		d.info("over SSH, consider clipboard.backend: osc52")
+	}
+}
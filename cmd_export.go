@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/4ndew/terminal-history-navigator/internal/export"
+	"github.com/4ndew/terminal-history-navigator/internal/logging"
+	"github.com/4ndew/terminal-history-navigator/internal/storage"
+	"github.com/4ndew/terminal-history-navigator/internal/timefmt"
+)
+
+// runExport writes every stored command to stdout in a structured format
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	configFlag := fs.String("config", "", "path to an alternate config file")
+	verbose := fs.Bool("verbose", false, "also print warnings to stderr")
+	format := fs.String("format", "json", "output format: "+strings.Join(export.Formats, ", "))
+	sinceFlag := fs.String("since", "", "only commands run at or after this date (YYYY-MM-DD, YYYY-MM-DD HH:MM or RFC 3339)")
+	untilFlag := fs.String("until", "", "only commands run at or before this date (YYYY-MM-DD, YYYY-MM-DD HH:MM or RFC 3339)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: terminal-history-navigator export [flags]")
+		fmt.Fprintln(fs.Output(), "\nWrites the deduplicated history to stdout, newest first.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	logging.SetVerbose(*verbose)
+
+	since, err := parseDate(*sinceFlag, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --since: %v\n", err)
+		return 2
+	}
+	until, err := parseDate(*untilFlag, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --until: %v\n", err)
+		return 2
+	}
+
+	cfg, err := loadConfig(*configFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load config: %v\n", err)
+		return 2
+	}
+	logWarnings(cfg.Validate())
+
+	store := storage.NewMemoryStorage()
+	if err := loadHistory(newReader(cfg), store); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read history: %v\n", err)
+		return 2
+	}
+
+	commands := storage.FilterByTime(store.GetAll(), since, until)
+	times, _ := timefmt.New(cfg.UI.TimeFormat)
+	if err := export.Write(os.Stdout, *format, commands, times); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	return 0
+}
+
+// parseDate parses a YYYY-MM-DD date, a YYYY-MM-DD HH:MM time or an RFC
+// 3339 timestamp in local time.
+// With endOfDay, a plain date means the end of that day.
+func parseDate(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not YYYY-MM-DD, YYYY-MM-DD HH:MM or RFC 3339", value)
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/4ndew/terminal-history-navigator/internal/config"
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+	"github.com/4ndew/terminal-history-navigator/internal/logging"
+)
+
+// runImport parses an external history file and appends the runs the
+// current history doesn't have yet to the import file, which is read like
+// another source
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	configFlag := fs.String("config", "", "path to an alternate config file")
+	verbose := fs.Bool("verbose", false, "also print warnings to stderr")
+	format := fs.String("format", "auto", "history format of the file: zsh, bash, tcsh, nushell or auto")
+	dryRun := fs.Bool("dry-run", false, "only report what would be merged")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: terminal-history-navigator import [flags] FILE")
+		fmt.Fprintln(fs.Output(), "\nMerges an external history file into the import file of the config.")
+		fmt.Fprintln(fs.Output(), "Importing the same file again adds nothing.")
+		fs.PrintDefaults()
+	}
+	files := parseInterspersed(fs, args)
+	logging.SetVerbose(*verbose)
+	if len(files) != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	switch *format {
+	case "zsh", "bash", "tcsh", "nushell", "auto":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid format %q (use zsh, bash, tcsh, nushell or auto)\n", *format)
+		return exitUsage
+	}
+
+	if _, err := os.Stat(files[0]); err != nil && !history.IsRemote(files[0]) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	cfg, err := loadConfig(*configFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load config: %v\n", err)
+		return exitUsage
+	}
+	logWarnings(cfg.Validate())
+
+	// History is re-read from the source files on every run, so imported
+	// commands need a file of their own to survive
+	if cfg.ImportFile == "" {
+		fmt.Fprintln(os.Stderr, "Error: import_file is empty in the config, so imported commands would be lost on the next run.")
+		fmt.Fprintf(os.Stderr, "Set import_file, or add %s to sources instead.\n", files[0])
+		return exitUsage
+	}
+
+	// Read every run of the current history, imports included, to tell
+	// which runs of the file are already there
+	reader := newReader(cfg)
+	reader.SetMaxLines(math.MaxInt)
+	reader.SetMaxCommandLength(0, false)
+	if _, err := reader.ReadHistory(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read history: %v\n", err)
+		return exitError
+	}
+	current := reader.Occurrences()
+
+	// Parse the whole file with the same filters as the configured sources
+	imported, err := readImport(cfg, files[0], *format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read %s: %v\n", files[0], err)
+		return exitError
+	}
+
+	runs, added, merged := importRuns(current, imported)
+	fmt.Printf("%d entries read from %s, %d already in the history\n", len(imported), files[0], len(imported)-len(runs))
+	fmt.Printf("%d new commands, %d merged duplicates\n", added, merged)
+	if *dryRun || len(runs) == 0 {
+		return 0
+	}
+
+	if err := appendImport(cfg.ImportFile, runs); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write %s: %v\n", cfg.ImportFile, err)
+		return exitError
+	}
+	fmt.Printf("%d entries added to %s\n", len(runs), cfg.ImportFile)
+	return 0
+}
+
+// readImport returns every run in a history file, newest first, read with
+// the filters of the configuration
+func readImport(cfg *config.Config, file, format string) ([]history.Command, error) {
+	importCfg := *cfg
+	importCfg.Sources = []string{file}
+	importCfg.ImportFile = ""
+	reader := newReader(&importCfg)
+	reader.SetMaxLines(math.MaxInt)
+	reader.SetMaxCommandLength(0, false)
+	reader.SetFormat(format)
+	if _, err := reader.ReadHistory(); err != nil {
+		return nil, err
+	}
+	if skipped := reader.Skipped(); len(skipped) > 0 {
+		return nil, skipped[0]
+	}
+	return reader.Occurrences(), nil
+}
+
+// runKey identifies a run of a command across history files
+type runKey struct {
+	text      string
+	timestamp int64
+}
+
+// keyOf returns the key of a run, the same for a run without a timestamp
+// wherever it is read
+func keyOf(cmd history.Command) runKey {
+	key := runKey{text: cmd.Text}
+	if !cmd.Timestamp.IsZero() {
+		key.timestamp = cmd.Timestamp.Unix()
+	}
+	return key
+}
+
+// importRuns returns the imported runs, oldest first, that the current
+// history doesn't have, and how many distinct commands among them are new
+// or would have their counts merged into an existing command. A run is
+// already there when the history has a run of the same command at the same
+// time; a file with more runs of a command than the history only adds the
+// extra ones, so importing it twice adds nothing the second time.
+func importRuns(current, imported []history.Command) (runs []history.Command, added, merged int) {
+	known := make(map[string]bool, len(current))
+	existing := make(map[runKey]int, len(current))
+	for _, cmd := range current {
+		known[cmd.Text] = true
+		existing[keyOf(cmd)]++
+	}
+
+	counted := make(map[string]bool)
+	for i := len(imported) - 1; i >= 0; i-- {
+		cmd := imported[i]
+		key := keyOf(cmd)
+		if existing[key] > 0 {
+			existing[key]--
+			continue
+		}
+		runs = append(runs, cmd)
+
+		if counted[cmd.Text] {
+			continue
+		}
+		counted[cmd.Text] = true
+		if known[cmd.Text] {
+			merged++
+		} else {
+			added++
+		}
+	}
+	return runs, added, merged
+}
+
+// appendImport appends runs to the import file in zsh history format,
+// creating it and its directory if needed
+func appendImport(path string, runs []history.Command) error {
+	var buf bytes.Buffer
+	if err := history.WriteZshEntries(&buf, zshEntries(runs)); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
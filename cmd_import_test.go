@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+)
+
+// writeImportConfig writes a config reading current, with its import file
+// in a temporary directory, and returns the config and import file paths
+func writeImportConfig(t *testing.T, current string) (cfg, importFile string) {
+	t.Helper()
+	importFile = filepath.Join(t.TempDir(), "imported.zsh_history")
+	cfg = writeConfig(t, fmt.Sprintf("sources:\n  - %q\nexclude_patterns: []\nimport_file: %q\n", current, importFile))
+	return cfg, importFile
+}
+
+func TestImportRuns(t *testing.T) {
+	at := func(seconds int64) time.Time { return time.Unix(1700000000+seconds, 0) }
+	current := []history.Command{
+		{Text: "make", Timestamp: at(5)},
+		{Text: "ls -la"},
+	}
+	imported := []history.Command{
+		{Text: "ls -la"},
+		{Text: "ls -la"},
+		{Text: "make", Timestamp: at(9)},
+		{Text: "go test ./...", Timestamp: at(7)},
+		{Text: "make", Timestamp: at(5)},
+		{Text: "go test ./...", Timestamp: at(3)},
+	}
+
+	runs, added, merged := importRuns(current, imported)
+	want := []history.Command{imported[5], imported[3], imported[2], imported[1]}
+	if !reflect.DeepEqual(runs, want) {
+		t.Errorf("runs = %+v, want %+v", runs, want)
+	}
+	if added != 1 || merged != 2 {
+		t.Errorf("added, merged = %d, %d; want 1, 2", added, merged)
+	}
+
+	runs, added, merged = importRuns(append(current, runs...), imported)
+	if len(runs) != 0 || added != 0 || merged != 0 {
+		t.Errorf("second import = %+v, %d, %d; want nothing", runs, added, merged)
+	}
+}
+
+// TestImportIdempotent imports a file twice and checks the second import
+// leaves the import file unchanged, and that the imported runs are read
+// with the rest of the history
+func TestImportIdempotent(t *testing.T) {
+	current := writeZshHistory(t, []history.ZshEntry{
+		{Command: "git status", Timestamp: 1700000000, Extended: true},
+	})
+	cfgPath, importFile := writeImportConfig(t, current)
+	old := writeZshHistory(t, []history.ZshEntry{
+		{Command: "git status", Timestamp: 1600000000, Extended: true},
+		{Command: "echo 日本語", Timestamp: 1600000001, Extended: true},
+		{Command: "for f in *; do\n  echo $f\ndone", Timestamp: 1600000002, Extended: true},
+		{Command: "git status", Timestamp: 1700000000, Extended: true},
+	})
+
+	if code := runImport([]string{"--config", cfgPath, old}); code != 0 {
+		t.Fatalf("import exited with %d", code)
+	}
+	want := []history.ZshEntry{
+		{Command: "git status", Timestamp: 1600000000, Extended: true},
+		{Command: "echo 日本語", Timestamp: 1600000001, Extended: true},
+		{Command: "for f in *; do\n  echo $f\ndone", Timestamp: 1600000002, Extended: true},
+	}
+	if got := readZshHistory(t, importFile); !reflect.DeepEqual(got, want) {
+		t.Fatalf("imported entries = %+v, want %+v", got, want)
+	}
+
+	before, err := os.ReadFile(importFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := runImport([]string{"--config", cfgPath, old}); code != 0 {
+		t.Fatalf("second import exited with %d", code)
+	}
+	after, err := os.ReadFile(importFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Errorf("second import changed the import file:\n%q\nwant\n%q", after, before)
+	}
+
+	cfg, err := loadConfig(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commands, err := newReader(cfg).ReadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]int)
+	for _, cmd := range commands {
+		counts[cmd.Text] = cmd.Count
+	}
+	if counts["git status"] != 2 || counts["echo 日本語"] != 1 || len(commands) != 3 {
+		t.Errorf("history after import = %v, want git status twice and both new commands", counts)
+	}
+}
+
+// TestImportBashIdempotent imports a file without timestamps twice
+func TestImportBashIdempotent(t *testing.T) {
+	current := writeZshHistory(t, nil)
+	cfgPath, importFile := writeImportConfig(t, current)
+	old := filepath.Join(t.TempDir(), ".bash_history")
+	if err := os.WriteFile(old, []byte("make\nmake\ncd /tmp\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := runImport([]string{"--config", cfgPath, "--format", "bash", old}); code != 0 {
+			t.Fatalf("import %d exited with %d", i, code)
+		}
+	}
+	want := []history.ZshEntry{{Command: "make"}, {Command: "make"}, {Command: "cd /tmp"}}
+	if got := readZshHistory(t, importFile); !reflect.DeepEqual(got, want) {
+		t.Errorf("imported entries = %+v, want %+v", got, want)
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	current := writeZshHistory(t, nil)
+	cfgPath, importFile := writeImportConfig(t, current)
+	old := writeZshHistory(t, []history.ZshEntry{{Command: "make", Timestamp: 1600000000, Extended: true}})
+
+	if code := runImport([]string{"--config", cfgPath, "--dry-run", old}); code != 0 {
+		t.Fatalf("import exited with %d", code)
+	}
+	if _, err := os.Stat(importFile); !os.IsNotExist(err) {
+		t.Errorf("dry run created the import file: %v", err)
+	}
+}
+
+func TestImportExitCodes(t *testing.T) {
+	current := writeZshHistory(t, nil)
+	cfgPath, _ := writeImportConfig(t, current)
+	old := writeZshHistory(t, []history.ZshEntry{{Command: "make"}})
+
+	if code := runImport([]string{"--config", cfgPath, "--format", "fish", old}); code != exitUsage {
+		t.Errorf("invalid format exited with %d, want %d", code, exitUsage)
+	}
+	if code := runImport([]string{"--config", cfgPath, filepath.Join(t.TempDir(), "missing")}); code != exitError {
+		t.Errorf("missing file exited with %d, want %d", code, exitError)
+	}
+
+	disabled := writeConfig(t, "exclude_patterns: []\nimport_file: \"\"\n")
+	if code := runImport([]string{"--config", disabled, old}); code != exitUsage {
+		t.Errorf("import without an import file exited with %d, want %d", code, exitUsage)
+	}
+}
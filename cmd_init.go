@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// zshInitScript is a zle widget that replaces ctrl+r with the navigator
+const zshInitScript = `# terminal-history-navigator ctrl+r widget for zsh
+_history_nav_widget() {
+  local selected
+  selected=$(@BIN@ --print --query "$BUFFER" </dev/tty)
+  if [[ $? -eq 0 && -n $selected ]]; then
+    BUFFER=$selected
+    CURSOR=${#BUFFER}
+  fi
+  zle reset-prompt
+}
+zle -N _history_nav_widget
+bindkey '^R' _history_nav_widget
+`
+
+// bashInitScript is a readline binding that replaces ctrl+r with the navigator
+const bashInitScript = `# terminal-history-navigator ctrl+r widget for bash
+_history_nav_widget() {
+  local selected
+  selected=$(@BIN@ --print --query "$READLINE_LINE" </dev/tty)
+  if [[ $? -eq 0 && -n $selected ]]; then
+    READLINE_LINE=$selected
+    READLINE_POINT=${#READLINE_LINE}
+  fi
+}
+bind -m emacs-standard -x '"\C-r": _history_nav_widget'
+bind -m vi-insert -x '"\C-r": _history_nav_widget'
+`
+
+// runInit prints shell integration code for eval in a shell rc file
+func runInit(args []string) int {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: terminal-history-navigator init zsh|bash")
+		fmt.Fprintln(fs.Output(), "\nPrints a ctrl+r widget that opens the navigator and places the selected")
+		fmt.Fprintln(fs.Output(), "command on the command line without running it. Add to your rc file:")
+		fmt.Fprintln(fs.Output(), "\n  ~/.zshrc:  eval \"$(terminal-history-navigator init zsh)\"")
+		fmt.Fprintln(fs.Output(), "  ~/.bashrc: eval \"$(terminal-history-navigator init bash)\"")
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	script, ok := initScript(fs.Arg(0), executablePath())
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unsupported shell %q (use zsh or bash)\n", fs.Arg(0))
+		return 2
+	}
+
+	fmt.Print(script)
+	return 0
+}
+
+// initScript returns the widget for a shell running the binary at path,
+// and false for an unsupported shell
+func initScript(shell, path string) (string, bool) {
+	var script string
+	switch shell {
+	case "zsh":
+		script = zshInitScript
+	case "bash":
+		script = bashInitScript
+	default:
+		return "", false
+	}
+	return strings.ReplaceAll(script, "@BIN@", shellQuote(path)), true
+}
+
+// executablePath returns the path of the running binary, so the widget
+// works even when the navigator is only reachable through an alias
+func executablePath() string {
+	path, err := os.Executable()
+	if err != nil {
+		return "terminal-history-navigator"
+	}
+	return path
+}
+
+// shellQuote quotes a string for safe use in a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
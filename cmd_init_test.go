@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestInitScriptGolden compares the widgets with testdata/init.<shell>.golden,
+// for a binary path that needs quoting
+func TestInitScriptGolden(t *testing.T) {
+	for _, shell := range []string{"zsh", "bash"} {
+		script, ok := initScript(shell, "/opt/my tools/it's/terminal-history-navigator")
+		if !ok {
+			t.Fatalf("initScript(%q) reported an unsupported shell", shell)
+		}
+
+		golden := filepath.Join("testdata", "init."+shell+".golden")
+		if *update {
+			if err := os.WriteFile(golden, []byte(script), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if script != string(want) {
+			t.Errorf("%s widget differs from %s:\n%s", shell, golden, script)
+		}
+	}
+
+	if _, ok := initScript("fish", "terminal-history-navigator"); ok {
+		t.Error("initScript accepted fish")
+	}
+}
+
+// TestInitScriptSyntax checks the widgets parse in the shells installed
+func TestInitScriptSyntax(t *testing.T) {
+	for _, shell := range []string{"zsh", "bash"} {
+		path, err := exec.LookPath(shell)
+		if err != nil {
+			t.Logf("%s is not installed, skipping its syntax check", shell)
+			continue
+		}
+		script, _ := initScript(shell, "/opt/my tools/it's/terminal-history-navigator")
+		if out, err := exec.Command(path, "-n", "-c", script).CombinedOutput(); err != nil {
+			t.Errorf("%s -n: %v\n%s", shell, err, out)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+	"github.com/4ndew/terminal-history-navigator/internal/logging"
+	"github.com/4ndew/terminal-history-navigator/internal/storage"
+)
+
+// runList prints recent commands to stdout, one per line, for piping into
+// other tools
+func runList(args []string) int {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	configFlag := fs.String("config", "", "path to an alternate config file")
+	verbose := fs.Bool("verbose", false, "also print warnings to stderr")
+	limit := fs.Int("n", 0, "maximum number of commands (0 = unlimited)")
+	byFrequency := fs.Bool("by-frequency", false, "order by usage count instead of recency")
+	failedOnly := fs.Bool("failed-only", false, "only commands whose last run exited non-zero")
+	dir := fs.String("dir", "", "only commands whose last run was in this directory (needs a history that records directories, e.g. zsh-histdb)")
+	format := fs.String("format", "plain", "output format: plain or tsv")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: terminal-history-navigator list [flags]")
+		fmt.Fprintln(fs.Output(), "\nPrints deduplicated commands, newest first, without headers or colors.")
+		fmt.Fprintln(fs.Output(), "Line breaks inside a command are printed as the two characters \\n.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	logging.SetVerbose(*verbose)
+
+	switch *format {
+	case "plain", "tsv":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid format %q (use plain or tsv)\n", *format)
+		return 2
+	}
+
+	cfg, err := loadConfig(*configFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load config: %v\n", err)
+		return 2
+	}
+	logWarnings(cfg.Validate())
+
+	store := storage.NewMemoryStorage()
+	if err := loadHistory(newReader(cfg), store); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read history: %v\n", err)
+		return 2
+	}
+
+	// Filter before limiting so -n counts only matching commands
+	fetch := *limit
+	if *failedOnly || *dir != "" {
+		fetch = 0
+	}
+
+	var commands []history.Command
+	if *byFrequency {
+		commands = store.GetByFrequency(cfg.UI.FrequencyMinCount, fetch)
+	} else if *failedOnly && *dir == "" {
+		commands = store.GetByExitStatus(true, *limit)
+	} else {
+		commands = store.GetRecent(fetch)
+	}
+
+	if *dir != "" {
+		abs, err := filepath.Abs(*dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		commands = storage.FilterByDirectory(commands, abs)
+	}
+	if *failedOnly {
+		commands = storage.FilterByExitStatus(commands, true)
+	}
+	if *limit > 0 && *limit < len(commands) {
+		commands = commands[:*limit]
+	}
+
+	if err := printCommands(commands, *format); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/4ndew/terminal-history-navigator/internal/export"
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+	"github.com/4ndew/terminal-history-navigator/internal/logging"
+)
+
+// runMerge combines several history files into one deduplicated file,
+// oldest command first
+func runMerge(args []string) int {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	configFlag := fs.String("config", "", "path to an alternate config file")
+	verbose := fs.Bool("verbose", false, "also print warnings to stderr")
+	output := fs.String("o", "", "file to write the merged history to")
+	format := fs.String("format", "zsh", "output format: zsh or json")
+	inputFormat := fs.String("input-format", "auto", "history format of the inputs: zsh, bash, tcsh, nushell or auto")
+	dryRun := fs.Bool("dry-run", false, "only report how many entries would be written")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: terminal-history-navigator merge -o FILE [flags] FILE...")
+		fmt.Fprintln(fs.Output(), "\nMerges history files into one, keeping each command once with its newest")
+		fmt.Fprintln(fs.Output(), "timestamp, ordered oldest to newest. The exclude patterns and noise filters")
+		fmt.Fprintln(fs.Output(), "of the config apply.")
+		fs.PrintDefaults()
+	}
+	files := parseInterspersed(fs, args)
+	logging.SetVerbose(*verbose)
+	if len(files) == 0 || (*output == "" && !*dryRun) {
+		fs.Usage()
+		return exitUsage
+	}
+
+	switch *format {
+	case "zsh", "json":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid format %q (use zsh or json)\n", *format)
+		return exitUsage
+	}
+	switch *inputFormat {
+	case "zsh", "bash", "tcsh", "nushell", "auto":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid input format %q (use zsh, bash, tcsh, nushell or auto)\n", *inputFormat)
+		return exitUsage
+	}
+
+	// The reader silently skips missing sources, which would hide typos
+	for _, file := range files {
+		if history.IsRemote(file) {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitError
+		}
+	}
+
+	cfg, err := loadConfig(*configFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load config: %v\n", err)
+		return exitUsage
+	}
+	logWarnings(cfg.Validate())
+
+	// Read every line, and never truncate: the result is meant to replace
+	// the inputs
+	mergeCfg := *cfg
+	mergeCfg.Sources = files
+	mergeCfg.ImportFile = ""
+	reader := newReader(&mergeCfg)
+	reader.SetMaxLines(math.MaxInt)
+	reader.SetMaxCommandLength(0, false)
+	if *inputFormat != "auto" {
+		reader.SetFormat(*inputFormat)
+	}
+	if _, err := reader.ReadHistory(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read history: %v\n", err)
+		return exitError
+	}
+	if skipped := reader.Skipped(); len(skipped) > 0 {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", skipped[0])
+		return exitError
+	}
+
+	occurrences := reader.Occurrences()
+	merged := mergeCommands(occurrences)
+	fmt.Printf("%d entries read from %d files\n", len(occurrences), len(files))
+	fmt.Printf("%d commands after merging duplicates\n", len(merged))
+	if *dryRun {
+		return 0
+	}
+
+	var buf bytes.Buffer
+	if *format == "json" {
+		err = export.WriteJSON(&buf, merged)
+	} else {
+		err = history.WriteZshEntries(&buf, zshEntries(merged))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	// The output may also be one of the inputs, e.g. when adding new
+	// histories to an existing archive, so never truncate it in place
+	_, err = os.Stat(*output)
+	switch {
+	case err == nil:
+		err = history.WriteFileAtomic(*output, buf.Bytes())
+	case errors.Is(err, os.ErrNotExist):
+		err = os.WriteFile(*output, buf.Bytes(), 0600)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write %s: %v\n", *output, err)
+		return exitError
+	}
+	fmt.Printf("Merged history written to %s\n", *output)
+	return 0
+}
+
+// mergeCommands deduplicates commands like ReadHistory: each command is kept
+// once with its counts summed and the metadata of its newest run. Commands
+// from different files are compared by timestamp, since positions are only
+// meaningful within one file. The result is ordered oldest first, with
+// commands that have no timestamp first in the order they were read.
+func mergeCommands(commands []history.Command) []history.Command {
+	index := make(map[string]int, len(commands))
+	var merged []history.Command
+
+	for _, cmd := range commands {
+		i, found := index[cmd.Text]
+		if !found {
+			index[cmd.Text] = len(merged)
+			merged = append(merged, cmd)
+			continue
+		}
+
+		existing := &merged[i]
+		count := existing.Count + cmd.Count
+		if cmd.Timestamp.After(existing.Timestamp) {
+			*existing = cmd
+		}
+		existing.Count = count
+	}
+
+	// Commands are read newest first, so reverse before the stable sort to
+	// keep commands without timestamps, and equal timestamps, oldest first
+	for i, j := 0, len(merged)-1; i < j; i, j = i+1, j-1 {
+		merged[i], merged[j] = merged[j], merged[i]
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Timestamp.Before(merged[j].Timestamp)
+	})
+	return merged
+}
+
+// zshEntries converts commands to zsh history entries, in extended format
+// for commands with a timestamp
+func zshEntries(commands []history.Command) []history.ZshEntry {
+	entries := make([]history.ZshEntry, 0, len(commands))
+	for _, cmd := range commands {
+		entry := history.ZshEntry{Command: cmd.Text}
+		if !cmd.Timestamp.IsZero() {
+			entry.Timestamp = cmd.Timestamp.Unix()
+			entry.Duration = int(cmd.Duration / time.Second)
+			entry.Extended = true
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/4ndew/terminal-history-navigator/internal/export"
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+)
+
+// mergeInputs are two zsh histories sharing some commands, with multibyte
+// and multi-line entries
+var mergeInputs = [][]history.ZshEntry{
+	{
+		{Command: "git status", Timestamp: 1700000000, Extended: true},
+		{Command: "echo 日本語 🎉", Timestamp: 1700000002, Duration: 1, Extended: true},
+		{Command: "for f in *; do\n  echo \"ü $f\"\ndone", Timestamp: 1700000004, Duration: 3, Extended: true},
+	},
+	{
+		{Command: "echo 日本語 🎉", Timestamp: 1700000001, Extended: true},
+		{Command: "cat <<EOF\nпривет\nEOF", Timestamp: 1700000003, Extended: true},
+		{Command: "git status", Timestamp: 1700000005, Duration: 2, Extended: true},
+	},
+}
+
+// mergedEntries is mergeInputs merged: each command once with its newest
+// run, oldest first
+var mergedEntries = []history.ZshEntry{
+	mergeInputs[0][1],
+	mergeInputs[1][1],
+	mergeInputs[0][2],
+	mergeInputs[1][2],
+}
+
+func TestMergeCommands(t *testing.T) {
+	commands := []history.Command{
+		{Text: "make", Timestamp: time.Unix(1700000005, 0), Count: 2, Source: "a"},
+		{Text: "ls -la", Count: 1, Source: "b"},
+		{Text: "make", Timestamp: time.Unix(1700000009, 0), Count: 1, Source: "b"},
+		{Text: "git status", Timestamp: time.Unix(1700000001, 0), Count: 3, Source: "a"},
+		{Text: "cd /tmp", Count: 1, Source: "b"},
+	}
+	merged := mergeCommands(commands)
+
+	var got []string
+	for _, cmd := range merged {
+		got = append(got, cmd.Text)
+	}
+	want := []string{"cd /tmp", "ls -la", "git status", "make"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("merged = %q, want %q", got, want)
+	}
+	if newest := merged[3]; newest.Count != 3 || newest.Source != "b" || newest.Timestamp.Unix() != 1700000009 {
+		t.Errorf("make = %+v, want count 3 from b at the newest timestamp", newest)
+	}
+}
+
+// TestMergeZshRoundTrip merges two files and checks the written file parses
+// back to the merged set, and that merging it again changes nothing
+func TestMergeZshRoundTrip(t *testing.T) {
+	cfg := writeConfig(t, "exclude_patterns: []\n")
+	first := writeZshHistory(t, mergeInputs[0])
+	second := writeZshHistory(t, mergeInputs[1])
+	out := filepath.Join(t.TempDir(), "merged_history")
+
+	if code := runMerge([]string{"--config", cfg, "-o", out, first, second}); code != 0 {
+		t.Fatalf("merge exited with %d", code)
+	}
+	if got := readZshHistory(t, out); !reflect.DeepEqual(got, mergedEntries) {
+		t.Fatalf("merged entries = %+v, want %+v", got, mergedEntries)
+	}
+
+	written, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again := filepath.Join(t.TempDir(), "merged_again")
+	if code := runMerge([]string{"--config", cfg, "-o", again, out}); code != 0 {
+		t.Fatalf("second merge exited with %d", code)
+	}
+	rewritten, err := os.ReadFile(again)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(written, rewritten) {
+		t.Errorf("merging the merged file gave\n%q\nwant\n%q", rewritten, written)
+	}
+}
+
+// TestMergeJSONRoundTrip checks JSON output parses back to the merged
+// commands with their summed counts
+func TestMergeJSONRoundTrip(t *testing.T) {
+	cfg := writeConfig(t, "exclude_patterns: []\n")
+	first := writeZshHistory(t, mergeInputs[0])
+	second := writeZshHistory(t, mergeInputs[1])
+	out := filepath.Join(t.TempDir(), "merged.json")
+
+	if code := runMerge([]string{"--config", cfg, "-o", out, "--format", "json", first, second}); code != 0 {
+		t.Fatalf("merge exited with %d", code)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	commands, err := export.ReadJSON(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(commands) != len(mergedEntries) {
+		t.Fatalf("commands = %+v, want %d", commands, len(mergedEntries))
+	}
+	for i, cmd := range commands {
+		want := mergedEntries[i]
+		count := 2
+		if want.Command == mergeInputs[1][1].Command || want.Command == mergeInputs[0][2].Command {
+			count = 1
+		}
+		if cmd.Text != want.Command || cmd.Timestamp.Unix() != want.Timestamp || cmd.Count != count {
+			t.Errorf("command %d = %q at %d count %d, want %q at %d count %d",
+				i, cmd.Text, cmd.Timestamp.Unix(), cmd.Count, want.Command, want.Timestamp, count)
+		}
+	}
+}
+
+func TestMergeExitCodes(t *testing.T) {
+	cfg := writeConfig(t, "exclude_patterns: []\n")
+	input := writeZshHistory(t, mergeInputs[0])
+
+	if code := runMerge([]string{"--config", cfg, "-o", filepath.Join(t.TempDir(), "out"), "--format", "csv", input}); code != exitUsage {
+		t.Errorf("invalid format exited with %d, want %d", code, exitUsage)
+	}
+	unwritable := filepath.Join(t.TempDir(), "missing", "out")
+	if code := runMerge([]string{"--config", cfg, "-o", unwritable, input}); code != exitError {
+		t.Errorf("failed write exited with %d, want %d", code, exitError)
+	}
+}
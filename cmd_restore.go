@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/4ndew/terminal-history-navigator/internal/backup"
+	"github.com/4ndew/terminal-history-navigator/internal/config"
+	"github.com/4ndew/terminal-history-navigator/internal/session"
+)
+
+// runRestore unpacks an archive written by backup into the config, templates
+// and state locations of this machine
+func runRestore(args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	configFlag := fs.String("config", "", "restore the config to this file instead of the default")
+	force := fs.Bool("force", false, "overwrite existing files")
+	dryRun := fs.Bool("dry-run", false, "only print where files would be restored")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: terminal-history-navigator restore [flags] FILE.tar.gz")
+		fmt.Fprintln(fs.Output(), "\nRestores a backup. Don't run it while the navigator is open.")
+		fs.PrintDefaults()
+	}
+	files := parseInterspersed(fs, args)
+	if len(files) != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	in, err := os.Open(files[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	archived, err := backup.Read(in)
+	in.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read %s: %v\n", files[0], err)
+		return exitError
+	}
+
+	loc, err := restoreLocations(*configFlag, archived)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	if *dryRun {
+		for _, file := range archived {
+			target := backup.Target(file.Name, loc)
+			if _, err := os.Lstat(target); err == nil {
+				target += " (exists)"
+			}
+			fmt.Printf("%s -> %s\n", file.Name, target)
+		}
+		return 0
+	}
+
+	written, err := backup.Restore(archived, loc, *force)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	fmt.Printf("%d files restored\n", len(written))
+	for _, path := range written {
+		fmt.Printf("  %s\n", path)
+	}
+	return 0
+}
+
+// restoreLocations returns where the parts of a backup go. Templates are
+// restored to the templates_path of the archived config.
+func restoreLocations(configPath string, archived []backup.File) (backup.Locations, error) {
+	if configPath == "" {
+		configPath = config.DefaultPath()
+	}
+	configPath, err := filepath.Abs(configPath)
+	if err != nil {
+		return backup.Locations{}, err
+	}
+
+	cfg := config.DefaultConfig()
+	if file, ok := backup.Find(archived, backup.ConfigName); ok {
+		cfg, err = config.Parse(file.Data)
+		if err != nil {
+			return backup.Locations{}, fmt.Errorf("archived config is invalid: %v", err)
+		}
+	}
+
+	return backup.Locations{
+		Config:    configPath,
+		Templates: cfg.TemplatesPath,
+		State:     session.Dir(),
+	}, nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/4ndew/terminal-history-navigator/internal/export"
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+	"github.com/4ndew/terminal-history-navigator/internal/logging"
+	"github.com/4ndew/terminal-history-navigator/internal/storage"
+)
+
+// runSearch prints commands matching a query to stdout without starting the TUI
+func runSearch(args []string) int {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	configFlag := fs.String("config", "", "path to an alternate config file")
+	verbose := fs.Bool("verbose", false, "also print warnings to stderr")
+	limit := fs.Int("limit", 0, "maximum number of results (0 = unlimited)")
+	fuzzy := fs.Bool("fuzzy", false, "match query words as letters in order, best match first")
+	sinceFlag := fs.String("since", "", "only runs at or after this time, listed oldest first (YYYY-MM-DD, YYYY-MM-DD HH:MM or RFC 3339)")
+	untilFlag := fs.String("until", "", "only runs at or before this time, listed oldest first (YYYY-MM-DD, YYYY-MM-DD HH:MM or RFC 3339)")
+	format := fs.String("format", "plain", "output format: plain, tsv or json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: terminal-history-navigator search [flags] QUERY...")
+		fmt.Fprintln(fs.Output(), "\nPrints matching commands, frequent and recent first (newest first with\nui.search_order: recent). With --since or --until, prints every run in that\nrange oldest first. Exits 1 when nothing matched.")
+		fs.PrintDefaults()
+	}
+	query := strings.Join(parseInterspersed(fs, args), " ")
+	logging.SetVerbose(*verbose)
+
+	switch *format {
+	case "plain", "tsv", "json":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid format %q (use plain, tsv or json)\n", *format)
+		return 2
+	}
+
+	since, err := parseDate(*sinceFlag, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --since: %v\n", err)
+		return 2
+	}
+	until, err := parseDate(*untilFlag, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --until: %v\n", err)
+		return 2
+	}
+
+	cfg, err := loadConfig(*configFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load config: %v\n", err)
+		return 2
+	}
+	logWarnings(cfg.Validate())
+
+	store := storage.NewMemoryStorage()
+	if err := loadHistory(newReader(cfg), store); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read history: %v\n", err)
+		return 2
+	}
+
+	search := store.Search
+	if *fuzzy {
+		search = store.SearchFuzzy
+	}
+	var results []history.Command
+	if !since.IsZero() || !until.IsZero() {
+		// A time range lists every run in it as a timeline
+		results = store.SearchTimeRange(query, since, until)
+		if *limit > 0 && len(results) > *limit {
+			results = results[:*limit]
+		}
+	} else if !*fuzzy && cfg.UI.SearchOrder == "frecency" {
+		// Rank every match, then keep the best
+		results = storage.SortByFrecency(search(query, 0), cfg.UI.FrecencyHalfLife, time.Now())
+		if *limit > 0 && len(results) > *limit {
+			results = results[:*limit]
+		}
+	} else {
+		results = search(query, *limit)
+	}
+	if len(results) == 0 {
+		return 1
+	}
+
+	if err := printCommands(results, *format); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	return 0
+}
+
+// parseInterspersed parses flags that may appear before, between or after
+// positional arguments, returning the positional arguments. Everything
+// after -- is positional, and so is anything after the first positional
+// argument that isn't a defined flag, so "search git push -force" searches
+// for -force.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var flags, positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			positional = append(positional, args[i+1:]...)
+			fs.Parse(flags)
+			return positional
+		case isFlag(fs, arg):
+			flags = append(flags, arg)
+			if takesValue(fs, arg) && i+1 < len(args) {
+				i++
+				flags = append(flags, args[i])
+			}
+		case len(positional) == 0 && len(arg) > 1 && arg[0] == '-':
+			// Let the flag set report unknown flags before the arguments
+			flags = append(flags, arg)
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	fs.Parse(flags)
+	return positional
+}
+
+// flagName returns the name of the flag arg sets, without dashes or value
+func flagName(arg string) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+	name, _, _ = strings.Cut(name, "=")
+	return name
+}
+
+// isFlag reports whether arg sets a flag of fs, or asks for help
+func isFlag(fs *flag.FlagSet, arg string) bool {
+	if len(arg) < 2 || arg[0] != '-' || arg == "--" {
+		return false
+	}
+	name := flagName(arg)
+	return fs.Lookup(name) != nil || name == "h" || name == "help"
+}
+
+// takesValue reports whether the flag arg sets is followed by its value,
+// as for "-limit 3" but not "-limit=3" or a boolean flag
+func takesValue(fs *flag.FlagSet, arg string) bool {
+	if strings.Contains(arg, "=") {
+		return false
+	}
+	f := fs.Lookup(flagName(arg))
+	if f == nil {
+		return false
+	}
+	boolean, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return !ok || !boolean.IsBoolFlag()
+}
+
+// printCommands writes commands to stdout in the given format
+func printCommands(commands []history.Command, format string) error {
+	switch format {
+	case "tsv":
+		for _, cmd := range commands {
+			fmt.Printf("%s\t%d\t%s\n", formatTimestamp(cmd.Timestamp), cmd.Count, flattenCommand(cmd.Text))
+		}
+	case "json":
+		return export.WriteJSON(os.Stdout, commands)
+	default:
+		for _, cmd := range commands {
+			fmt.Println(flattenCommand(cmd.Text))
+		}
+	}
+	return nil
+}
+
+// flattenCommand keeps a command on one output line by printing line
+// breaks as the two characters \n
+func flattenCommand(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.ReplaceAll(text, "\n", `\n`)
+}
+
+// formatTimestamp formats a timestamp as RFC 3339, or empty if unknown
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"slices"
+	"testing"
+)
+
+func TestParseInterspersed(t *testing.T) {
+	tests := []struct {
+		args       []string
+		positional []string
+		limit      int
+		fuzzy      bool
+	}{
+		{[]string{"docker", "build"}, []string{"docker", "build"}, 0, false},
+		{[]string{"-limit", "3", "docker", "--fuzzy", "build"}, []string{"docker", "build"}, 3, true},
+		{[]string{"docker", "-limit=3"}, []string{"docker"}, 3, false},
+		{[]string{"--", "foo", "-limit", "3"}, []string{"foo", "-limit", "3"}, 0, false},
+		{[]string{"-fuzzy", "foo", "--", "-fuzzy"}, []string{"foo", "-fuzzy"}, 0, true},
+		{[]string{"git", "push", "-force"}, []string{"git", "push", "-force"}, 0, false},
+		{[]string{"-limit", "-1", "-"}, []string{"-"}, -1, false},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("search", flag.ContinueOnError)
+		limit := fs.Int("limit", 0, "")
+		fuzzy := fs.Bool("fuzzy", false, "")
+
+		positional := parseInterspersed(fs, tt.args)
+		if !slices.Equal(positional, tt.positional) || *limit != tt.limit || *fuzzy != tt.fuzzy {
+			t.Errorf("parseInterspersed(%q) = %q, limit %d, fuzzy %v; want %q, limit %d, fuzzy %v",
+				tt.args, positional, *limit, *fuzzy, tt.positional, tt.limit, tt.fuzzy)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/4ndew/terminal-history-navigator/internal/logging"
+	"github.com/4ndew/terminal-history-navigator/internal/storage"
+)
+
+// statsBarWidth is the width of the longest bar in the hour histogram
+const statsBarWidth = 40
+
+// runStats prints a usage report to stdout without starting the TUI
+func runStats(args []string) int {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	configFlag := fs.String("config", "", "path to an alternate config file")
+	verbose := fs.Bool("verbose", false, "also print warnings to stderr")
+	top := fs.Int("top", 20, "number of entries in each ranking (0 = unlimited)")
+	jsonFlag := fs.Bool("json", false, "print the raw numbers as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: terminal-history-navigator stats [flags]")
+		fmt.Fprintln(fs.Output(), "\nPrints a report of command usage.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	logging.SetVerbose(*verbose)
+
+	cfg, err := loadConfig(*configFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load config: %v\n", err)
+		return 2
+	}
+	logWarnings(cfg.Validate())
+
+	store := storage.NewMemoryStorage()
+	reader := newReader(cfg)
+	if err := loadHistory(reader, store); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read history: %v\n", err)
+		return 2
+	}
+
+	var skipped []string
+	for _, err := range reader.Skipped() {
+		skipped = append(skipped, err.Error())
+		logging.Warnf("skipped source %v", err)
+	}
+
+	stats := store.Stats(*top)
+	if *jsonFlag {
+		data, err := json.MarshalIndent(struct {
+			storage.Stats
+			Skipped   []string `json:"skipped_sources,omitempty"`
+			Truncated bool     `json:"truncated,omitempty"` // Counts leave out lines before max_history_lines
+		}{stats, skipped, reader.Truncated()}, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		fmt.Println(string(data))
+		return 0
+	}
+
+	printStats(stats)
+	if reader.Truncated() {
+		fmt.Printf("\nCounts cover only the last %d lines of each history file (performance.max_history_lines).\n", cfg.Performance.MaxHistoryLines)
+	}
+	return 0
+}
+
+// printStats writes a human-readable usage report to stdout
+func printStats(stats storage.Stats) {
+	fmt.Printf("Commands:  %d total, %d unique\n", stats.Total, stats.Unique)
+	if stats.First != nil {
+		fmt.Printf("Period:    %s to %s\n",
+			stats.First.Local().Format("2006-01-02"), stats.Last.Local().Format("2006-01-02"))
+	}
+	if stats.WithExitCode > 0 {
+		fmt.Printf("Failures:  %.1f%% (%d of %d with exit codes)\n",
+			stats.FailureRate()*100, stats.Failed, stats.WithExitCode)
+	}
+
+	printRanking("Top commands", stats.TopCommands)
+	printRanking("Top programs", stats.TopFirstWords)
+
+	// The histogram is only meaningful when timestamps were recorded
+	peak := 0
+	for _, n := range stats.Hours {
+		peak = max(peak, n)
+	}
+	if peak == 0 {
+		return
+	}
+	fmt.Println("\nBy hour of day")
+	for hour, n := range stats.Hours {
+		width := n * statsBarWidth / peak
+		bar := strings.Repeat("█", width) + strings.Repeat(" ", statsBarWidth-width)
+		fmt.Printf("  %02d  %s %d\n", hour, bar, n)
+	}
+}
+
+// printRanking writes a titled list of entries with their counts
+func printRanking(title string, entries []storage.CountEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	fmt.Printf("\n%s\n", title)
+	for _, entry := range entries {
+		fmt.Printf("  %6d  %s\n", entry.Count, entry.Text)
+	}
+}
@@ -0,0 +1,250 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Names of the parts of a backup inside the archive
+const (
+	ConfigName    = "config.yaml" // The config file
+	TemplatesName = "templates"   // The templates file, or a directory of them
+	StateName     = "state"       // The state directory
+)
+
+// maxFileSize bounds the size of a single restored file
+const maxFileSize = 256 << 20
+
+// Locations are where the parts of a backup live on this machine
+type Locations struct {
+	Config    string // Config file
+	Templates string // Templates file or directory
+	State     string // State directory
+}
+
+// File is a regular file read from a backup
+type File struct {
+	Name string // Slash-separated path in the archive, e.g. state/session.yaml
+	Mode fs.FileMode
+	Data []byte
+}
+
+// Write writes the config file, templates and state as a gzipped tar
+// archive and returns the names of the files it contains. Missing
+// templates or state are skipped; log files in the state directory are
+// left out.
+func Write(w io.Writer, loc Locations) ([]string, error) {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	var names []string
+	add := func(name, file string) error {
+		if err := addFile(tw, name, file); err != nil {
+			return err
+		}
+		names = append(names, name)
+		return nil
+	}
+
+	if err := add(ConfigName, loc.Config); err != nil {
+		return nil, err
+	}
+
+	for _, part := range []struct{ name, root string }{
+		{TemplatesName, loc.Templates},
+		{StateName, loc.State},
+	} {
+		err := filepath.WalkDir(part.root, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				if file == part.root && errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			if part.name == StateName && isLog(entry.Name()) {
+				return nil
+			}
+
+			rel, err := filepath.Rel(part.root, file)
+			if err != nil {
+				return err
+			}
+			name := part.name
+			if rel != "." {
+				name = path.Join(part.name, filepath.ToSlash(rel))
+			}
+			return add(name, file)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return names, gz.Close()
+}
+
+// addFile writes a regular file to the archive under name
+func addFile(tw *tar.Writer, name, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     int64(info.Mode().Perm()),
+		Size:     int64(len(data)),
+		ModTime:  info.ModTime(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = tw.Write(data)
+	return err
+}
+
+// isLog reports whether a state file is a log, which is specific to the
+// machine it was written on
+func isLog(name string) bool {
+	return strings.HasSuffix(name, ".log") || strings.Contains(name, ".log.")
+}
+
+// Read reads the files of a backup archive. Entries that aren't regular
+// files are ignored, and names that would escape the restore locations
+// are rejected.
+func Read(r io.Reader) ([]File, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	defer gz.Close()
+
+	var files []File
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := checkName(header.Name); err != nil {
+			return nil, err
+		}
+		if header.Size > maxFileSize {
+			return nil, fmt.Errorf("%s is too large (%d bytes)", header.Name, header.Size)
+		}
+
+		data, err := io.ReadAll(io.LimitReader(tr, maxFileSize))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{
+			Name: header.Name,
+			Mode: fs.FileMode(header.Mode).Perm(),
+			Data: data,
+		})
+	}
+}
+
+// checkName rejects archive names that are absolute, contain .. or don't
+// belong to a part of the backup
+func checkName(name string) error {
+	if !filepath.IsLocal(filepath.FromSlash(name)) || path.Clean(name) != name {
+		return fmt.Errorf("unsafe path %q in archive", name)
+	}
+	if name == ConfigName || name == TemplatesName ||
+		strings.HasPrefix(name, TemplatesName+"/") || strings.HasPrefix(name, StateName+"/") {
+		return nil
+	}
+	return fmt.Errorf("unexpected file %q in archive", name)
+}
+
+// Find returns the file with the given archive name
+func Find(files []File, name string) (File, bool) {
+	for _, file := range files {
+		if file.Name == name {
+			return file, true
+		}
+	}
+	return File{}, false
+}
+
+// Target returns where an archived file is restored to
+func Target(name string, loc Locations) string {
+	switch {
+	case name == ConfigName:
+		return loc.Config
+	case name == TemplatesName:
+		return loc.Templates
+	case strings.HasPrefix(name, TemplatesName+"/"):
+		return filepath.Join(loc.Templates, filepath.FromSlash(strings.TrimPrefix(name, TemplatesName+"/")))
+	default:
+		return filepath.Join(loc.State, filepath.FromSlash(strings.TrimPrefix(name, StateName+"/")))
+	}
+}
+
+// Restore writes files to their locations and returns the paths written.
+// Unless force is set, nothing is written if any target already exists.
+func Restore(files []File, loc Locations, force bool) ([]string, error) {
+	targets := make([]string, len(files))
+	var existing []string
+	for i, file := range files {
+		if err := checkName(file.Name); err != nil {
+			return nil, err
+		}
+		targets[i] = Target(file.Name, loc)
+		if _, err := os.Lstat(targets[i]); err == nil {
+			existing = append(existing, targets[i])
+		}
+	}
+	if len(existing) == 1 && !force {
+		return nil, fmt.Errorf("%s already exists (use --force to overwrite)", existing[0])
+	}
+	if len(existing) > 1 && !force {
+		return nil, fmt.Errorf("%d files already exist (use --force to overwrite): %s", len(existing), strings.Join(existing, ", "))
+	}
+
+	for i, file := range files {
+		if err := os.MkdirAll(filepath.Dir(targets[i]), 0755); err != nil {
+			return targets[:i], err
+		}
+		// Don't write through a symlink planted where a file is restored
+		if info, err := os.Lstat(targets[i]); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			if err := os.Remove(targets[i]); err != nil {
+				return targets[:i], err
+			}
+		}
+		mode := file.Mode
+		if mode == 0 {
+			mode = 0644
+		}
+		if err := os.WriteFile(targets[i], file.Data, mode); err != nil {
+			return targets[:i], err
+		}
+	}
+	return targets, nil
+}
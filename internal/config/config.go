@@ -1,44 +1,142 @@
 package config
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+	"github.com/4ndew/terminal-history-navigator/internal/redact"
+	"github.com/4ndew/terminal-history-navigator/internal/storage"
+	"github.com/4ndew/terminal-history-navigator/internal/timefmt"
 	"gopkg.in/yaml.v3"
 )
 
+// colorPattern matches the colors accepted in the config: hex RGB or an
+// ANSI 256-color index
+var colorPattern = regexp.MustCompile(`^(#[0-9A-Fa-f]{6}|#[0-9A-Fa-f]{3}|[0-9]|[1-9][0-9]|1[0-9][0-9]|2[0-4][0-9]|25[0-5])$`)
+
 // Config represents the application configuration
 type Config struct {
 	Sources         []string    `yaml:"sources"`
 	ExcludePatterns []string    `yaml:"exclude_patterns"`
+	IncludePatterns []string    `yaml:"include_patterns"`
+	KeepPatterns    []string    `yaml:"keep_patterns"`
+	Dedupe          bool        `yaml:"dedupe"`
 	UI              UIConfig    `yaml:"ui"`
 	TemplatesPath   string      `yaml:"templates_path"`
+	PinsPath        string      `yaml:"pins_path"`
+	ImportFile      string      `yaml:"import_file"`
 	Performance     Performance `yaml:"performance"`
+	Filters         Filters     `yaml:"filters"`
+	Normalize       Normalize   `yaml:"normalize"`
+	Clipboard       Clipboard   `yaml:"clipboard"`
+	Security        Security    `yaml:"security"`
+	Secrets         Secrets     `yaml:"secrets"`
+	Log             Log         `yaml:"log"`
+
+	// AllowHistoryFileEdits lets x also remove commands from the history files
+	AllowHistoryFileEdits bool `yaml:"allow_history_file_edits"`
+
+	path    string // File the configuration was loaded from
+	created bool   // Whether the file was created with defaults by this load
 }
 
 // UIConfig represents UI-specific settings
 type UIConfig struct {
-	MaxItems       int    `yaml:"max_items"`
-	Theme          string `yaml:"theme"`
-	ShowTimestamps bool   `yaml:"show_timestamps"`
-	ShowFrequency  bool   `yaml:"show_frequency"`
+	MaxItems          int               `yaml:"max_items"`
+	Theme             string            `yaml:"theme"`
+	ShowTimestamps    bool              `yaml:"show_timestamps"`
+	ShowFrequency     bool              `yaml:"show_frequency"`
+	RestoreSession    bool              `yaml:"restore_session"`
+	StartMode         string            `yaml:"start_mode"`
+	StartQuery        string            `yaml:"start_query"`
+	SessionGap        time.Duration     `yaml:"session_gap"`
+	DirectoryBoost    bool              `yaml:"directory_boost"`
+	LineMode          string            `yaml:"line_mode"`
+	WrapSelected      bool              `yaml:"wrap_selected"`
+	ModeColors        map[string]string `yaml:"mode_colors"`
+	Scroll            string            `yaml:"scroll"`
+	Scrolloff         int               `yaml:"scrolloff"`
+	TypoTolerance     bool              `yaml:"typo_tolerance"`
+	FuzzySearch       bool              `yaml:"fuzzy_search"`
+	SearchOrder       string            `yaml:"search_order"`
+	FrecencyHalfLife  time.Duration     `yaml:"frecency_half_life"`
+	TimeFormat        string            `yaml:"time_format"`
+	FrequencyMinCount int               `yaml:"frequency_min_count"`
+	ASCIIOnly         bool              `yaml:"ascii_only"`
 }
 
 // Performance represents performance-related settings
 type Performance struct {
-	CacheEnabled    bool `yaml:"cache_enabled"`
-	MaxHistoryLines int  `yaml:"max_history_lines"`
+	CacheEnabled       bool          `yaml:"cache_enabled"`
+	CachePath          string        `yaml:"cache_path"`
+	MaxHistoryLines    int           `yaml:"max_history_lines"`
+	AutoRefreshSeconds int           `yaml:"auto_refresh_seconds"`
+	WatchFiles         bool          `yaml:"watch_files"`
+	MaxCommandLength   int           `yaml:"max_command_length"`
+	LongCommands       string        `yaml:"long_commands"`
+	MaxAgeDays         int           `yaml:"max_age_days"`
+	RemoteTimeout      time.Duration `yaml:"remote_timeout"`
+}
+
+// Filters represents the noise-filter thresholds for history commands
+type Filters struct {
+	MinLength            int           `yaml:"min_length"`
+	DropNumeric          bool          `yaml:"drop_numeric"`
+	DropBinary           bool          `yaml:"drop_binary"`
+	DropSpaced           bool          `yaml:"drop_spaced"`
+	DropFutureTimestamps bool          `yaml:"drop_future_timestamps"`
+	FutureSkew           time.Duration `yaml:"future_skew"`
+}
+
+// Normalize represents which differences between commands are ignored when
+// they are deduplicated
+type Normalize struct {
+	CollapseWhitespace bool `yaml:"collapse_whitespace"`
+	StripEnv           bool `yaml:"strip_env"`
+	LowercaseCommand   bool `yaml:"lowercase_command"`
+}
+
+// Clipboard represents clipboard settings
+type Clipboard struct {
+	Backend           string        `yaml:"backend"`
+	Timeout           time.Duration `yaml:"timeout"`
+	ClearAfterSeconds int           `yaml:"clear_after_seconds"`
+	SensitivePatterns []string      `yaml:"sensitive_patterns"`
+	FallbackFile      string        `yaml:"fallback_file"`
+}
+
+// Security represents how secrets in commands are masked for display
+type Security struct {
+	Redact         bool     `yaml:"redact"`
+	RedactPatterns []string `yaml:"redact_patterns"`
+	CopyRedacted   bool     `yaml:"copy_redacted"`
+}
+
+// Secrets represents how commands with likely secrets are treated when
+// history is read: "off", "redact" to store them with the secrets replaced,
+// or "drop" to leave them out
+type Secrets struct {
+	Mode string `yaml:"mode"`
+}
+
+// Log represents where warnings are logged
+type Log struct {
+	File      string `yaml:"file"`
+	MaxSizeKB int    `yaml:"max_size_kb"`
 }
 
 // DefaultConfig returns a configuration with default values
 func DefaultConfig() *Config {
 	homeDir, _ := os.UserHomeDir()
 	return &Config{
-		Sources: []string{
-			filepath.Join(homeDir, ".zsh_history"),
-			filepath.Join(homeDir, ".bash_history"),
-		},
+		Sources: defaultSources(homeDir),
 		ExcludePatterns: []string{
 			"^sudo su", // Only sudo su commands (not all sudo)
 			"password",
@@ -56,27 +154,109 @@ func DefaultConfig() *Config {
 			"^h$",
 		},
 		UI: UIConfig{
-			MaxItems:       1000,
-			Theme:          "dark",
-			ShowTimestamps: true,
-			ShowFrequency:  true,
+			MaxItems:          1000,
+			Theme:             "auto",
+			ShowTimestamps:    true,
+			TimeFormat:        timefmt.Default,
+			FrequencyMinCount: 2,
+			ShowFrequency:     true,
+			StartMode:         "history",
+			SessionGap:        30 * time.Minute,
+			SearchOrder:       "frecency",
+			FrecencyHalfLife:  storage.DefaultHalfLife,
+			DirectoryBoost:    true,
+			LineMode:          "wrap",
+			WrapSelected:      true,
+			ModeColors: map[string]string{
+				"history":   "#14B8A6",
+				"templates": "#F59E0B",
+				"search":    "#3B82F6",
+			},
+			Scroll:    "edge",
+			Scrolloff: 2,
 		},
 		TemplatesPath: filepath.Join(homeDir, ".config", "history-nav", "templates.yaml"),
+		PinsPath:      filepath.Join(homeDir, ".config", "history-nav", "pins.yaml"),
+		ImportFile:    filepath.Join(homeDir, ".local", "share", "history-nav", "imported.zsh_history"),
 		Performance: Performance{
-			CacheEnabled:    true,
-			MaxHistoryLines: 10000,
+			CacheEnabled:     true,
+			CachePath:        filepath.Join(homeDir, ".cache", "history-nav", "history.cache"),
+			MaxHistoryLines:  10000,
+			WatchFiles:       true,
+			RemoteTimeout:    10 * time.Second,
+			MaxCommandLength: 4096,
+			LongCommands:     "truncate",
+		},
+		Filters: Filters{
+			MinLength:            1,
+			DropNumeric:          true,
+			DropBinary:           true,
+			DropSpaced:           true,
+			DropFutureTimestamps: true,
+			FutureSkew:           time.Hour,
+		},
+		Dedupe: true,
+		Normalize: Normalize{
+			CollapseWhitespace: true,
+			StripEnv:           true,
+		},
+		Clipboard: Clipboard{
+			Backend: "auto",
+			Timeout: 2 * time.Second,
+			SensitivePatterns: []string{
+				"(?i)password",
+				"(?i)passwd",
+				"(?i)token",
+				"(?i)secret",
+				"(?i)bearer ",
+				"(?i)api[_-]?key",
+			},
+			FallbackFile: filepath.Join(homeDir, ".cache", "history-nav", "clipboard.txt"),
+		},
+		Security: Security{
+			RedactPatterns: slices.Clone(redact.DefaultPatterns),
+		},
+		Secrets: Secrets{
+			Mode: "off",
+		},
+		Log: Log{
+			MaxSizeKB: 512,
 		},
 	}
 }
 
 // Load loads configuration from the config file or creates default config
 func Load() (*Config, error) {
-	configPath := getConfigPath()
+	return load(DefaultPath())
+}
+
+// LoadFrom loads configuration from an explicit file path. Relative paths
+// resolve against the current directory. A missing file is created with
+// defaults only if its parent directory already exists.
+func LoadFrom(path string) (*Config, error) {
+	configPath, err := filepath.Abs(expandHome(path))
+	if err != nil {
+		return nil, err
+	}
 
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		configDir := filepath.Dir(configPath)
+		if _, err := os.Stat(configDir); err != nil {
+			return nil, fmt.Errorf("config directory %s does not exist", configDir)
+		}
+	}
+
+	return load(configPath)
+}
+
+// load reads the config at configPath, creating it with defaults if missing
+func load(configPath string) (*Config, error) {
 	// Check if config file exists
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		// Create default config
 		config := DefaultConfig()
+		config.path = configPath
+		config.created = true
 		err := config.Save()
 		if err != nil {
 			return nil, err
@@ -90,11 +270,28 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
-	config := DefaultConfig()
-	err = yaml.Unmarshal(data, config)
+	config, err := Parse(data)
 	if err != nil {
 		return nil, err
 	}
+	config.path = configPath
+	return config, nil
+}
+
+// Parse parses config file contents over the defaults without reading or
+// creating any file
+func Parse(data []byte) (*Config, error) {
+	config := DefaultConfig()
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+
+	// An empty list asks for whatever history files exist
+	if len(config.Sources) == 0 {
+		for _, source := range history.DetectSources() {
+			config.Sources = append(config.Sources, source.Path)
+		}
+	}
 
 	// Expand home directory in paths
 	config.expandPaths()
@@ -102,9 +299,197 @@ func Load() (*Config, error) {
 	return config, nil
 }
 
+// Path returns the file the configuration was loaded from
+func (c *Config) Path() string {
+	if c.path == "" {
+		return DefaultPath()
+	}
+	return c.path
+}
+
+// Created reports whether the config file didn't exist and was created
+// with defaults when it was loaded
+func (c *Config) Created() bool {
+	return c.created
+}
+
+// Validate checks configuration values, resetting invalid ones to their
+// defaults and returning a warning for each
+func (c *Config) Validate() []string {
+	var warnings []string
+
+	if c.UI.MaxItems < 0 {
+		warnings = append(warnings, fmt.Sprintf("invalid ui.max_items %d, using 1000 (0 means unlimited)", c.UI.MaxItems))
+		c.UI.MaxItems = 1000
+	}
+
+	if c.Performance.AutoRefreshSeconds < 0 {
+		warnings = append(warnings, fmt.Sprintf("invalid performance.auto_refresh_seconds %d, auto-refresh disabled", c.Performance.AutoRefreshSeconds))
+		c.Performance.AutoRefreshSeconds = 0
+	}
+
+	if c.Performance.MaxCommandLength < 0 {
+		warnings = append(warnings, fmt.Sprintf("invalid performance.max_command_length %d, using 4096 (0 means unlimited)", c.Performance.MaxCommandLength))
+		c.Performance.MaxCommandLength = 4096
+	}
+
+	if c.Performance.MaxAgeDays < 0 {
+		warnings = append(warnings, fmt.Sprintf("invalid performance.max_age_days %d, keeping all commands (0 means unlimited)", c.Performance.MaxAgeDays))
+		c.Performance.MaxAgeDays = 0
+	}
+
+	if c.Filters.MinLength < 0 {
+		warnings = append(warnings, fmt.Sprintf("invalid filters.min_length %d, using 1", c.Filters.MinLength))
+		c.Filters.MinLength = 1
+	}
+
+	if c.Filters.FutureSkew < 0 {
+		warnings = append(warnings, fmt.Sprintf("invalid filters.future_skew %s, using 1h", c.Filters.FutureSkew))
+		c.Filters.FutureSkew = time.Hour
+	}
+
+	if c.Performance.RemoteTimeout <= 0 {
+		warnings = append(warnings, fmt.Sprintf("invalid performance.remote_timeout %s, using 10s", c.Performance.RemoteTimeout))
+		c.Performance.RemoteTimeout = 10 * time.Second
+	}
+
+	switch c.Performance.LongCommands {
+	case "truncate", "skip":
+	case "":
+		c.Performance.LongCommands = "truncate"
+	default:
+		warnings = append(warnings, fmt.Sprintf("invalid performance.long_commands %q, using truncate", c.Performance.LongCommands))
+		c.Performance.LongCommands = "truncate"
+	}
+
+	if c.UI.FrequencyMinCount < 1 {
+		warnings = append(warnings, fmt.Sprintf("invalid ui.frequency_min_count %d, using 2", c.UI.FrequencyMinCount))
+		c.UI.FrequencyMinCount = 2
+	}
+
+	if c.UI.SessionGap <= 0 {
+		warnings = append(warnings, fmt.Sprintf("invalid ui.session_gap %s, using 30m", c.UI.SessionGap))
+		c.UI.SessionGap = 30 * time.Minute
+	}
+
+	switch c.UI.SearchOrder {
+	case "frecency", "recent":
+	case "":
+		c.UI.SearchOrder = "frecency"
+	default:
+		warnings = append(warnings, fmt.Sprintf("invalid ui.search_order %q, using frecency", c.UI.SearchOrder))
+		c.UI.SearchOrder = "frecency"
+	}
+
+	if c.UI.FrecencyHalfLife <= 0 {
+		warnings = append(warnings, fmt.Sprintf("invalid ui.frecency_half_life %s, using %s", c.UI.FrecencyHalfLife, storage.DefaultHalfLife))
+		c.UI.FrecencyHalfLife = storage.DefaultHalfLife
+	}
+
+	switch c.UI.StartMode {
+	case "history", "templates", "search":
+	case "":
+		c.UI.StartMode = "history"
+	default:
+		warnings = append(warnings, fmt.Sprintf("invalid ui.start_mode %q, using history", c.UI.StartMode))
+		c.UI.StartMode = "history"
+	}
+
+	if c.UI.TimeFormat == "" {
+		c.UI.TimeFormat = timefmt.Default
+	} else if _, err := timefmt.New(c.UI.TimeFormat); err != nil {
+		warnings = append(warnings, fmt.Sprintf("invalid ui.time_format: %v, using %s", err, timefmt.Default))
+		c.UI.TimeFormat = timefmt.Default
+	}
+
+	switch c.UI.Theme {
+	case "auto", "dark", "light":
+	case "":
+		c.UI.Theme = "auto"
+	default:
+		warnings = append(warnings, fmt.Sprintf("invalid ui.theme %q, using auto", c.UI.Theme))
+		c.UI.Theme = "auto"
+	}
+
+	switch c.UI.LineMode {
+	case "wrap", "truncate":
+	case "":
+		c.UI.LineMode = "wrap"
+	default:
+		warnings = append(warnings, fmt.Sprintf("invalid ui.line_mode %q, using wrap", c.UI.LineMode))
+		c.UI.LineMode = "wrap"
+	}
+
+	switch c.UI.Scroll {
+	case "center", "edge":
+	case "":
+		c.UI.Scroll = "edge"
+	default:
+		warnings = append(warnings, fmt.Sprintf("invalid ui.scroll %q, using edge", c.UI.Scroll))
+		c.UI.Scroll = "edge"
+	}
+
+	if c.UI.Scrolloff < 0 {
+		warnings = append(warnings, fmt.Sprintf("invalid ui.scrolloff %d, using 2", c.UI.Scrolloff))
+		c.UI.Scrolloff = 2
+	}
+
+	modes := make([]string, 0, len(c.UI.ModeColors))
+	for mode := range c.UI.ModeColors {
+		modes = append(modes, mode)
+	}
+	slices.Sort(modes)
+	for _, mode := range modes {
+		color := c.UI.ModeColors[mode]
+		switch mode {
+		case "history", "templates", "search", "sessions", "suggestions":
+		default:
+			warnings = append(warnings, fmt.Sprintf("unknown mode %q in ui.mode_colors, ignoring it", mode))
+			delete(c.UI.ModeColors, mode)
+			continue
+		}
+		if !colorPattern.MatchString(color) {
+			warnings = append(warnings, fmt.Sprintf("invalid ui.mode_colors.%s %q (use #RRGGBB or 0-255), using the default accent", mode, color))
+			delete(c.UI.ModeColors, mode)
+		}
+	}
+
+	if c.Clipboard.ClearAfterSeconds < 0 {
+		warnings = append(warnings, fmt.Sprintf("invalid clipboard.clear_after_seconds %d, clipboard will not be cleared", c.Clipboard.ClearAfterSeconds))
+		c.Clipboard.ClearAfterSeconds = 0
+	}
+	for _, pattern := range c.Clipboard.SensitivePatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			warnings = append(warnings, fmt.Sprintf("invalid clipboard.sensitive_patterns entry %q: %v", pattern, err))
+		}
+	}
+
+	if c.Log.MaxSizeKB < 0 {
+		warnings = append(warnings, fmt.Sprintf("invalid log.max_size_kb %d, using 512 (0 means unlimited)", c.Log.MaxSizeKB))
+		c.Log.MaxSizeKB = 512
+	}
+
+	for _, pattern := range c.Security.RedactPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			warnings = append(warnings, fmt.Sprintf("invalid security.redact_patterns entry %q: %v", pattern, err))
+		}
+	}
+
+	switch c.Secrets.Mode {
+	case "off", "redact", "drop":
+	case "":
+		c.Secrets.Mode = "off"
+	default:
+		warnings = append(warnings, fmt.Sprintf("invalid secrets.mode %q, using off", c.Secrets.Mode))
+		c.Secrets.Mode = "off"
+	}
+
+	return warnings
+}
+
 // Save saves the configuration to the config file
 func (c *Config) Save() error {
-	configPath := getConfigPath()
+	configPath := c.Path()
 
 	// Create config directory if it doesn't exist
 	configDir := filepath.Dir(configPath)
@@ -121,25 +506,192 @@ func (c *Config) Save() error {
 	return os.WriteFile(configPath, data, 0644)
 }
 
+// AddExcludePattern appends a pattern to exclude_patterns and writes it to
+// the config file, leaving the rest of the file (including comments) as is
+func (c *Config) AddExcludePattern(pattern string) error {
+	if _, err := regexp.Compile(pattern); err != nil {
+		return err
+	}
+
+	err := c.editFile(func(root *yaml.Node) {
+		value := &yaml.Node{Kind: yaml.ScalarNode, Value: pattern}
+		patterns := mappingValue(root, "exclude_patterns")
+		if patterns == nil {
+			root.Content = append(root.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "exclude_patterns"},
+				&yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{value}})
+			return
+		}
+		// An empty "exclude_patterns:" is a null scalar, not a sequence
+		makeSequence(patterns)
+		patterns.Content = append(patterns.Content, value)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.ExcludePatterns = append(c.ExcludePatterns, pattern)
+	return nil
+}
+
+// SetSources replaces sources and writes them to the config file, leaving
+// the rest of the file as is
+func (c *Config) SetSources(sources []string) error {
+	err := c.editFile(func(root *yaml.Node) {
+		list := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, source := range sources {
+			list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: source})
+		}
+		if existing := mappingValue(root, "sources"); existing != nil {
+			makeSequence(existing)
+			existing.Content = list.Content
+			return
+		}
+		root.Content = append([]*yaml.Node{{Kind: yaml.ScalarNode, Value: "sources"}, list}, root.Content...)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Sources = sources
+	return nil
+}
+
+// editFile applies edit to the top-level mapping of the config file and
+// writes it back, keeping comments and the order of keys
+func (c *Config) editFile(edit func(root *yaml.Node)) error {
+	configPath := c.Path()
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("%s is not a YAML mapping", configPath)
+	}
+
+	edit(doc.Content[0])
+
+	// Write with the two-space indent of the generated and example configs,
+	// atomically and keeping the file's permissions
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	return history.WriteFileAtomic(configPath, out.Bytes())
+}
+
+// makeSequence turns a node into a block sequence, keeping its items if it
+// already is one
+func makeSequence(node *yaml.Node) {
+	if node.Kind == yaml.SequenceNode {
+		node.Style = 0
+		return
+	}
+	node.Kind = yaml.SequenceNode
+	node.Tag = ""
+	node.Value = ""
+	node.Style = 0
+}
+
+// mappingValue returns the value node for key in a YAML mapping, or nil
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// defaultSources returns the history files read without a sources list:
+// the ones named by $HISTFILE and $ZDOTDIR when they exist, then the usual
+// zsh, bash and NuShell files
+func defaultSources(homeDir string) []string {
+	var sources []string
+	if histfile := os.Getenv("HISTFILE"); histfile != "" {
+		sources = append(sources, expandHome(histfile))
+	}
+	if zdotdir := os.Getenv("ZDOTDIR"); zdotdir != "" {
+		sources = append(sources, filepath.Join(zdotdir, ".zsh_history"))
+	}
+	sources = slices.DeleteFunc(sources, func(path string) bool {
+		info, err := os.Stat(path)
+		return err != nil || !info.Mode().IsRegular()
+	})
+
+	for _, path := range append([]string{
+		filepath.Join(homeDir, ".zsh_history"),
+		filepath.Join(homeDir, ".bash_history"),
+	}, nushellSources()...) {
+		if !slices.Contains(sources, path) {
+			sources = append(sources, path)
+		}
+	}
+	return sources
+}
+
+// nushellSources returns the NuShell history files that exist, preferring
+// the sqlite backend, which records more, when both do
+func nushellSources() []string {
+	for _, dir := range history.NushellDirs() {
+		for _, name := range []string{"history.sqlite3", "history.txt"} {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+				return []string{path}
+			}
+		}
+	}
+	return nil
+}
+
 // expandPaths expands ~ to home directory in file paths
 func (c *Config) expandPaths() {
-	homeDir, _ := os.UserHomeDir()
-
 	// Expand sources
 	for i, source := range c.Sources {
-		if strings.HasPrefix(source, "~/") {
-			c.Sources[i] = filepath.Join(homeDir, source[2:])
-		}
+		c.Sources[i] = expandHome(source)
 	}
 
 	// Expand templates path
-	if strings.HasPrefix(c.TemplatesPath, "~/") {
-		c.TemplatesPath = filepath.Join(homeDir, c.TemplatesPath[2:])
+	c.TemplatesPath = expandHome(c.TemplatesPath)
+
+	// Expand pins path
+	c.PinsPath = expandHome(c.PinsPath)
+
+	// Expand import file
+	c.ImportFile = expandHome(c.ImportFile)
+
+	// Expand cache path
+	c.Performance.CachePath = expandHome(c.Performance.CachePath)
+
+	// Expand clipboard fallback file
+	c.Clipboard.FallbackFile = expandHome(c.Clipboard.FallbackFile)
+
+	// Expand log file
+	c.Log.File = expandHome(c.Log.File)
+}
+
+// expandHome expands a leading ~/ to the home directory
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		homeDir, _ := os.UserHomeDir()
+		return filepath.Join(homeDir, path[2:])
 	}
+	return path
 }
 
-// getConfigPath returns the path to the configuration file
-func getConfigPath() string {
+// DefaultPath returns the path of the configuration file used when no
+// other file is given
+func DefaultPath() string {
 	homeDir, _ := os.UserHomeDir()
 	return filepath.Join(homeDir, ".config", "history-nav", "config.yaml")
 }
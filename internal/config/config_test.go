@@ -0,0 +1,154 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestAddExcludePatternKeepsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	original := `# History sources
+sources:
+  - /tmp/.zsh_history # main shell
+exclude_patterns:
+  - "^ls$"
+ui:
+  max_items: 100
+`
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.AddExcludePattern("^secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# History sources
+sources:
+  - /tmp/.zsh_history # main shell
+exclude_patterns:
+  - "^ls$"
+  - ^secret
+ui:
+  max_items: 100
+`
+	if string(data) != want {
+		t.Errorf("config after AddExcludePattern:\n%s\nwant:\n%s", data, want)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("config mode = %o, want 600", mode)
+	}
+
+	reloaded, err := LoadFrom(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.ExcludePatterns; len(got) != 2 || got[1] != "^secret" {
+		t.Errorf("reloaded exclude patterns = %q", got)
+	}
+}
+
+// TestDefaultSources checks which history files are read without a
+// sources list, for each of $HISTFILE and $ZDOTDIR, with HOME in a temp dir
+func TestDefaultSources(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	for _, dir := range []string{".config/zsh", ".config/empty", ".histdir"} {
+		if err := os.MkdirAll(filepath.Join(home, dir), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{".zsh_history", ".histfile", ".config/zsh/.zsh_history"} {
+		if err := os.WriteFile(filepath.Join(home, name), []byte("ls\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name              string
+		histfile, zdotdir string
+		want              []string
+	}{
+		{"neither set", "", "", []string{".zsh_history", ".bash_history"}},
+		{"HISTFILE", filepath.Join(home, ".histfile"), "", []string{".histfile", ".zsh_history", ".bash_history"}},
+		{"HISTFILE with ~", "~/.histfile", "", []string{".histfile", ".zsh_history", ".bash_history"}},
+		{"HISTFILE missing", filepath.Join(home, ".missing"), "", []string{".zsh_history", ".bash_history"}},
+		{"HISTFILE a directory", filepath.Join(home, ".histdir"), "", []string{".zsh_history", ".bash_history"}},
+		{"HISTFILE the usual file", filepath.Join(home, ".zsh_history"), "", []string{".zsh_history", ".bash_history"}},
+		{"ZDOTDIR", "", filepath.Join(home, ".config/zsh"), []string{".config/zsh/.zsh_history", ".zsh_history", ".bash_history"}},
+		{"ZDOTDIR without history", "", filepath.Join(home, ".config/empty"), []string{".zsh_history", ".bash_history"}},
+		{"both", filepath.Join(home, ".histfile"), filepath.Join(home, ".config/zsh"), []string{".histfile", ".config/zsh/.zsh_history", ".zsh_history", ".bash_history"}},
+	}
+	for _, tt := range tests {
+		t.Setenv("HISTFILE", tt.histfile)
+		t.Setenv("ZDOTDIR", tt.zdotdir)
+
+		var want []string
+		for _, name := range tt.want {
+			want = append(want, filepath.Join(home, name))
+		}
+		if got := defaultSources(home); !slices.Equal(got, want) {
+			t.Errorf("%s: defaultSources = %q, want %q", tt.name, got, want)
+		}
+		cfg, err := Parse([]byte("ui:\n  max_items: 100\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(cfg.Sources, want) {
+			t.Errorf("%s: sources without a list = %q, want %q", tt.name, cfg.Sources, want)
+		}
+	}
+}
+
+// TestFallbackFile checks the clipboard fallback file is on by default and
+// can be turned off
+func TestFallbackFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	cfg, err := Parse([]byte("sources: [/tmp/.zsh_history]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, ".cache", "history-nav", "clipboard.txt"); cfg.Clipboard.FallbackFile != want {
+		t.Errorf("default fallback file = %q, want %q", cfg.Clipboard.FallbackFile, want)
+	}
+
+	cfg, err = Parse([]byte("clipboard:\n  fallback_file: \"\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Clipboard.FallbackFile != "" {
+		t.Errorf("fallback file set to \"\" = %q, want it off", cfg.Clipboard.FallbackFile)
+	}
+}
+
+// TestValidateMaxItems checks a negative ui.max_items is reported and
+// reset, while 0 stays unlimited
+func TestValidateMaxItems(t *testing.T) {
+	for _, tt := range []struct{ value, want, warnings int }{{-1, 1000, 1}, {0, 0, 0}, {50, 50, 0}} {
+		cfg := DefaultConfig()
+		cfg.UI.MaxItems = tt.value
+		warnings := cfg.Validate()
+		if cfg.UI.MaxItems != tt.want || len(warnings) != tt.warnings {
+			t.Errorf("max_items %d validated to %d with warnings %q, want %d with %d warnings",
+				tt.value, cfg.UI.MaxItems, warnings, tt.want, tt.warnings)
+		}
+	}
+}
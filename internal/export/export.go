@@ -0,0 +1,198 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+	"github.com/4ndew/terminal-history-navigator/internal/templates"
+	"github.com/4ndew/terminal-history-navigator/internal/timefmt"
+)
+
+// Formats lists the supported export formats
+var Formats = []string{"json", "csv", "markdown"}
+
+// Record is the serialized form of a history command
+type Record struct {
+	Command   string     `json:"command"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+	Inferred  bool       `json:"timestamp_inferred,omitempty"`
+	Count     int        `json:"count"`
+	ExitCode  *int       `json:"exit_code"` // null when the exit code is unknown
+	Directory string     `json:"directory,omitempty"`
+	Source    string     `json:"source,omitempty"`
+}
+
+// NewRecord converts a command to its serialized form
+func NewRecord(cmd history.Command) Record {
+	record := Record{
+		Command:   cmd.Text,
+		Count:     cmd.Count,
+		Directory: cmd.Directory,
+		Source:    cmd.Source,
+	}
+	if !cmd.Timestamp.IsZero() {
+		timestamp := cmd.Timestamp
+		record.Timestamp = &timestamp
+		record.Inferred = cmd.Inferred
+	}
+	if cmd.HasExit {
+		exitCode := cmd.ExitCode
+		record.ExitCode = &exitCode
+	}
+	return record
+}
+
+// ToCommand converts a record back to a command
+func (r Record) ToCommand() history.Command {
+	cmd := history.Command{
+		Text:      r.Command,
+		Count:     r.Count,
+		Directory: r.Directory,
+		Source:    r.Source,
+	}
+	if r.Timestamp != nil {
+		cmd.Timestamp = *r.Timestamp
+		cmd.Inferred = r.Inferred
+	}
+	if r.ExitCode != nil {
+		cmd.ExitCode = *r.ExitCode
+		cmd.HasExit = true
+	}
+	return cmd
+}
+
+// Write serializes commands in the given format. Markdown, meant for
+// reading, formats timestamps with times; the other formats use RFC 3339.
+func Write(w io.Writer, format string, commands []history.Command, times timefmt.Formatter) error {
+	switch format {
+	case "json":
+		return WriteJSON(w, commands)
+	case "csv":
+		return WriteCSV(w, commands)
+	case "markdown":
+		return WriteMarkdown(w, commands, times)
+	default:
+		return fmt.Errorf("unknown format %q (use %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// WriteJSON writes commands as an indented JSON array
+func WriteJSON(w io.Writer, commands []history.Command) error {
+	records := make([]Record, 0, len(commands))
+	for _, cmd := range commands {
+		records = append(records, NewRecord(cmd))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
+
+// ReadJSON reads commands written by WriteJSON
+func ReadJSON(r io.Reader) ([]history.Command, error) {
+	var records []Record
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, err
+	}
+
+	commands := make([]history.Command, 0, len(records))
+	for _, record := range records {
+		commands = append(commands, record.ToCommand())
+	}
+	return commands, nil
+}
+
+// WriteCSV writes commands as CSV with a header row
+func WriteCSV(w io.Writer, commands []history.Command) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"command", "timestamp", "count", "exit_code", "directory", "source"})
+
+	for _, cmd := range commands {
+		writer.Write(fields(cmd))
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteMarkdown writes commands as a markdown table, formatting timestamps
+// with times
+func WriteMarkdown(w io.Writer, commands []history.Command, times timefmt.Formatter) error {
+	now := time.Now()
+	var b strings.Builder
+	b.WriteString("| Command | Timestamp | Count | Exit code | Directory | Source |\n")
+	b.WriteString("|---------|-----------|-------|-----------|-----------|--------|\n")
+
+	for _, cmd := range commands {
+		row := fields(cmd)
+		if !cmd.Timestamp.IsZero() {
+			row[1] = times.Format(cmd.Timestamp, now)
+		}
+		for i, field := range row {
+			row[i] = escapeMarkdown(field)
+		}
+		// Wrap the command in a code span so shell syntax renders literally
+		if row[0] != "" {
+			row[0] = "`" + row[0] + "`"
+		}
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// fields returns the column values for a command
+func fields(cmd history.Command) []string {
+	var timestamp, exitCode string
+	if !cmd.Timestamp.IsZero() {
+		timestamp = cmd.Timestamp.Format(time.RFC3339)
+	}
+	if cmd.HasExit {
+		exitCode = strconv.Itoa(cmd.ExitCode)
+	}
+	return []string{cmd.Text, timestamp, strconv.Itoa(cmd.Count), exitCode, cmd.Directory, cmd.Source}
+}
+
+// escapeMarkdown makes a value safe for a single markdown table cell
+func escapeMarkdown(s string) string {
+	s = strings.ReplaceAll(s, "\n", "<br>")
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "`", "'")
+}
+
+// Pick is the serialized form of a command selected in the picker
+type Pick struct {
+	Record
+	Kind     string        `json:"kind"` // "history" or "template"
+	Template *TemplateInfo `json:"template,omitempty"`
+}
+
+// TemplateInfo describes the template a picked command came from
+type TemplateInfo struct {
+	Name     string `json:"name"`
+	Category string `json:"category,omitempty"`
+}
+
+// NewHistoryPick converts a picked history command to its serialized form
+func NewHistoryPick(cmd history.Command) Pick {
+	return Pick{Record: NewRecord(cmd), Kind: "history"}
+}
+
+// NewTemplatePick converts a picked template to its serialized form
+func NewTemplatePick(template templates.Template) Pick {
+	return Pick{
+		Record: Record{Command: template.Command},
+		Kind:   "template",
+		Template: &TemplateInfo{
+			Name:     template.Name,
+			Category: template.Category,
+		},
+	}
+}
@@ -0,0 +1,165 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+	"github.com/4ndew/terminal-history-navigator/internal/templates"
+	"github.com/4ndew/terminal-history-navigator/internal/timefmt"
+)
+
+// exportCommands cover every field, text that needs quoting or escaping,
+// and commands without a timestamp or exit code
+var exportCommands = []history.Command{
+	{
+		Text:      "git commit -m \"fix, \\\"quoted\\\" | piped\"",
+		Timestamp: time.Date(2026, 10, 13, 14, 0, 0, 0, time.UTC),
+		Count:     3,
+		ExitCode:  1,
+		HasExit:   true,
+		Directory: "/home/me/src",
+		Source:    "/home/me/.zsh_history",
+	},
+	{Text: "for f in *; do\n  echo `date` $f\ndone", Count: 1, ExitCode: 0, HasExit: true},
+	{Text: "ls -la", Timestamp: time.Date(2026, 10, 12, 9, 30, 0, 0, time.UTC), Count: 7},
+}
+
+// decode returns the JSON object v encodes to
+func decode(t *testing.T, v any) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var object map[string]any
+	if err := json.Unmarshal(data, &object); err != nil {
+		t.Fatal(err)
+	}
+	return object
+}
+
+func TestHistoryPickSchema(t *testing.T) {
+	pick := NewHistoryPick(history.Command{
+		Text:      "make test",
+		Timestamp: time.Date(2026, 10, 13, 14, 0, 0, 0, time.UTC),
+		Count:     3,
+		ExitCode:  0,
+		HasExit:   true,
+		Source:    "/home/me/.zsh_history",
+	})
+	want := map[string]any{
+		"command":   "make test",
+		"timestamp": "2026-10-13T14:00:00Z",
+		"count":     3.0,
+		"exit_code": 0.0,
+		"source":    "/home/me/.zsh_history",
+		"kind":      "history",
+	}
+	if got := decode(t, pick); !reflect.DeepEqual(got, want) {
+		t.Errorf("history pick = %v, want %v", got, want)
+	}
+
+	pick = NewHistoryPick(history.Command{Text: "ls", Count: 1})
+	want = map[string]any{"command": "ls", "count": 1.0, "exit_code": nil, "kind": "history"}
+	if got := decode(t, pick); !reflect.DeepEqual(got, want) {
+		t.Errorf("history pick without exit code = %v, want %v", got, want)
+	}
+}
+
+func TestTemplatePickSchema(t *testing.T) {
+	pick := NewTemplatePick(templates.Template{Name: "Build", Category: "go", Command: "go build ./..."})
+	want := map[string]any{
+		"command":   "go build ./...",
+		"count":     0.0,
+		"exit_code": nil,
+		"kind":      "template",
+		"template":  map[string]any{"name": "Build", "category": "go"},
+	}
+	if got := decode(t, pick); !reflect.DeepEqual(got, want) {
+		t.Errorf("template pick = %v, want %v", got, want)
+	}
+}
+
+// TestJSONRoundTrip checks ReadJSON returns the commands WriteJSON wrote
+func TestJSONRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "json", exportCommands, timefmt.Formatter{}); err != nil {
+		t.Fatal(err)
+	}
+	commands, err := ReadJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(commands) != len(exportCommands) {
+		t.Fatalf("read %d commands, want %d", len(commands), len(exportCommands))
+	}
+	for i, cmd := range commands {
+		want := exportCommands[i]
+		if !cmd.Timestamp.Equal(want.Timestamp) {
+			t.Errorf("command %d timestamp = %v, want %v", i, cmd.Timestamp, want.Timestamp)
+		}
+		cmd.Timestamp, want.Timestamp = time.Time{}, time.Time{}
+		if !reflect.DeepEqual(cmd, want) {
+			t.Errorf("command %d = %+v, want %+v", i, cmd, want)
+		}
+	}
+}
+
+// TestCSV checks the header and that fields with commas, quotes and line
+// breaks are quoted so a CSV reader gets them back
+func TestCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "csv", exportCommands, timefmt.Formatter{}); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"command", "timestamp", "count", "exit_code", "directory", "source"},
+		{exportCommands[0].Text, "2026-10-13T14:00:00Z", "3", "1", "/home/me/src", "/home/me/.zsh_history"},
+		{exportCommands[1].Text, "", "1", "0", "", ""},
+		{"ls -la", "2026-10-12T09:30:00Z", "7", "", "", ""},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %q, want %q", rows, want)
+	}
+}
+
+// TestMarkdown checks every command is one table row, with pipes, line
+// breaks and backticks escaped
+func TestMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "markdown", exportCommands, timefmt.Formatter{}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2+len(exportCommands) {
+		t.Fatalf("table has %d lines, want %d:\n%s", len(lines), 2+len(exportCommands), buf.String())
+	}
+
+	for i, line := range lines {
+		cells := strings.Count(strings.ReplaceAll(line, "\\|", ""), "|")
+		if cells != 7 {
+			t.Errorf("line %d has %d unescaped pipes, want 7: %s", i, cells, line)
+		}
+	}
+	if want := "| `for f in *; do<br>  echo 'date' $f<br>done` |"; !strings.HasPrefix(lines[3], want) {
+		t.Errorf("multi-line row = %s, want it to start with %s", lines[3], want)
+	}
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, "xml", exportCommands, timefmt.Formatter{}); err == nil {
+		t.Error("Write accepted the xml format")
+	}
+}
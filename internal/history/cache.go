@@ -0,0 +1,259 @@
+package history
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/4ndew/terminal-history-navigator/internal/version"
+)
+
+// cacheVersion is bumped whenever the cache layout or the way history is
+// parsed changes, so caches written before are ignored
+const cacheVersion = 1
+
+// cacheTailSize is how many bytes before its mark a cached file must still
+// hold for the lines after the mark to be taken as appended
+const cacheTailSize = 256
+
+// cacheFile is the layout of the history cache
+type cacheFile struct {
+	Version     int
+	Settings    string // Reader settings the history was parsed with
+	Files       []cachedFile
+	Occurrences []Command // Every run read, newest first; the commands are counted from them again
+	Truncated   bool
+}
+
+// cachedFile records how far a source was read when the cache was saved
+type cachedFile struct {
+	Path     string
+	Missing  bool // Whether the source didn't exist
+	Size     int64
+	ModTime  time.Time
+	Tail     []byte // Last bytes before Size, to recognize the file later
+	Format   string
+	Lines    int
+	LastLine int
+}
+
+// SetCachePath sets the file the parsed history is cached in, "" for no
+// cache
+func (r *Reader) SetCachePath(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cachePath = path
+}
+
+// LoadCache returns the history saved by SaveCache if the reader has the
+// same settings and every source is the same file as then, unchanged or
+// only appended to. The reader then continues from the cache, so ReadNew
+// reads only what was appended since. A missing, stale, corrupt or
+// outdated cache is ignored.
+func (r *Reader) LoadCache() ([]Command, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cachePath == "" {
+		return nil, false
+	}
+	data, err := os.ReadFile(r.cachePath)
+	if err != nil {
+		return nil, false
+	}
+	var cache cacheFile
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&cache); err != nil {
+		return nil, false
+	}
+	if cache.Version != cacheVersion || cache.Settings != r.settings() {
+		return nil, false
+	}
+
+	sources := ExpandSources(r.sources)
+	if len(sources) != len(cache.Files) {
+		return nil, false
+	}
+	marks := make(map[string]*fileMark, len(sources))
+	for i, source := range sources {
+		file := cache.Files[i]
+		if file.Path != source {
+			return nil, false
+		}
+		info, err := os.Stat(source)
+		if file.Missing {
+			if !os.IsNotExist(err) {
+				return nil, false
+			}
+			continue
+		}
+		if err != nil || !file.continues(info) {
+			return nil, false
+		}
+		marks[source] = &fileMark{
+			info:     info,
+			size:     file.Size,
+			format:   file.Format,
+			lines:    file.Lines,
+			lastLine: file.LastLine,
+		}
+	}
+
+	// Runs that were recent enough when cached may have aged out since.
+	// Merging the rest oldest first as one source deduplicates and counts
+	// them as ReadHistory did.
+	now := time.Now()
+	runs := slices.DeleteFunc(slices.Clone(cache.Occurrences), func(cmd Command) bool {
+		return r.outsideTimeFilters(cmd, now)
+	})
+	slices.Reverse(runs)
+	r.marks = marks
+	r.truncated = cache.Truncated
+	r.skipped = nil
+	r.texts = make(map[string]string)
+	return r.merge([][]Command{runs}, len(runs)), true
+}
+
+// ClearCache deletes the history cache, so commands deleted or excluded
+// since it was saved don't stay on disk. The next start reads the history
+// in full and saves a new one.
+func (r *Reader) ClearCache() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.clearCache()
+}
+
+// clearCache deletes the history cache, with r.mu held
+func (r *Reader) clearCache() error {
+	if r.cachePath == "" {
+		return nil
+	}
+	if err := os.Remove(r.cachePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// continues reports whether the file described by info still starts with
+// what was cached of it
+func (f cachedFile) continues(info os.FileInfo) bool {
+	if info.Size() < f.Size {
+		return false
+	}
+	if info.Size() == f.Size && info.ModTime().Equal(f.ModTime) {
+		return true
+	}
+
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	tail := make([]byte, len(f.Tail))
+	if _, err := file.ReadAt(tail, f.Size-int64(len(tail))); err != nil {
+		return false
+	}
+	return bytes.Equal(tail, f.Tail)
+}
+
+// SaveCache saves the history read so far for LoadCache, along with how
+// far each source was read. Nothing is saved without a
+// cache path, or when a source can't be continued from a cache: stdin,
+// remote sources, databases and files that failed to read or were being
+// written.
+func (r *Reader) SaveCache() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cachePath == "" || r.marks == nil {
+		return nil
+	}
+
+	cache := cacheFile{
+		Version:     cacheVersion,
+		Settings:    r.settings(),
+		Occurrences: r.occurrences,
+		Truncated:   r.truncated,
+	}
+	for _, source := range ExpandSources(r.sources) {
+		if source == StdinSource || IsRemote(source) {
+			return nil
+		}
+		mark := r.marks[source]
+		if mark == nil {
+			if _, err := os.Stat(source); os.IsNotExist(err) {
+				cache.Files = append(cache.Files, cachedFile{Path: source, Missing: true})
+				continue
+			}
+			return nil
+		}
+		if mark.info == nil {
+			return nil
+		}
+
+		tail, err := readTail(source, mark.size)
+		if err != nil {
+			return err
+		}
+		cache.Files = append(cache.Files, cachedFile{
+			Path:     source,
+			Size:     mark.size,
+			ModTime:  mark.info.ModTime(),
+			Tail:     tail,
+			Format:   mark.format,
+			Lines:    mark.lines,
+			LastLine: mark.lastLine,
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(cache); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.cachePath), 0700); err != nil {
+		return err
+	}
+	// Write a new file first so an interrupted save can't leave a corrupt
+	// cache behind
+	tmp := r.cachePath + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, r.cachePath)
+}
+
+// readTail returns the last cacheTailSize bytes before offset in a file
+func readTail(path string, offset int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	tail := make([]byte, min(offset, cacheTailSize))
+	if _, err := file.ReadAt(tail, offset-int64(len(tail))); err != nil {
+		return nil, err
+	}
+	return tail, nil
+}
+
+// settings describes everything that changes how the history is parsed,
+// so a cache is only used by the same build with a reader set up the same
+// way
+func (r *Reader) settings() string {
+	patterns := func(regexes []*regexp.Regexp) []string {
+		var texts []string
+		for _, regex := range regexes {
+			texts = append(texts, regex.String())
+		}
+		return texts
+	}
+	return fmt.Sprintf("%q %q %d %+v %q %d %t %q %+v %q %q %q",
+		version.String(), r.sources, r.maxLines, r.filters, r.format, r.maxLength, r.skipLong,
+		r.secretsMode, r.normalization, patterns(r.excludePatterns), patterns(r.includePatterns),
+		patterns(r.keepPatterns))
+}
@@ -0,0 +1,253 @@
+package history
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newCachedReader returns a reader of path caching in a temporary file
+func newCachedReader(t testing.TB, path string) *Reader {
+	t.Helper()
+	reader := NewReader([]string{path})
+	reader.SetCachePath(filepath.Join(t.TempDir(), "history.cache"))
+	return reader
+}
+
+// saveCache reads the history and saves the cache of reader
+func saveCache(t testing.TB, reader *Reader) []Command {
+	t.Helper()
+	commands, err := reader.ReadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reader.SaveCache(); err != nil {
+		t.Fatal(err)
+	}
+	return commands
+}
+
+// withCachePath returns a new reader of path using the cache of reader
+func withCachePath(reader *Reader, path string) *Reader {
+	next := NewReader([]string{path})
+	next.SetCachePath(reader.cachePath)
+	return next
+}
+
+func TestLoadCacheHit(t *testing.T) {
+	path := writeHistory(t, ".zsh_history",
+		": 1700000000:0;ls",
+		": 1700000001:0;git status",
+		": 1700000002:0;ls",
+	)
+	reader := newCachedReader(t, path)
+	read := saveCache(t, reader)
+
+	next := withCachePath(reader, path)
+	cached, ok := next.LoadCache()
+	if !ok {
+		t.Fatal("LoadCache missed a fresh cache")
+	}
+	if !slices.Equal(texts(cached), texts(read)) {
+		t.Errorf("cached commands = %q, want %q", texts(cached), texts(read))
+	}
+	if cached[0].Count != 2 {
+		t.Errorf("cached count of ls = %d, want 2", cached[0].Count)
+	}
+
+	// Lines appended since are read on their own
+	if err := appendHistory(path, ": 1700000003:0;make"); err != nil {
+		t.Fatal(err)
+	}
+	commands, incremental, err := next.ReadNew()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !incremental || !slices.Equal(texts(commands), []string{"make"}) {
+		t.Errorf("ReadNew after LoadCache = %q, incremental %t; want [make], true", texts(commands), incremental)
+	}
+}
+
+func TestLoadCacheStale(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, reader *Reader, path string) *Reader
+	}{
+		{"rewritten", func(t *testing.T, reader *Reader, path string) *Reader {
+			if err := os.WriteFile(path, []byte(": 1700000000:0;pwd\n: 1700000001:0;git stash\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			return withCachePath(reader, path)
+		}},
+		{"shrunk", func(t *testing.T, reader *Reader, path string) *Reader {
+			if err := os.WriteFile(path, []byte(": 1700000000:0;ls\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			return withCachePath(reader, path)
+		}},
+		{"removed", func(t *testing.T, reader *Reader, path string) *Reader {
+			if err := os.Remove(path); err != nil {
+				t.Fatal(err)
+			}
+			return withCachePath(reader, path)
+		}},
+		{"other settings", func(t *testing.T, reader *Reader, path string) *Reader {
+			next := withCachePath(reader, path)
+			if err := next.SetExcludePatterns([]string{"^git"}); err != nil {
+				t.Fatal(err)
+			}
+			return next
+		}},
+		{"other sources", func(t *testing.T, reader *Reader, path string) *Reader {
+			other := writeHistory(t, ".bash_history", "ls")
+			next := withCachePath(reader, path)
+			next.SetSources([]string{path, other})
+			return next
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeHistory(t, ".zsh_history", ": 1700000000:0;ls", ": 1700000001:0;git status")
+			reader := newCachedReader(t, path)
+			saveCache(t, reader)
+
+			if _, ok := tt.change(t, reader, path).LoadCache(); ok {
+				t.Error("LoadCache used a stale cache")
+			}
+		})
+	}
+}
+
+func TestLoadCacheCorrupt(t *testing.T) {
+	path := writeHistory(t, ".zsh_history", ": 1700000000:0;ls")
+	reader := newCachedReader(t, path)
+	saveCache(t, reader)
+
+	data, err := os.ReadFile(reader.cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, corrupt := range map[string][]byte{
+		"garbage":   []byte("not a cache"),
+		"truncated": data[:len(data)/2],
+		"empty":     nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := os.WriteFile(reader.cachePath, corrupt, 0600); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := withCachePath(reader, path).LoadCache(); ok {
+				t.Error("LoadCache used a corrupt cache")
+			}
+		})
+	}
+}
+
+// TestLoadCacheAppliesTimeFilters checks runs that aged out since the cache
+// was saved are left out
+func TestLoadCacheAppliesTimeFilters(t *testing.T) {
+	recent := time.Now().Add(-time.Hour).Unix()
+	path := writeHistory(t, ".zsh_history",
+		fmt.Sprintf(": %d:0;ls", recent),
+		fmt.Sprintf(": %d:0;git status", recent+1),
+	)
+	filters := DefaultFilters()
+	filters.MaxAge = 24 * time.Hour
+	reader := newCachedReader(t, path)
+	reader.SetFilters(filters)
+	saveCache(t, reader)
+
+	// Age the cached run of ls past MaxAge
+	data, err := os.ReadFile(reader.cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cache cacheFile
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&cache); err != nil {
+		t.Fatal(err)
+	}
+	for i := range cache.Occurrences {
+		if cache.Occurrences[i].Text == "ls" {
+			cache.Occurrences[i].Timestamp = time.Now().Add(-48 * time.Hour)
+		}
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(cache); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(reader.cachePath, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	next := withCachePath(reader, path)
+	next.SetFilters(filters)
+	cached, ok := next.LoadCache()
+	if !ok {
+		t.Fatal("LoadCache missed the cache")
+	}
+	if got := texts(cached); !slices.Equal(got, []string{"git status"}) {
+		t.Errorf("cached commands = %q, want [git status]", got)
+	}
+}
+
+func TestDeleteCommandClearsCache(t *testing.T) {
+	path := writeHistory(t, ".zsh_history", ": 1700000000:0;ls", ": 1700000001:0;export TOKEN=hunter2")
+	reader := newCachedReader(t, path)
+	saveCache(t, reader)
+
+	if _, err := reader.DeleteCommand("export TOKEN=hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(reader.cachePath); !os.IsNotExist(err) {
+		t.Errorf("cache still exists after DeleteCommand: %v", err)
+	}
+}
+
+// largeHistory writes a zsh history of n mostly distinct commands
+func largeHistory(b testing.TB, n int) string {
+	b.Helper()
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf(": %d:0;git commit -m 'change %d' --author %q", 1700000000+i, i%(n/2), strings.Repeat("x", i%20))
+	}
+	return writeHistory(b, ".zsh_history", lines...)
+}
+
+// BenchmarkStartWithoutCache measures reading a 50000-line history in full,
+// as a start without a cache does
+func BenchmarkStartWithoutCache(b *testing.B) {
+	path := largeHistory(b, 50000)
+	for i := 0; i < b.N; i++ {
+		reader := NewReader([]string{path})
+		reader.SetMaxLines(50000)
+		if _, _, err := reader.ReadNew(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkStartWithCache measures the same start continuing from a cache
+func BenchmarkStartWithCache(b *testing.B) {
+	path := largeHistory(b, 50000)
+	reader := newCachedReader(b, path)
+	reader.SetMaxLines(50000)
+	saveCache(b, reader)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		next := withCachePath(reader, path)
+		next.SetMaxLines(50000)
+		if _, ok := next.LoadCache(); !ok {
+			b.Fatal("LoadCache missed the cache")
+		}
+		if _, _, err := next.ReadNew(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/4ndew/terminal-history-navigator/internal/redact"
+)
+
+// DeleteCommand removes every entry of a command from the history files
+// among the sources, rewriting each file that had one. Entries count as
+// the command when they read as the same text, so a command listed once
+// loses every run and variant that was merged into it. Multi-line entries
+// go as a whole, with the timestamp comment bash or tcsh wrote before them.
+// The history cache is cleared when anything was removed. It returns how
+// many entries were removed; sources that can't be edited (stdin, ssh and
+// databases) are reported in the error.
+func (r *Reader) DeleteCommand(text string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	removed := 0
+	var errs []error
+	for _, source := range ExpandSources(r.sources) {
+		if source == StdinSource {
+			continue
+		}
+		if IsRemote(source) {
+			errs = append(errs, fmt.Errorf("%s: remote sources can't be edited", source))
+			continue
+		}
+		if _, err := os.Stat(source); os.IsNotExist(err) {
+			continue
+		}
+		n, err := r.deleteFromFile(source, text)
+		removed += n
+		if n > 0 {
+			// The rewritten file can only be read again in full
+			delete(r.marks, source)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source, err))
+		}
+	}
+	// The cache holds the deleted entries too
+	if removed > 0 {
+		if err := r.clearCache(); err != nil {
+			errs = append(errs, fmt.Errorf("cache: %w", err))
+		}
+	}
+	return removed, errors.Join(errs...)
+}
+
+// deleteFromFile removes the entries of a command from one history file
+func (r *Reader) deleteFromFile(filename, text string) (int, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	sqlite := isSQLite(file)
+	file.Close()
+	if sqlite {
+		return 0, errors.New("databases can't be edited")
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	content := string(data)
+	trailingNewline := strings.HasSuffix(content, "\n")
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+
+	format := r.format
+	if format == "" {
+		format = DetectFormat(ringLines(lines, 0, sniffLines))
+		if format == "" {
+			format = FileFormat(filename)
+		}
+	}
+	if format == "fish" {
+		return 0, errors.New("fish history is not supported")
+	}
+
+	key := r.normalization.Key(text)
+	deleted := make([]bool, len(lines))
+	removed := 0
+	comment := -1 // Timestamp comment line before the next bash or tcsh command
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		// Join multi-line entries the way readFrom does
+		start := i
+		if joinsLines(format) {
+			for strings.HasSuffix(line, "\\") && i+1 < len(lines) {
+				i++
+				line = strings.TrimSuffix(line, "\\") + "\n" + lines[i]
+			}
+		}
+		if format == "bash" {
+			line, i = joinBashLines(lines, 0, i)
+		}
+		if format != "zsh" && format != "nushell" {
+			if _, ok := commentTimestamp(line); ok {
+				comment = i
+				continue
+			}
+		}
+
+		var raw string
+		switch {
+		case format == "zsh" || format == "auto" && strings.HasPrefix(strings.TrimSpace(line), ":"):
+			raw = r.parseZshLine(unmetafy(line), start).Text
+		case format == "nushell":
+			raw = parseNushellLine(line, start).Text
+		default:
+			raw = line
+		}
+
+		if r.normalization.Key(r.displayText(raw)) == key {
+			if comment >= 0 && comment == start-1 {
+				deleted[comment] = true
+			}
+			for k := start; k <= i; k++ {
+				deleted[k] = true
+			}
+			removed++
+		}
+		comment = -1
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+
+	var kept []string
+	for i, line := range lines {
+		if !deleted[i] {
+			kept = append(kept, line)
+		}
+	}
+	out := strings.Join(kept, "\n")
+	if trailingNewline && len(kept) > 0 {
+		out += "\n"
+	}
+	if err := WriteFileAtomic(filename, []byte(out)); err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// displayText returns the text a raw command is listed with: cleaned as
+// keep cleans it, with secrets masked and long commands truncated as set
+func (r *Reader) displayText(raw string) string {
+	text := strings.TrimSpace(strings.ToValidUTF8(raw, "\uFFFD"))
+	if r.secretsMode == "redact" {
+		text, _ = redact.Scrub(text)
+		text = strings.TrimSpace(text)
+	}
+	if r.maxLength > 0 && !r.skipLong && utf8.RuneCountInString(text) > r.maxLength {
+		text = truncateRunes(text, r.maxLength)
+	}
+	return text
+}
+
+// WriteFileAtomic replaces path with data by writing a temporary file in the
+// same directory and renaming it over the original, keeping its permissions
+func WriteFileAtomic(path string, data []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
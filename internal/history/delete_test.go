@@ -0,0 +1,114 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// writeHistory writes lines to a history file in a temporary directory and
+// returns its path
+func writeHistory(t testing.TB, name string, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// appendHistory appends lines to a history file, as a shell does
+func appendHistory(path string, lines ...string) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.WriteString(strings.Join(lines, "\n") + "\n")
+	return err
+}
+
+// texts returns the text of each command
+func texts(commands []Command) []string {
+	var result []string
+	for _, cmd := range commands {
+		result = append(result, cmd.Text)
+	}
+	return result
+}
+
+func TestDeleteCommand(t *testing.T) {
+	path := writeHistory(t, ".zsh_history",
+		": 1700000000:0;ls",
+		": 1700000001:0;export TOKEN=hunter2",
+		": 1700000002:0;git status",
+		": 1700000003:0;export TOKEN=hunter2",
+	)
+	reader := NewReader([]string{path})
+
+	removed, err := reader.DeleteCommand("export TOKEN=hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("removed %d entries, want 2", removed)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ": 1700000000:0;ls\n: 1700000002:0;git status\n"
+	if string(data) != want {
+		t.Errorf("file after delete = %q, want %q", data, want)
+	}
+}
+
+// TestDeleteCommandDuringRefresh deletes commands while refreshes read the
+// same file in the background, as the UI does; run with -race
+func TestDeleteCommandDuringRefresh(t *testing.T) {
+	path := writeHistory(t, ".zsh_history", ": 1700000000:0;ls")
+	reader := NewReader([]string{path})
+	if _, err := reader.ReadHistory(); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if err := appendHistory(path, fmt.Sprintf(": %d:0;echo %d", 1700000001+i, i)); err != nil {
+				t.Error(err)
+				return
+			}
+			if _, _, err := reader.ReadNew(); err != nil {
+				t.Error(err)
+				return
+			}
+			reader.Occurrences()
+			reader.Truncated()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if _, err := reader.DeleteCommand(fmt.Sprintf("echo %d", i)); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	commands, err := reader.ReadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) == 0 || commands[len(commands)-1].Text != "ls" {
+		t.Errorf("commands after deletes = %q, want ls to survive", texts(commands))
+	}
+}
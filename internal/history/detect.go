@@ -0,0 +1,121 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DetectedSource is a history file found where a shell keeps it by default
+type DetectedSource struct {
+	Shell string // "zsh", "bash", "tcsh", "histdb" or "nu"
+	Path  string
+}
+
+// DetectSources returns the existing history files of the supported shells:
+// $HISTFILE, the default zsh, bash, tcsh and NuShell locations and the
+// zsh-histdb database
+func DetectSources() []DetectedSource {
+	homeDir, _ := os.UserHomeDir()
+	candidates := []DetectedSource{
+		{Shell: "zsh", Path: filepath.Join(homeDir, ".zsh_history")},
+		{Shell: "zsh", Path: filepath.Join(homeDir, ".zhistory")},
+		{Shell: "bash", Path: filepath.Join(homeDir, ".bash_history")},
+		{Shell: "tcsh", Path: filepath.Join(homeDir, ".history")},
+		{Shell: "histdb", Path: filepath.Join(homeDir, ".histdb", "zsh-history.db")},
+	}
+	// zsh in macOS Terminal also keeps a file per window session
+	if sessions := filepath.Join(homeDir, ".zsh_sessions", "*.history"); len(ExpandSources([]string{sessions})) > 0 {
+		candidates = append(candidates, DetectedSource{Shell: "zsh", Path: sessions})
+	}
+	for _, dir := range NushellDirs() {
+		candidates = append(candidates,
+			DetectedSource{Shell: "nu", Path: filepath.Join(dir, "history.sqlite3")},
+			DetectedSource{Shell: "nu", Path: filepath.Join(dir, "history.txt")},
+		)
+	}
+
+	// zsh reads its startup files and, by default, writes history in ZDOTDIR
+	if zdotdir := os.Getenv("ZDOTDIR"); zdotdir != "" {
+		candidates = append([]DetectedSource{
+			{Shell: "zsh", Path: filepath.Join(zdotdir, ".zsh_history")},
+			{Shell: "zsh", Path: filepath.Join(zdotdir, ".zhistory")},
+		}, candidates...)
+	}
+
+	// HISTFILE is only exported by some setups, but names the file in use
+	if histfile := os.Getenv("HISTFILE"); histfile != "" {
+		shell := FileFormat(histfile)
+		if shell == "auto" {
+			shell = filepath.Base(os.Getenv("SHELL"))
+		}
+		candidates = append([]DetectedSource{{Shell: shell, Path: histfile}}, candidates...)
+	}
+
+	var found []DetectedSource
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		if seen[candidate.Path] {
+			continue
+		}
+		seen[candidate.Path] = true
+		if IsGlob(candidate.Path) {
+			found = append(found, candidate)
+			continue
+		}
+		if info, err := os.Stat(candidate.Path); err == nil && info.Mode().IsRegular() {
+			found = append(found, candidate)
+		}
+	}
+	return found
+}
+
+// ExpandSources replaces glob patterns in sources, such as
+// ~/.zsh_history.*, with the files they match in sorted order. Patterns
+// matching nothing are dropped like missing files, invalid patterns are
+// kept as plain paths, and each file is listed once.
+func ExpandSources(sources []string) []string {
+	var expanded []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			expanded = append(expanded, path)
+		}
+	}
+
+	for _, source := range sources {
+		if !IsGlob(source) {
+			add(source)
+			continue
+		}
+		matches, err := filepath.Glob(source)
+		if err != nil {
+			add(source)
+			continue
+		}
+		for _, match := range matches {
+			add(match)
+		}
+	}
+	return expanded
+}
+
+// IsGlob reports whether a source is a glob pattern rather than a path
+func IsGlob(source string) bool {
+	return strings.ContainsAny(source, "*?[")
+}
+
+// NushellDirs returns the directories NuShell may keep its history in:
+// ~/.config/nushell, and the platform config directory where that differs,
+// e.g. on macOS
+func NushellDirs() []string {
+	homeDir, _ := os.UserHomeDir()
+	dirs := []string{filepath.Join(homeDir, ".config", "nushell")}
+	if configDir, err := os.UserConfigDir(); err == nil {
+		if dir := filepath.Join(configDir, "nushell"); dir != dirs[0] {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
@@ -0,0 +1,56 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestExpandSources checks glob sources expand to the files they match in
+// sorted order, each listed once, while plain paths are kept as given
+func TestExpandSources(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{".zsh_history", ".zsh_history.2", ".zsh_history.10", ".zsh_history.1", ".bash_history", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("ls\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sessions"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sessions", "a.history"), []byte("ls\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		sources []string
+		want    []string
+	}{
+		{[]string{".zsh_history.*"}, []string{".zsh_history.1", ".zsh_history.10", ".zsh_history.2"}},
+		{[]string{".zsh_history*"}, []string{".zsh_history", ".zsh_history.1", ".zsh_history.10", ".zsh_history.2"}},
+		{[]string{".zsh_history.?"}, []string{".zsh_history.1", ".zsh_history.2"}},
+		{[]string{".*_history"}, []string{".bash_history", ".zsh_history"}},
+		{[]string{"sessions/*.history", ".bash_history"}, []string{"sessions/a.history", ".bash_history"}},
+		// Files matched twice are listed where they first appear
+		{[]string{".zsh_history.1", ".zsh_history.*"}, []string{".zsh_history.1", ".zsh_history.10", ".zsh_history.2"}},
+		// Patterns matching nothing are dropped, missing plain paths kept
+		{[]string{".fish_history.*", ".missing_history"}, []string{".missing_history"}},
+		// Invalid patterns are kept as paths
+		{[]string{"[.zsh_history"}, []string{"[.zsh_history"}},
+	}
+	for _, tt := range tests {
+		var sources []string
+		for _, source := range tt.sources {
+			sources = append(sources, filepath.Join(dir, source))
+		}
+		var got []string
+		for _, path := range ExpandSources(sources) {
+			got = append(got, filepath.ToSlash(strings.TrimPrefix(path, dir+string(filepath.Separator))))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("ExpandSources(%q) = %q, want %q", tt.sources, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package history
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// sniffLines is how many non-empty lines DetectFormat looks at
+const sniffLines = 20
+
+// zshExtendedPattern matches the ": <start>:<duration>;" prefix of zsh
+// extended history lines
+var zshExtendedPattern = regexp.MustCompile(`^: *\d+:\d+(:\d+)?;`)
+
+// DetectFormat returns the history format the lines are written in: "zsh"
+// for extended history lines, "bash" for "#<epoch>" timestamp comments,
+// "tcsh" for "#+<epoch>" ones, "fish" for "- cmd:" entries, or "" if the
+// lines are plain commands that any parser reads the same way. The first
+// line with a signature decides.
+func DetectFormat(lines []string) string {
+	checked := 0
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		switch {
+		case zshExtendedPattern.MatchString(line):
+			return "zsh"
+		case strings.HasPrefix(line, "- cmd: "):
+			return "fish"
+		}
+		if _, ok := commentTimestamp(line); ok {
+			if strings.HasPrefix(line, "#+") {
+				return "tcsh"
+			}
+			return "bash"
+		}
+
+		checked++
+		if checked == sniffLines {
+			break
+		}
+	}
+	return ""
+}
+
+// FileFormat returns the history format assumed for a file based on its
+// name: "nushell", "zsh", "bash", "tcsh", or "auto" to detect zsh extended
+// lines individually.
+// It only breaks ties when the content has no signature.
+func FileFormat(filename string) string {
+	switch {
+	case strings.Contains(filename, "nushell"):
+		return "nushell"
+	case strings.Contains(filename, "zsh"):
+		return "zsh"
+	case strings.Contains(filename, "bash") || filepath.Ext(filename) == ".bash_history":
+		return "bash"
+	case strings.Contains(filename, "csh"):
+		return "tcsh"
+	default:
+		return "auto"
+	}
+}
+
+// DetectFileFormat returns the format of a history file from its first
+// lines, falling back to its name. zsh-histdb databases are "histdb", and
+// NuShell ones "nushell".
+func DetectFileFormat(filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if isSQLite(file) {
+		if isNushellDatabase(filename) {
+			return "nushell", nil
+		}
+		return "histdb", nil
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxLineSize)
+	for len(lines) < sniffLines && scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) != "" {
+			lines = append(lines, scanner.Text())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	if format := DetectFormat(lines); format != "" {
+		return format, nil
+	}
+	return FileFormat(filename), nil
+}
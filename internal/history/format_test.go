@@ -0,0 +1,107 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{"zsh extended", []string{": 1700000000:0;ls", ": 1700000001:0;make"}, "zsh"},
+		{"zsh with exit code", []string{": 1700000000:0:1;false"}, "zsh"},
+		{"zsh after blank lines", []string{"", "  ", ": 1700000000:0;ls"}, "zsh"},
+		{"bash timestamps", []string{"#1700000000", "ls"}, "bash"},
+		{"tcsh timestamps", []string{"#+1700000000", "ls"}, "tcsh"},
+		{"fish", []string{"- cmd: ls", "  when: 1700000000"}, "fish"},
+		{"plain", []string{"ls", "make", "git status"}, ""},
+		{"plain with a comment", []string{"# not a timestamp", "ls"}, ""},
+		{"first signature decides", []string{"ls", "#1700000000", ": 1700000001:0;make"}, "bash"},
+		{"colon command", []string{": nothing to see", "ls"}, ""},
+		{"empty", nil, ""},
+	}
+	for _, tt := range tests {
+		if got := DetectFormat(tt.lines); got != tt.want {
+			t.Errorf("%s: DetectFormat(%q) = %q, want %q", tt.name, tt.lines, got, tt.want)
+		}
+	}
+}
+
+// TestDetectFormatLooksAtFirstLines checks a signature after the first
+// sniffLines plain lines is not looked for
+func TestDetectFormatLooksAtFirstLines(t *testing.T) {
+	lines := make([]string, sniffLines, sniffLines+1)
+	for i := range lines {
+		lines[i] = "ls"
+	}
+	if got := DetectFormat(append(lines, ": 1700000000:0;ls")); got != "" {
+		t.Errorf("DetectFormat with a late signature = %q, want plain", got)
+	}
+}
+
+func TestFileFormat(t *testing.T) {
+	tests := []struct{ filename, want string }{
+		{"/home/me/.zsh_history", "zsh"},
+		{"/home/me/.bash_history", "bash"},
+		{"/home/me/.history.bash_history", "bash"},
+		{"/home/me/.tcsh_history", "tcsh"},
+		{"/home/me/.config/nushell/history.txt", "nushell"},
+		{"/home/me/history.txt", "auto"},
+	}
+	for _, tt := range tests {
+		if got := FileFormat(tt.filename); got != tt.want {
+			t.Errorf("FileFormat(%q) = %q, want %q", tt.filename, got, tt.want)
+		}
+	}
+}
+
+// TestDetectFileFormat checks the content decides over the file name,
+// which only breaks ties for plain lines
+func TestDetectFileFormat(t *testing.T) {
+	tests := []struct {
+		dir, name, content, want string
+	}{
+		{"zsh-box", ".bash_history", "#1700000000\nls\n", "bash"},
+		{"backups", "history.txt", ": 1700000000:0;ls\n", "zsh"},
+		{"backups", ".zsh_history", "ls\nmake\n", "zsh"},
+		{"backups", "history.txt", "ls\nmake\n", "auto"},
+	}
+	for _, tt := range tests {
+		dir := filepath.Join(t.TempDir(), tt.dir)
+		if err := os.Mkdir(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, tt.name)
+		if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := DetectFileFormat(path); err != nil || got != tt.want {
+			t.Errorf("DetectFileFormat(%s/%s) = %q, %v; want %q", tt.dir, tt.name, got, err, tt.want)
+		}
+	}
+}
+
+// TestReaderSniffsFormat checks a bash history in a directory named after
+// zsh is read as bash
+func TestReaderSniffsFormat(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "zsh-box")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, ".bash_history")
+	if err := os.WriteFile(path, []byte("#1700000000\nls -la\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	commands, err := NewReader([]string{path}).ReadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != 1 || commands[0].Text != "ls -la" || commands[0].Timestamp.Unix() != 1700000000 {
+		t.Errorf("commands = %+v, want ls -la at 1700000000", commands)
+	}
+}
@@ -0,0 +1,82 @@
+package history
+
+import (
+	"regexp"
+	"strings"
+)
+
+// maxHeredocLines is how far a heredoc terminator is looked for. A heredoc
+// left open longer is not joined, so one unterminated heredoc can't swallow
+// the rest of the history.
+const maxHeredocLines = 500
+
+// heredocPattern matches a heredoc redirection, <<EOF, <<-EOF, <<'EOF' or
+// <<"EOF", capturing the delimiter in one of its groups
+var heredocPattern = regexp.MustCompile(`<<-?[ \t]*(?:'([^']+)'|"([^"]+)"|\\?([A-Za-z_][A-Za-z0-9_]*))`)
+
+// heredocDelimiters returns the delimiters of the heredocs a command line
+// opens, in order. Here-strings (<<<) are not heredocs.
+func heredocDelimiters(line string) []string {
+	var delimiters []string
+	for _, match := range heredocPattern.FindAllStringSubmatchIndex(line, -1) {
+		if match[0] > 0 && line[match[0]-1] == '<' {
+			continue
+		}
+		for group := 1; group <= 3; group++ {
+			if start := match[2*group]; start >= 0 {
+				delimiters = append(delimiters, line[start:match[2*group+1]])
+				break
+			}
+		}
+	}
+	return delimiters
+}
+
+// joinBashLines joins the ring line at index i with the lines that bash
+// saved as separate entries because cmdhist was off: continuations of a
+// line ending in a backslash, and the bodies of the heredocs it opens up to
+// their terminators. Timestamp comments between the parts are dropped. It
+// returns the joined command with embedded newlines and the index of its
+// last line.
+func joinBashLines(ring []string, oldest, i int) (string, int) {
+	at := func(k int) string { return ring[(oldest+k)%len(ring)] }
+
+	// nextLine returns the next line after k that isn't a timestamp comment
+	nextLine := func(k int) int {
+		for k++; k < len(ring); k++ {
+			if _, ok := commentTimestamp(at(k)); !ok {
+				return k
+			}
+		}
+		return -1
+	}
+
+	line := at(i)
+	for strings.HasSuffix(line, "\\") {
+		next := nextLine(i)
+		if next < 0 {
+			break
+		}
+		line = strings.TrimSuffix(line, "\\") + "\n" + at(next)
+		i = next
+	}
+
+	joined := line
+	end := i
+	for _, delimiter := range heredocDelimiters(line) {
+		terminated := false
+		for k := nextLine(end); k >= 0 && k-i <= maxHeredocLines; k = nextLine(k) {
+			joined += "\n" + at(k)
+			end = k
+			// <<- strips leading tabs, and editors often indent the rest
+			if strings.TrimSpace(at(k)) == delimiter {
+				terminated = true
+				break
+			}
+		}
+		if !terminated {
+			return line, i
+		}
+	}
+	return joined, end
+}
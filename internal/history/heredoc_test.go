@@ -0,0 +1,86 @@
+package history
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHeredocDelimiters(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"cat <<EOF", "[EOF]"},
+		{"cat << EOF > out.txt", "[EOF]"},
+		{"cat <<-END", "[END]"},
+		{"cat <<'EOF'", "[EOF]"},
+		{`cat <<"my delim"`, "[my delim]"},
+		{`cat <<\EOF`, "[EOF]"},
+		{"paste <<A <<B", "[A B]"},
+		{"grep x <<< 'here string'", "[]"},
+		{"echo $((1 << 2))", "[]"},
+		{"ls", "[]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(heredocDelimiters(tt.line)); got != tt.want {
+			t.Errorf("heredocDelimiters(%q) = %s, want %s", tt.line, got, tt.want)
+		}
+	}
+}
+
+// TestBashHeredocs checks multi-line commands bash saved line by line are
+// joined back up
+func TestBashHeredocs(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []string
+	}{
+		{
+			"heredoc",
+			[]string{"cat <<EOF > notes.txt", "hello", "EOF", "ls"},
+			[]string{"ls", "cat <<EOF > notes.txt\nhello\nEOF"},
+		},
+		{
+			"quoted delimiter",
+			[]string{"cat <<'END'", "$HOME stays literal", "END"},
+			[]string{"cat <<'END'\n$HOME stays literal\nEND"},
+		},
+		{
+			"indented terminator",
+			[]string{"cat <<-EOF", "\tindented", "\tEOF"},
+			[]string{"cat <<-EOF\n\tindented\n\tEOF"},
+		},
+		{
+			"two heredocs",
+			[]string{"paste <<A <<B", "1", "A", "2", "B"},
+			[]string{"paste <<A <<B\n1\nA\n2\nB"},
+		},
+		{
+			"continuation",
+			[]string{"docker run \\", "  -it alpine", "make"},
+			[]string{"make", "docker run \n  -it alpine"},
+		},
+		{
+			"timestamps between the lines",
+			[]string{"#1700000000", "cat <<EOF", "#1700000000", "hello", "#1700000000", "EOF"},
+			[]string{"cat <<EOF\nhello\nEOF"},
+		},
+		{
+			"unterminated",
+			[]string{"cat <<EOF", "hello", "ls"},
+			[]string{"ls", "hello", "cat <<EOF"},
+		},
+		{
+			"here string",
+			[]string{"grep x <<< 'a'", "ls"},
+			[]string{"ls", "grep x <<< 'a'"},
+		},
+	}
+	for _, tt := range tests {
+		commands := readFile(t, ".bash_history", tt.lines...)
+		if got := texts(commands); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: commands = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
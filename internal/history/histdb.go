@@ -0,0 +1,95 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// histdbQuery selects the newest runs recorded by zsh-histdb, limited to a
+// number of rows
+const histdbQuery = `SELECT history.id AS id, commands.argv AS argv, places.dir AS dir,
+	history.exit_status AS exit_status, history.start_time AS start_time, history.duration AS duration
+FROM history
+JOIN commands ON history.command_id = commands.id
+JOIN places ON history.place_id = places.id
+ORDER BY history.id DESC
+LIMIT %d`
+
+// histdbRow is one run as printed by sqlite3 -json. Columns are NULL for
+// commands that are still running.
+type histdbRow struct {
+	ID         int    `json:"id"`
+	Argv       string `json:"argv"`
+	Dir        string `json:"dir"`
+	ExitStatus *int   `json:"exit_status"`
+	StartTime  *int64 `json:"start_time"`
+	Duration   *int64 `json:"duration"`
+}
+
+// readHistdb reads the last maxLines runs from a zsh-histdb database,
+// oldest first, with the directory, exit code and timing of each run
+func (r *Reader) readHistdb(filename string) ([]Command, error) {
+	if r.maxLines <= 0 {
+		return nil, nil
+	}
+	var rows []histdbRow
+	if err := querySQLite(filename, fmt.Sprintf(histdbQuery, r.maxLines), &rows); err != nil {
+		return nil, err
+	}
+	r.truncated = r.truncated || len(rows) == r.maxLines
+
+	commands := make([]Command, 0, len(rows))
+	for i := len(rows) - 1; i >= 0; i-- {
+		row := rows[i]
+		if r.filters.DropSpaced && typedWithSpace(row.Argv) {
+			continue
+		}
+		cmd := Command{
+			Text:      row.Argv,
+			Position:  row.ID,
+			Directory: row.Dir,
+		}
+		if row.StartTime != nil && *row.StartTime > 0 {
+			cmd.Timestamp = time.Unix(*row.StartTime, 0)
+		}
+		if row.Duration != nil && *row.Duration >= 0 {
+			cmd.Duration = time.Duration(*row.Duration) * time.Second
+			cmd.HasDuration = true
+		}
+		if row.ExitStatus != nil {
+			cmd.ExitCode = *row.ExitStatus
+			cmd.HasExit = true
+		}
+
+		if !r.keep(&cmd) {
+			continue
+		}
+		cmd.Source = filename
+		commands = append(commands, cmd)
+	}
+	return commands, nil
+}
+
+// directoryHistoryBase returns the directory the per-directory-history zsh
+// plugin keeps its files in
+func directoryHistoryBase() string {
+	if base := os.Getenv("HISTORY_BASE"); base != "" {
+		return base
+	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".directory_history")
+}
+
+// historyDirectory returns the directory whose commands a history file of
+// the per-directory-history plugin records, e.g. /src/app for
+// ~/.directory_history/src/app/history, or "" for any other file
+func historyDirectory(filename string) string {
+	rel, err := filepath.Rel(directoryHistoryBase(), filepath.Dir(filename))
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	return string(filepath.Separator) + rel
+}
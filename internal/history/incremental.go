@@ -0,0 +1,113 @@
+package history
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// fileMark records how far a history file was read, so ReadNew can parse
+// only the lines appended since
+type fileMark struct {
+	info     os.FileInfo // File read, to notice it being replaced; nil if it can't be continued
+	size     int64       // Bytes read
+	format   string      // Format the file was parsed as
+	lines    int         // Lines kept so far, which the positions of new ones follow
+	lastLine int         // Line number of the last line read
+}
+
+// advance moves the mark to the end of a read of size bytes of src. A file
+// not ending with a newline is still being written, so its last line may
+// be incomplete and it can only be read again in full.
+func (m *fileMark) advance(src io.ReaderAt, size int64, format string, lines, lastLine int) {
+	m.size, m.format, m.lines, m.lastLine = size, format, lines, lastLine
+	if size == 0 {
+		return
+	}
+	last := make([]byte, 1)
+	if _, err := src.ReadAt(last, size-1); err != nil || last[0] != '\n' {
+		m.info = nil
+	}
+}
+
+// ReadNew reads only the commands appended to the history files since the
+// last ReadHistory, ReadNew or LoadCache and reports true, counting them
+// among themselves. When it can't tell what is new, it reads the whole
+// history like ReadHistory and reports false: before the first read, with
+// stdin, remote or database sources, when a file shrank, was replaced,
+// appeared or is gone, and when a new command reads as the same as one read
+// before but with another text. Occurrences returns every run either way.
+func (r *Reader) ReadNew() ([]Command, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	readAll := func() ([]Command, bool, error) {
+		commands, err := r.readHistory()
+		return commands, false, err
+	}
+	if r.marks == nil {
+		return readAll()
+	}
+
+	var sources [][]Command
+	total := 0
+	r.skipped = nil
+	for _, source := range ExpandSources(r.sources) {
+		if source == StdinSource || IsRemote(source) {
+			return readAll()
+		}
+		mark := r.marks[source]
+		info, err := os.Stat(source)
+		if os.IsNotExist(err) && mark == nil {
+			continue
+		}
+		if err != nil || !mark.continues(info) {
+			return readAll()
+		}
+		if info.Size() == mark.size {
+			continue
+		}
+
+		commands, err := r.readNewFromFile(source, mark)
+		if err != nil {
+			return readAll()
+		}
+		sources = append(sources, commands)
+		total += len(commands)
+	}
+
+	previous := r.occurrences
+	commands := r.merge(sources, total)
+	for _, cmd := range commands {
+		if text := r.texts[r.normalization.Key(cmd.Text)]; text != cmd.Text {
+			return readAll()
+		}
+	}
+	r.occurrences = append(r.occurrences, previous...)
+	return commands, true, nil
+}
+
+// continues reports whether the file described by info is the one the mark
+// was made for, grown or unchanged since
+func (m *fileMark) continues(info os.FileInfo) bool {
+	return m != nil && m.info != nil && os.SameFile(m.info, info) && info.Size() >= m.size
+}
+
+// readNewFromFile reads the lines appended to a history file after its mark
+func (r *Reader) readNewFromFile(filename string, mark *fileMark) ([]Command, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !mark.continues(info) {
+		return nil, fmt.Errorf("%s was rewritten", filename)
+	}
+	mark.info = info
+	return r.readFrom(filename, file, info.Size(), mark)
+}
@@ -0,0 +1,174 @@
+package history
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// linearMerge is the merge that scanned every source for the newest
+// command at each step, kept to check merge against
+func linearMerge(sources [][]Command) []Command {
+	total := 0
+	next := make([]int, len(sources))
+	for i, commands := range sources {
+		next[i] = len(commands) - 1
+		total += len(commands)
+	}
+
+	var merged []Command
+	for n := 0; n < total; n++ {
+		newest := -1
+		for i, commands := range sources {
+			if next[i] < 0 {
+				continue
+			}
+			if newest == -1 || commands[next[i]].NewerThan(sources[newest][next[newest]]) {
+				newest = i
+			}
+		}
+		merged = append(merged, sources[newest][next[newest]])
+		next[newest]--
+	}
+	return merged
+}
+
+// randomSources returns n sources of random commands in file order. Each
+// source is timestamped, with repeats across and within sources, if
+// timestamped says so for its index.
+func randomSources(rng *rand.Rand, n, size int, timestamped func(source int) bool) [][]Command {
+	sources := make([][]Command, n)
+	for i := range sources {
+		timestamped := timestamped(i)
+		stamp := int64(1700000000 + rng.Intn(100))
+		lines := rng.Intn(size + 1)
+		for line := 0; line < lines; line++ {
+			cmd := Command{
+				Text:     fmt.Sprintf("cmd %d", rng.Intn(size)),
+				Position: line,
+				Source:   fmt.Sprintf("source %d", i),
+				Count:    1,
+			}
+			if timestamped {
+				stamp += int64(rng.Intn(3))
+				cmd.Timestamp = time.Unix(stamp, 0)
+			}
+			sources[i] = append(sources[i], cmd)
+		}
+	}
+	return sources
+}
+
+// TestMergeMatchesLinearMerge checks the heap merge puts runs in the same
+// order as scanning every source, and deduplicates them the same way. The
+// sources are all timestamped, like ~/.zsh_sessions files, or none is,
+// like plain bash files; mixing them leaves NewerThan without a consistent
+// order, which TestMergeKeepsFileOrder covers.
+func TestMergeMatchesLinearMerge(t *testing.T) {
+	for seed := int64(1); seed <= 200; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		timestamped := seed%2 == 0
+		sources := randomSources(rng, 1+rng.Intn(8), 30, func(int) bool { return timestamped })
+		total := 0
+		for _, commands := range sources {
+			total += len(commands)
+		}
+
+		reader := NewReader(nil)
+		reader.texts = make(map[string]string)
+		commands := reader.merge(sources, total)
+
+		want := linearMerge(sources)
+		if len(want) > 0 && !reflect.DeepEqual(reader.occurrences, want) || len(want) != len(reader.occurrences) {
+			t.Fatalf("seed %d: merged runs differ from the linear merge\ngot  %v\nwant %v", seed, reader.occurrences, want)
+		}
+
+		counts := make(map[string]int)
+		var first []string
+		for _, cmd := range want {
+			if counts[cmd.Text] == 0 {
+				first = append(first, cmd.Text)
+			}
+			counts[cmd.Text]++
+		}
+		if len(commands) != len(first) {
+			t.Fatalf("seed %d: %d commands, want %d", seed, len(commands), len(first))
+		}
+		for i, cmd := range commands {
+			if cmd.Text != first[i] || cmd.Count != counts[cmd.Text] {
+				t.Errorf("seed %d: command %d = %q count %d, want %q count %d",
+					seed, i, cmd.Text, cmd.Count, first[i], counts[first[i]])
+			}
+		}
+	}
+}
+
+// TestMergeKeepsFileOrder checks that with timestamped and untimestamped
+// sources mixed, every run is merged once and each source's runs stay in
+// file order
+func TestMergeKeepsFileOrder(t *testing.T) {
+	for seed := int64(1); seed <= 200; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		sources := randomSources(rng, 2+rng.Intn(6), 30, func(source int) bool { return source%2 == 0 })
+		total := 0
+		for _, commands := range sources {
+			total += len(commands)
+		}
+
+		reader := NewReader(nil)
+		reader.texts = make(map[string]string)
+		reader.merge(sources, total)
+
+		if len(reader.occurrences) != total {
+			t.Fatalf("seed %d: %d runs merged, want %d", seed, len(reader.occurrences), total)
+		}
+		last := make(map[string]int)
+		for _, cmd := range reader.occurrences {
+			if previous, found := last[cmd.Source]; found && cmd.Position >= previous {
+				t.Fatalf("seed %d: %s line %d merged after line %d", seed, cmd.Source, cmd.Position, previous)
+			}
+			last[cmd.Source] = cmd.Position
+		}
+	}
+}
+
+// sessionSources returns n per-session sources of size timestamped
+// commands each, interleaved in time like ~/.zsh_sessions files
+func sessionSources(n, size int) [][]Command {
+	sources := make([][]Command, n)
+	for i := range sources {
+		sources[i] = make([]Command, size)
+		for line := range sources[i] {
+			sources[i][line] = Command{
+				Text:      fmt.Sprintf("cmd %d", (i*size+line)%5000),
+				Position:  line,
+				Timestamp: time.Unix(int64(1700000000+line*n+i), 0),
+				Count:     1,
+			}
+		}
+	}
+	return sources
+}
+
+// BenchmarkMerge merges 500 session files of 100 commands each
+func BenchmarkMerge(b *testing.B) {
+	sources := sessionSources(500, 100)
+	reader := NewReader(nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reader.texts = make(map[string]string)
+		reader.merge(sources, 500*100)
+	}
+}
+
+// BenchmarkLinearMerge merges the same files by scanning every source for
+// the newest command, as merge did before
+func BenchmarkLinearMerge(b *testing.B) {
+	sources := sessionSources(500, 100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		linearMerge(sources)
+	}
+}
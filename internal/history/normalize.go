@@ -0,0 +1,89 @@
+package history
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Normalization holds the rules that decide when two commands count as the
+// same one. Commands with equal normalized text are deduplicated into one
+// entry that shows the most recent variant.
+type Normalization struct {
+	CollapseSpaces   bool // Treat runs of spaces and tabs outside quotes as one space
+	StripEnv         bool // Ignore leading environment assignments, as in FOO=bar cmd
+	LowercaseCommand bool // Ignore the case of the command word
+}
+
+// DefaultNormalization returns the default normalization rules
+func DefaultNormalization() Normalization {
+	return Normalization{
+		CollapseSpaces: true,
+		StripEnv:       true,
+	}
+}
+
+// envAssignmentPattern matches the environment assignments at the start of
+// a command, with unquoted, single- or double-quoted values
+var envAssignmentPattern = regexp.MustCompile(`^(?:[A-Za-z_][A-Za-z0-9_]*=(?:'[^']*'|"(?:[^"\\]|\\.)*"|[^\s'"]*)[ \t]+)+`)
+
+// Key returns the text commands are deduplicated by
+func (n Normalization) Key(text string) string {
+	if n.CollapseSpaces {
+		text = collapseSpaces(text)
+	}
+	if n.StripEnv {
+		// A command of only assignments sets shell variables, so keep it
+		if stripped := envAssignmentPattern.ReplaceAllString(text, ""); stripped != "" {
+			text = stripped
+		}
+	}
+	if n.LowercaseCommand {
+		end := strings.IndexAny(text, " \t\n")
+		if end < 0 {
+			end = len(text)
+		}
+		text = strings.ToLower(text[:end]) + text[end:]
+	}
+	return text
+}
+
+// collapseSpaces replaces runs of spaces and tabs with one space, except
+// inside quotes where spacing is part of the argument
+func collapseSpaces(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	var quote byte
+	space := false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' && i+1 < len(text) {
+				b.WriteByte(c)
+				i++
+				c = text[i]
+			}
+		case c == ' ' || c == '\t':
+			space = true
+			continue
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '\\' && i+1 < len(text):
+			if space {
+				b.WriteByte(' ')
+				space = false
+			}
+			b.WriteByte(c)
+			i++
+			c = text[i]
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,62 @@
+package history
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNormalizationKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules Normalization
+		text  string
+		want  string
+	}{
+		{"off", Normalization{}, "FOO=bar  Git\tstatus", "FOO=bar  Git\tstatus"},
+		{"collapse spaces", Normalization{CollapseSpaces: true}, "git  \t status", "git status"},
+		{"collapse keeps quoted spaces", Normalization{CollapseSpaces: true}, `echo  "a   b"  'c   d'`, `echo "a   b" 'c   d'`},
+		{"collapse keeps escaped spaces", Normalization{CollapseSpaces: true}, `ls  my\  file`, `ls my\  file`},
+		{"collapse keeps escaped quotes", Normalization{CollapseSpaces: true}, `echo "a \"  b"  c`, `echo "a \"  b" c`},
+		{"strip env", Normalization{StripEnv: true}, "FOO=bar git status", "git status"},
+		{"strip several", Normalization{StripEnv: true}, `A=1 B='x y' C="z" make`, "make"},
+		{"strip keeps assignments only", Normalization{StripEnv: true}, "FOO=bar", "FOO=bar"},
+		{"strip keeps later assignments", Normalization{StripEnv: true}, "make FOO=bar", "make FOO=bar"},
+		{"lowercase command", Normalization{LowercaseCommand: true}, "Git Status", "git Status"},
+		{"lowercase one word", Normalization{LowercaseCommand: true}, "LS", "ls"},
+		{"all", Normalization{CollapseSpaces: true, StripEnv: true, LowercaseCommand: true}, "FOO=bar   Git  status", "git status"},
+	}
+	for _, tt := range tests {
+		if got := tt.rules.Key(tt.text); got != tt.want {
+			t.Errorf("%s: Key(%q) = %q, want %q", tt.name, tt.text, got, tt.want)
+		}
+	}
+}
+
+// TestNormalizedDedupe checks normalized variants count as one command
+// shown as the most recent variant, and stay apart with the rules off
+func TestNormalizedDedupe(t *testing.T) {
+	path := writeHistory(t, ".zsh_history",
+		": 1700000000:0;git status",
+		": 1700000001:0;git  status",
+		": 1700000002:0;FOO=bar git status",
+		": 1700000003:0;ls",
+	)
+
+	reader := NewReader([]string{path})
+	commands, err := reader.ReadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(texts(commands)); got != "[ls FOO=bar git status]" || commands[1].Count != 3 {
+		t.Errorf("commands = %s with counts %+v, want FOO=bar git status counted 3 times", got, commands)
+	}
+
+	reader.SetNormalization(Normalization{})
+	commands, err = reader.ReadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != 4 {
+		t.Errorf("commands without normalization = %q, want all 4 apart", texts(commands))
+	}
+}
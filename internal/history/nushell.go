@@ -0,0 +1,95 @@
+package history
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// nushellDatabase is the name of NuShell's history file with the sqlite
+// backend; the plain backend writes history.txt
+const nushellDatabase = "history.sqlite3"
+
+// nushellNewline is how NuShell's plain history file writes line breaks
+// inside a command
+const nushellNewline = `<\n>`
+
+// nushellQuery selects the newest commands recorded by NuShell, limited to
+// a number of rows
+const nushellQuery = `SELECT id, command_line, start_timestamp, cwd, duration_ms, exit_status
+FROM history
+ORDER BY id DESC
+LIMIT %d`
+
+// nushellRow is one command as printed by sqlite3 -json. Metadata columns
+// are NULL when NuShell didn't record them.
+type nushellRow struct {
+	ID             int     `json:"id"`
+	CommandLine    string  `json:"command_line"`
+	StartTimestamp *int64  `json:"start_timestamp"` // Milliseconds since the epoch
+	Cwd            *string `json:"cwd"`
+	DurationMs     *int64  `json:"duration_ms"`
+	ExitStatus     *int    `json:"exit_status"`
+}
+
+// isNushellDatabase reports whether a SQLite history file was written by
+// NuShell rather than zsh-histdb
+func isNushellDatabase(filename string) bool {
+	return filepath.Base(filename) == nushellDatabase
+}
+
+// parseNushellLine parses a line of NuShell's plain history file
+func parseNushellLine(line string, lineNum int) Command {
+	return Command{
+		Text:     strings.TrimSpace(strings.ReplaceAll(line, nushellNewline, "\n")),
+		Position: lineNum,
+	}
+}
+
+// readNushellDatabase reads the last maxLines commands from NuShell's
+// sqlite history, oldest first, with the directory, exit code and timing of
+// each
+func (r *Reader) readNushellDatabase(filename string) ([]Command, error) {
+	if r.maxLines <= 0 {
+		return nil, nil
+	}
+	var rows []nushellRow
+	if err := querySQLite(filename, fmt.Sprintf(nushellQuery, r.maxLines), &rows); err != nil {
+		return nil, err
+	}
+	r.truncated = r.truncated || len(rows) == r.maxLines
+
+	commands := make([]Command, 0, len(rows))
+	for i := len(rows) - 1; i >= 0; i-- {
+		row := rows[i]
+		if r.filters.DropSpaced && typedWithSpace(row.CommandLine) {
+			continue
+		}
+		cmd := Command{
+			Text:     row.CommandLine,
+			Position: row.ID,
+		}
+		if row.StartTimestamp != nil && *row.StartTimestamp > 0 {
+			cmd.Timestamp = time.UnixMilli(*row.StartTimestamp)
+		}
+		if row.Cwd != nil {
+			cmd.Directory = *row.Cwd
+		}
+		if row.DurationMs != nil && *row.DurationMs >= 0 {
+			cmd.Duration = time.Duration(*row.DurationMs) * time.Millisecond
+			cmd.HasDuration = true
+		}
+		if row.ExitStatus != nil {
+			cmd.ExitCode = *row.ExitStatus
+			cmd.HasExit = true
+		}
+
+		if !r.keep(&cmd) {
+			continue
+		}
+		cmd.Source = filename
+		commands = append(commands, cmd)
+	}
+	return commands, nil
+}
@@ -2,140 +2,401 @@ package history
 
 import (
 	"bufio"
+	"bytes"
+	"container/heap"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"os"
-	"path/filepath"
 	"regexp"
-	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/4ndew/terminal-history-navigator/internal/redact"
 )
 
 // Command represents a shell command with metadata
 type Command struct {
-	Text      string
-	Position  int // Position in history file (higher = newer)
-	Directory string
-	Count     int
-	ExitCode  int  // Exit code if available
-	HasExit   bool // Whether exit code is available
+	Text         string
+	Position     int           // Position in history file (higher = newer)
+	Timestamp    time.Time     // Time the command was run, zero if not recorded
+	Duration     time.Duration // How long the command ran, if HasDuration
+	BadTimestamp bool          // Whether the history recorded a timestamp that couldn't be parsed
+	Inferred     bool          // Whether Timestamp was taken from the run before, as the history recorded none
+	HasDuration  bool          // Whether the history recorded the duration
+	Directory    string
+	Source       string // History file the command was read from
+	Line         int    // Line number in Source, from 1; 0 if unknown
+	Count        int
+	ExitCode     int  // Exit code if available
+	HasExit      bool // Whether exit code is available
+	Truncated    bool // Whether Text was cut to the maximum command length
+	FullSize     int  // Size in bytes of the original text if Truncated
 }
 
-// Reader handles reading command history from files
+// StdinSource is the source name for history piped to standard input
+const StdinSource = "-"
+
+// maxLineSize is the longest history line read; longer lines fail the file
+const maxLineSize = 16 * 1024 * 1024
+
+// Reader handles reading command history from files. Its methods are
+// safe to call concurrently: a refresh reads in the background while the
+// UI deletes commands.
 type Reader struct {
+	mu sync.Mutex // Guards everything below
+
 	sources         []string
 	excludePatterns []*regexp.Regexp
+	includePatterns []*regexp.Regexp
+	keepPatterns    []*regexp.Regexp
 	maxLines        int // Maximum lines to read from each file
+	filters         Filters
+	format          string        // Forced history format, "" to detect per file
+	maxLength       int           // Maximum command length in runes, 0 for no limit
+	skipLong        bool          // Drop commands over maxLength instead of truncating them
+	skipped         []error       // Sources that failed to read during the last ReadHistory
+	truncated       bool          // Whether the last ReadHistory left out older lines of a source
+	occurrences     []Command     // Every command read by the last ReadHistory, before deduplication
+	stdin           []byte        // History piped in for StdinSource, nil if none
+	remoteTimeout   time.Duration // Time allowed to read each remote source
+	secretsMode     string        // "redact" or "drop" commands with likely secrets, "" or "off" to keep them
+	normalization   Normalization // When commands count as the same for deduplication
+	cachePath       string        // File the parsed history is cached in, "" for none
+
+	// Kept between reads for ReadNew
+	marks map[string]*fileMark // How far each history file was read, nil before ReadHistory
+	texts map[string]string    // Text shown for each normalized command read
+}
+
+// Filters holds the thresholds used to drop noisy commands
+type Filters struct {
+	MinLength            int           // Minimum command length in characters
+	DropNumeric          bool          // Drop commands that are just numbers
+	DropBinary           bool          // Drop commands with NUL bytes or only invalid bytes
+	DropSpaced           bool          // Drop commands typed with a leading space, like ignorespace
+	DropFutureTimestamps bool          // Drop commands timestamped in the future, allowing FutureSkew
+	FutureSkew           time.Duration // Clock skew allowed by DropFutureTimestamps (0 also disables it)
+	MaxAge               time.Duration // Drop commands timestamped longer ago than this (0 keeps all)
+}
+
+// DefaultFilters returns the default noise-filter thresholds
+func DefaultFilters() Filters {
+	return Filters{
+		MinLength:            1,
+		DropNumeric:          true,
+		DropBinary:           true,
+		DropSpaced:           true,
+		DropFutureTimestamps: true,
+		FutureSkew:           time.Hour,
+	}
 }
 
 // NewReader creates a new history reader with given sources
 func NewReader(sources []string) *Reader {
 	return &Reader{
-		sources:  sources,
-		maxLines: 5000, // Default limit
+		sources:       sources,
+		maxLines:      5000, // Default limit
+		filters:       DefaultFilters(),
+		normalization: DefaultNormalization(),
 	}
 }
 
+// SetSources sets the history files to read
+func (r *Reader) SetSources(sources []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sources = sources
+	r.marks = nil
+}
+
+// SetStdin sets the history read for the StdinSource source. Standard
+// input can only be read once, so it is kept for every ReadHistory.
+func (r *Reader) SetStdin(data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stdin = data
+}
+
+// SetNormalization sets the rules deciding when commands are duplicates
+func (r *Reader) SetNormalization(normalization Normalization) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.normalization = normalization
+}
+
+// SetSecretsMode sets what happens to commands with likely secrets, found
+// by redact.Scrub: "redact" replaces the secrets, "drop" leaves the commands
+// out and "off" keeps them unchanged
+func (r *Reader) SetSecretsMode(mode string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.secretsMode = mode
+}
+
+// SetRemoteTimeout sets how long reading each ssh:// source may take
+func (r *Reader) SetRemoteTimeout(timeout time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.remoteTimeout = timeout
+}
+
 // SetMaxLines sets the maximum number of lines to read from each file
 func (r *Reader) SetMaxLines(maxLines int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.maxLines = maxLines
 }
 
+// SetFormat forces every source to be parsed as "zsh", "bash", "tcsh",
+// "nushell" or "auto" instead of detecting the format from its content. "" restores
+// detection.
+func (r *Reader) SetFormat(format string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.format = format
+}
+
+// SetMaxCommandLength limits command text to maxLength runes. Longer
+// commands are dropped if skip is set, otherwise truncated and marked.
+func (r *Reader) SetMaxCommandLength(maxLength int, skip bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxLength = maxLength
+	r.skipLong = skip
+}
+
+// SetFilters sets the noise-filter thresholds
+func (r *Reader) SetFilters(filters Filters) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.filters = filters
+}
+
 // SetExcludePatterns sets regex patterns for commands to exclude
 func (r *Reader) SetExcludePatterns(patterns []string) error {
-	r.excludePatterns = make([]*regexp.Regexp, 0, len(patterns))
+	regexes, err := compilePatterns(patterns)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.excludePatterns = regexes
+	return nil
+}
+
+// SetIncludePatterns sets regex patterns for commands to keep. When any are
+// set, a command must match at least one of them to be shown.
+func (r *Reader) SetIncludePatterns(patterns []string) error {
+	regexes, err := compilePatterns(patterns)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.includePatterns = regexes
+	return nil
+}
+
+// SetKeepPatterns sets regex patterns for commands to show whatever the
+// exclude and include patterns say, e.g. "^kubectl get secrets" despite an
+// exclude of "secret"
+func (r *Reader) SetKeepPatterns(patterns []string) error {
+	regexes, err := compilePatterns(patterns)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keepPatterns = regexes
+	return nil
+}
+
+// compilePatterns compiles a list of regex patterns
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	regexes := make([]*regexp.Regexp, 0, len(patterns))
 
 	for _, pattern := range patterns {
 		regex, err := regexp.Compile(pattern)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		r.excludePatterns = append(r.excludePatterns, regex)
+		regexes = append(regexes, regex)
 	}
 
-	return nil
+	return regexes, nil
 }
 
 // ReadHistory reads command history from all configured sources
 func (r *Reader) ReadHistory() ([]Command, error) {
-	var allCommands []Command
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.readHistory()
+}
 
-	for _, source := range r.sources {
-		// Check if file exists
-		if _, err := os.Stat(source); os.IsNotExist(err) {
-			continue
+// readHistory reads command history from all configured sources, with
+// r.mu held
+func (r *Reader) readHistory() ([]Command, error) {
+	var sources [][]Command
+	total := 0
+	r.skipped = nil
+	r.truncated = false
+	r.marks = make(map[string]*fileMark)
+
+	for _, source := range ExpandSources(r.sources) {
+		var commands []Command
+		var err error
+		if source == StdinSource {
+			// Without piped input there is nothing to read, like a
+			// missing file
+			if r.stdin == nil {
+				continue
+			}
+			commands, err = r.readFrom(source, bytes.NewReader(r.stdin), int64(len(r.stdin)), nil)
+		} else if IsRemote(source) {
+			var data []byte
+			if data, err = r.fetchRemote(source); err == nil {
+				commands, err = r.readFrom(source, bytes.NewReader(data), int64(len(data)), nil)
+			}
+		} else {
+			// Check if file exists
+			if _, err := os.Stat(source); os.IsNotExist(err) {
+				continue
+			}
+			commands, err = r.readFromFile(source)
 		}
-
-		commands, err := r.readFromFile(source)
 		if err != nil {
-			continue // Skip problematic files but don't fail completely
+			// Skip problematic files but don't fail completely
+			r.skipped = append(r.skipped, fmt.Errorf("%s: %w", source, err))
+			continue
 		}
 
-		allCommands = append(allCommands, commands...)
+		sources = append(sources, commands)
+		total += len(commands)
 	}
 
-	// Filter out problematic commands before sorting
-	allCommands = r.filterProblematicCommands(allCommands)
-
-	// Sort all commands by position (newest first - higher position = newer)
-	sort.Slice(allCommands, func(i, j int) bool {
-		return allCommands[i].Position > allCommands[j].Position
-	})
+	r.texts = make(map[string]string)
+	return r.merge(sources, total), nil
+}
 
-	// Deduplicate and count frequency
-	commandMap := make(map[string]*Command)
+// merge merges the commands of each source newest first into
+// r.occurrences, returning them deduplicated with their counts
+func (r *Reader) merge(sources [][]Command, total int) []Command {
+	// Merge the sources newest first. Each source is already in file order,
+	// so this needs no sort, only a heap of the newest remaining command of
+	// each source, and the first time a command is seen is its most recent
+	// appearance.
+	commandMap := make(map[string]int)
 	var result []Command
+	r.occurrences = make([]Command, 0, total)
 
-	for _, cmd := range allCommands {
-		// Skip excluded commands
-		if r.shouldExclude(cmd.Text) {
-			continue
+	heads := make(sourceHeap, 0, len(sources))
+	for i, commands := range sources {
+		if len(commands) > 0 {
+			heads = append(heads, sourceHead{commands: commands, next: len(commands) - 1, source: i})
 		}
+	}
+	heap.Init(&heads)
 
-		// Clean command text
-		cleanText := strings.TrimSpace(cmd.Text)
-		if cleanText == "" {
-			continue
+	for len(heads) > 0 {
+		head := &heads[0]
+		cmd := head.commands[head.next]
+		if head.next--; head.next < 0 {
+			heap.Pop(&heads)
+		} else {
+			heap.Fix(&heads, 0)
 		}
 
-		if existing, found := commandMap[cleanText]; found {
-			// Increment count and keep highest position (most recent appearance)
-			existing.Count++
-			if cmd.Position > existing.Position {
-				existing.Position = cmd.Position
-				existing.ExitCode = cmd.ExitCode
-				existing.HasExit = cmd.HasExit
-			}
-		} else {
-			// First occurrence - add to result and map
-			newCmd := cmd
-			newCmd.Text = cleanText
-			newCmd.Count = 1
-			commandMap[cleanText] = &newCmd
-			result = append(result, newCmd)
+		r.occurrences = append(r.occurrences, cmd)
+
+		// Deduplicate and count frequency, mapping normalized text to its
+		// index in result. The newest variant is seen first, so its text is
+		// the one shown.
+		key := r.normalization.Key(cmd.Text)
+		if index, found := commandMap[key]; found {
+			result[index].Count++
+			continue
+		}
+		commandMap[key] = len(result)
+		result = append(result, cmd)
+		if _, found := r.texts[key]; !found {
+			r.texts[key] = cmd.Text
 		}
 	}
 
-	// Re-sort result by position after deduplication (newest first)
-	sort.Slice(result, func(i, j int) bool {
-		return result[i].Position > result[j].Position
-	})
+	return result
+}
 
-	return result, nil
+// sourceHead is the newest command of a source not merged yet
+type sourceHead struct {
+	commands []Command // The source's commands, oldest first
+	next     int       // Index of the newest command not merged yet
+	source   int       // Index of the source, which breaks ties
 }
 
-// filterProblematicCommands removes commands that cause display issues
-func (r *Reader) filterProblematicCommands(commands []Command) []Command {
-	var filtered []Command
+// sourceHeap orders sources by their newest remaining command, newest
+// first; ties go to the earlier source. It implements heap.Interface.
+type sourceHeap []sourceHead
 
-	for _, cmd := range commands {
-		// Skip commands that are clearly problematic
-		if r.isProblematicCommand(cmd) {
-			continue
-		}
-		filtered = append(filtered, cmd)
+func (h sourceHeap) Len() int { return len(h) }
+
+func (h sourceHeap) Less(i, j int) bool {
+	a, b := h[i].commands[h[i].next], h[j].commands[h[j].next]
+	if a.NewerThan(b) {
+		return true
 	}
+	return !b.NewerThan(a) && h[i].source < h[j].source
+}
+
+func (h sourceHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
 
-	return filtered
+func (h *sourceHeap) Push(x any) { *h = append(*h, x.(sourceHead)) }
+
+func (h *sourceHeap) Pop() any {
+	old := *h
+	head := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return head
+}
+
+// NewerThan reports whether c ran after other. Commands of different files,
+// such as the overlapping per-session files of macOS zsh, are compared by
+// timestamp when both have one, since positions are only meaningful within
+// one file; otherwise the higher position is newer.
+func (c Command) NewerThan(other Command) bool {
+	if !c.Timestamp.IsZero() && !other.Timestamp.IsZero() && !c.Timestamp.Equal(other.Timestamp) {
+		return c.Timestamp.After(other.Timestamp)
+	}
+	return c.Position > other.Position
+}
+
+// Truncated reports whether the last ReadHistory read only the last
+// maxLines lines or rows of a source, so counts leave out older runs
+func (r *Reader) Truncated() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.truncated
+}
+
+// Skipped returns the errors for sources that could not be read during the
+// last ReadHistory. Missing files are not reported.
+func (r *Reader) Skipped() []error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.skipped
+}
+
+// Occurrences returns every command read by the last ReadHistory in the
+// same order (newest first) but without deduplication, so repeated commands
+// keep the timestamp of each run
+func (r *Reader) Occurrences() []Command {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.occurrences
 }
 
 // isProblematicCommand checks if a command should be filtered out
@@ -146,17 +407,39 @@ func (r *Reader) isProblematicCommand(cmd Command) bool {
 		return true
 	}
 
-	// Filter out commands with control characters
-	if strings.Contains(cleanText, "\x00") || strings.Contains(cleanText, "\xff") {
+	// Filter out commands that are too short
+	if utf8.RuneCountInString(cleanText) < r.filters.MinLength {
+		return true
+	}
+
+	// Filter out binary garbage: NUL bytes, or nothing but invalid bytes
+	if r.filters.DropBinary && (strings.Contains(cleanText, "\x00") || strings.Trim(cleanText, "\uFFFD \t") == "") {
 		return true
 	}
 
 	// Filter out commands that are just numbers
-	if isJustNumber(cleanText) {
+	if r.filters.DropNumeric && isJustNumber(cleanText) {
 		return true
 	}
 
-	return false
+	return r.outsideTimeFilters(cmd, time.Now())
+}
+
+// outsideTimeFilters reports whether a command is timestamped too far in
+// the future or longer ago than the retention period, as of now
+func (r *Reader) outsideTimeFilters(cmd Command, now time.Time) bool {
+	if cmd.Timestamp.IsZero() {
+		return false
+	}
+
+	// Filter out commands with timestamps too far in the future
+	if r.filters.DropFutureTimestamps && r.filters.FutureSkew > 0 &&
+		cmd.Timestamp.After(now.Add(r.filters.FutureSkew)) {
+		return true
+	}
+
+	// Filter out commands older than the retention period
+	return r.filters.MaxAge > 0 && cmd.Timestamp.Before(now.Add(-r.filters.MaxAge))
 }
 
 // isJustNumber checks if a string contains only digits
@@ -172,7 +455,9 @@ func isJustNumber(s string) bool {
 	return true
 }
 
-// readFromFile reads commands from a specific history file
+// readFromFile reads the last maxLines lines of a history file, or runs of
+// a zsh-histdb or NuShell database, and returns the commands that pass the filters,
+// oldest first, with cleaned text
 func (r *Reader) readFromFile(filename string) ([]Command, error) {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -180,60 +465,257 @@ func (r *Reader) readFromFile(filename string) ([]Command, error) {
 	}
 	defer file.Close()
 
-	// Read all lines
-	var lines []string
-	scanner := bufio.NewScanner(file)
+	if isSQLite(file) {
+		if isNushellDatabase(filename) {
+			return r.readNushellDatabase(filename)
+		}
+		return r.readHistdb(filename)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	mark := &fileMark{info: info}
+	commands, err := r.readFrom(filename, file, info.Size(), mark)
+	if err == nil {
+		r.marks[filename] = mark
+	}
+	return commands, err
+}
+
+// readFrom reads the last maxLines lines of a history of the given size
+// from src, named filename in the commands. A mark, if not nil, says where
+// a previous read stopped: only the lines after it are read, and it is
+// moved past them.
+func (r *Reader) readFrom(filename string, src io.ReaderAt, size int64, mark *fileMark) ([]Command, error) {
+	// Keep only the last N lines (most recent commands), starting the scan
+	// near the end of the file and holding them in a ring buffer
+	maxLines := r.maxLines
+	if maxLines <= 0 {
+		return nil, nil
+	}
+	var from int64    // Byte offset reading starts at
+	linesBefore := 0  // Lines in the file before from
+	positionBase := 0 // Position of the first line after from
+	if mark != nil {
+		from, linesBefore, positionBase = mark.size, mark.lastLine, mark.lines
+	}
+	section := io.NewSectionReader(src, from, size-from)
+	start, err := findTail(section, size-from, maxLines)
+	if err != nil {
+		return nil, err
+	}
+
+	ring := make([]string, 0, min(maxLines, 4096))
+	oldest := 0
+	lineCount := linesBefore + start.linesBefore
+	cutContinued := start.cutContinued // Whether the last line dropped before the ring continues on the next
+
+	scanner := bufio.NewScanner(io.NewSectionReader(section, start.offset, size-from-start.offset))
+	scanner.Buffer(nil, maxLineSize)
 	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+		lineCount++
+		if len(ring) < maxLines {
+			ring = append(ring, scanner.Text())
+			continue
+		}
+		cutContinued = strings.HasSuffix(ring[oldest], "\\")
+		ring[oldest] = scanner.Text()
+		oldest = (oldest + 1) % maxLines
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
+	r.truncated = r.truncated || lineCount > len(ring)
 
-	// Take only the last N lines (most recent commands)
-	maxLines := r.maxLines
-	if len(lines) > maxLines {
-		lines = lines[len(lines)-maxLines:]
+	// Parse lines based on their format, sniffed from the content unless
+	// forced
+	format := r.format
+	if format == "" && mark != nil {
+		format = mark.format
+	}
+	if format == "" {
+		format = DetectFormat(ringLines(ring, oldest, sniffLines))
+		if format == "" {
+			format = FileFormat(filename)
+		}
+	}
+	if format == "fish" {
+		return nil, errors.New("fish history is not supported")
+	}
+	if mark != nil {
+		mark.advance(src, size, format, positionBase+len(ring), lineCount)
 	}
 
-	// Parse lines based on file type
-	var commands []Command
+	// Line number in the file of the oldest line kept
+	firstLine := lineCount - len(ring) + 1
+
+	// Skip the rest of a multi-line command whose start was cut off
+	skip := 0
+	if cutContinued && joinsLines(format) {
+		for skip < len(ring) && strings.HasSuffix(ring[(oldest+skip)%len(ring)], "\\") {
+			skip++
+		}
+		skip++
+	}
+
+	// Files of the per-directory-history plugin record one directory
+	directory := historyDirectory(filename)
 
-	for i, line := range lines {
+	var commands []Command
+	var stamp time.Time // Timestamp comment for the next bash command
+	for i := skip; i < len(ring); i++ {
+		line := ring[(oldest+i)%len(ring)]
 		if strings.TrimSpace(line) == "" {
 			continue
 		}
 
+		// zsh ends every line of a multi-line command but the last with a
+		// backslash; join them into one command with embedded newlines
+		start := i
+		if joinsLines(format) {
+			for strings.HasSuffix(line, "\\") && i+1 < len(ring) {
+				i++
+				line = strings.TrimSuffix(line, "\\") + "\n" + ring[(oldest+i)%len(ring)]
+			}
+		}
+
+		// Without cmdhist, bash saves each line of a multi-line command as
+		// its own entry; join heredocs and continued lines back up
+		if format == "bash" {
+			line, i = joinBashLines(ring, oldest, i)
+		}
+
+		// With HISTTIMEFORMAT set, bash writes a "#<epoch>" line before
+		// each command, and tcsh a "#+<epoch>" line
+		if format != "zsh" && format != "nushell" {
+			if timestamp, ok := commentTimestamp(line); ok {
+				stamp = timestamp
+				continue
+			}
+		}
+
+		// Shells with ignorespace never save these; honor it for files
+		// written without the option
+		if r.filters.DropSpaced && typedWithSpace(line) {
+			stamp = time.Time{}
+			continue
+		}
+
 		var cmd Command
-		switch {
-		case strings.Contains(filename, "zsh"):
-			cmd = r.parseZshLine(line, i)
-		case strings.Contains(filename, "bash") || filepath.Ext(filename) == ".bash_history":
+		switch format {
+		case "zsh":
+			cmd = r.parseZshLine(unmetafy(line), positionBase+start)
+		case "nushell":
+			cmd = parseNushellLine(line, positionBase+start)
+		case "bash", "tcsh":
 			cmd = Command{
-				Text:     strings.TrimSpace(line),
-				Position: i, // Position in file
+				Text:      strings.TrimSpace(line),
+				Position:  positionBase + start, // Position in file
+				Timestamp: stamp,
 			}
 		default:
 			// Try zsh format first, then fallback
 			if strings.HasPrefix(strings.TrimSpace(line), ":") {
-				cmd = r.parseZshLine(line, i)
+				cmd = r.parseZshLine(unmetafy(line), positionBase+start)
 			} else {
 				cmd = Command{
-					Text:     strings.TrimSpace(line),
-					Position: i, // Position in file
+					Text:      strings.TrimSpace(line),
+					Position:  positionBase + start, // Position in file
+					Timestamp: stamp,
 				}
 			}
 		}
+		stamp = time.Time{}
 
-		if cmd.Text != "" {
-			commands = append(commands, cmd)
+		// Filter while parsing so dropped commands are never collected
+		if !r.keep(&cmd) {
+			continue
 		}
+		cmd.Source = filename
+		cmd.Line = firstLine + start
+		cmd.Directory = directory
+		commands = append(commands, cmd)
 	}
 
 	return commands, nil
 }
 
+// keep cleans the text of a parsed command and reports whether it passes
+// the filters, truncating it to the maximum command length
+func (r *Reader) keep(cmd *Command) bool {
+	// Keep commands with stray bytes, e.g. latin-1 file names, but
+	// replace the bytes so the text is valid UTF-8 for display
+	cmd.Text = strings.ToValidUTF8(cmd.Text, "\uFFFD")
+
+	if cmd.Text == "" || r.isProblematicCommand(*cmd) || r.shouldExclude(cmd.Text) {
+		return false
+	}
+
+	if r.secretsMode == "redact" || r.secretsMode == "drop" {
+		scrubbed, found := redact.Scrub(cmd.Text)
+		if found && r.secretsMode == "drop" {
+			return false
+		}
+		cmd.Text = scrubbed
+	}
+
+	cmd.Text = strings.TrimSpace(cmd.Text)
+	if r.maxLength > 0 && utf8.RuneCountInString(cmd.Text) > r.maxLength {
+		if r.skipLong {
+			return false
+		}
+		cmd.FullSize = len(cmd.Text)
+		cmd.Text = truncateRunes(cmd.Text, r.maxLength)
+		cmd.Truncated = true
+	}
+	cmd.Count = 1
+	return true
+}
+
+// joinsLines reports whether a trailing backslash continues a command on
+// the next line, as zsh writes multi-line commands
+func joinsLines(format string) bool {
+	return format == "zsh" || format == "auto"
+}
+
+// ringLines returns up to n non-empty lines of the ring, oldest first
+func ringLines(ring []string, oldest, n int) []string {
+	var lines []string
+	for i := 0; i < len(ring) && len(lines) < n; i++ {
+		if line := ring[(oldest+i)%len(ring)]; strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// truncateRunes returns the first n runes of s
+func truncateRunes(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+// zshExtendedPrefix matches the ": <time>:<duration>;" metadata that zsh's
+// extended format writes before each command
+var zshExtendedPrefix = regexp.MustCompile(`^: *[0-9]+:[0-9]*(?::-?[0-9]+)?;`)
+
+// typedWithSpace reports whether the command on a history line was typed
+// with a leading space, which HISTCONTROL=ignorespace and HIST_IGNORE_SPACE
+// keep out of history. In zsh's extended format the space follows the ;
+// separator.
+func typedWithSpace(line string) bool {
+	line = strings.TrimPrefix(line, zshExtendedPrefix.FindString(line))
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+}
+
 // parseZshLine parses a single zsh history line
 func (r *Reader) parseZshLine(line string, lineNum int) Command {
 	line = strings.TrimSpace(line)
@@ -271,6 +753,9 @@ func (r *Reader) parseZshLine(line string, lineNum int) Command {
 	var hasExit bool
 
 	parts := strings.Split(metadataPart, ":")
+	timestamp := parseTimestamp(parts[0])
+	badTimestamp := timestamp.IsZero() && strings.TrimSpace(parts[0]) != ""
+	duration, hasDuration := parseDuration(parts)
 	// Check for exit code (third part in format timestamp:duration:exitcode)
 	if len(parts) >= 3 && parts[2] != "" {
 		if code, err := strconv.Atoi(parts[2]); err == nil {
@@ -283,16 +768,92 @@ func (r *Reader) parseZshLine(line string, lineNum int) Command {
 	command := strings.TrimSpace(line[semiIndex+1:])
 
 	return Command{
-		Text:     command,
-		Position: lineNum, // Position in file
-		ExitCode: exitCode,
-		HasExit:  hasExit,
+		Text:         command,
+		Position:     lineNum, // Position in file
+		Timestamp:    timestamp,
+		BadTimestamp: badTimestamp,
+		Duration:     duration,
+		HasDuration:  hasDuration,
+		ExitCode:     exitCode,
+		HasExit:      hasExit,
 	}
 }
 
-// shouldExclude checks if a command should be excluded based on patterns
+// parseDuration parses the elapsed seconds of a zsh extended history line
+// from its metadata parts (timestamp:duration[:exitcode]). Missing, negative
+// and absurdly large values count as unknown.
+func parseDuration(parts []string) (time.Duration, bool) {
+	if len(parts) < 2 {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+	if err != nil || seconds < 0 || seconds > int64(math.MaxInt64/time.Second) {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// commentTimestamp parses the timestamp comment written before a command,
+// "#<epoch>" by bash and "#+<epoch>" by tcsh, returning false for any other
+// line
+func commentTimestamp(line string) (time.Time, bool) {
+	digits, found := strings.CutPrefix(strings.TrimSpace(line), "#")
+	digits = strings.TrimPrefix(digits, "+")
+	if !found || digits == "" {
+		return time.Time{}, false
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return time.Time{}, false
+		}
+	}
+	timestamp := parseTimestamp(digits)
+	return timestamp, !timestamp.IsZero()
+}
+
+// Epoch bounds for parseTimestamp. Values from minMilliEpoch on are in
+// milliseconds, as some tools write them: 13 digits, from 2001 on. A
+// seconds epoch that large would be after the year 33000.
+const (
+	minMilliEpoch = 1_000_000_000_000
+	maxEpoch      = 253402300799 // 9999-12-31, the last time formats can show
+)
+
+// parseTimestamp parses a Unix epoch timestamp in seconds or milliseconds,
+// returning zero time if invalid. Timestamps in the future are kept; the
+// future-timestamp filter decides about those.
+func parseTimestamp(s string) time.Time {
+	epoch, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || epoch <= 0 {
+		return time.Time{}
+	}
+	if epoch >= minMilliEpoch {
+		epoch /= 1000
+	}
+	if epoch > maxEpoch {
+		return time.Time{}
+	}
+	return time.Unix(epoch, 0)
+}
+
+// shouldExclude checks if a command should be excluded based on patterns.
+// A command matching a keep pattern is never excluded. Otherwise exclude
+// patterns are evaluated before include patterns, so a command matching
+// both is excluded, and with include patterns set a command matching none
+// of them is excluded too.
 func (r *Reader) shouldExclude(command string) bool {
-	for _, pattern := range r.excludePatterns {
+	if matchesAny(r.keepPatterns, command) {
+		return false
+	}
+	if matchesAny(r.excludePatterns, command) {
+		return true
+	}
+	return len(r.includePatterns) > 0 && !matchesAny(r.includePatterns, command)
+}
+
+// matchesAny reports whether any of the patterns matches command
+func matchesAny(patterns []*regexp.Regexp, command string) bool {
+	for _, pattern := range patterns {
 		if pattern.MatchString(command) {
 			return true
 		}
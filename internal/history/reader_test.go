@@ -0,0 +1,576 @@
+package history
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// TestSettersDuringRefresh changes patterns and sources while refreshes
+// read in the background, as the exclude and source prompts do; run with
+// -race
+func TestSettersDuringRefresh(t *testing.T) {
+	path := writeHistory(t, ".zsh_history", ": 1700000000:0;ls", ": 1700000001:0;git status")
+	reader := NewReader([]string{path})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if _, _, err := reader.ReadNew(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if err := reader.SetExcludePatterns([]string{fmt.Sprintf("^echo %d$", i)}); err != nil {
+				t.Error(err)
+				return
+			}
+			reader.SetSources([]string{path})
+		}
+	}()
+	wg.Wait()
+
+	if err := reader.SetExcludePatterns([]string{"^git"}); err != nil {
+		t.Fatal(err)
+	}
+	commands, err := reader.ReadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := texts(commands); len(got) != 1 || got[0] != "ls" {
+		t.Errorf("commands = %q, want [ls]", got)
+	}
+}
+
+// TestReadHistoryCounts checks each deduplicated command counts every run
+// read, across sources and normalized variants, and keeps its newest run
+func TestReadHistoryCounts(t *testing.T) {
+	zsh := writeHistory(t, ".zsh_history",
+		": 1700000000:0;ls",
+		": 1700000001:0;git status",
+		": 1700000002:0;ls",
+		": 1700000003:0;FOO=1 make",
+		": 1700000004:0;ls",
+	)
+	bash := writeHistory(t, ".bash_history",
+		"#1700000005",
+		"make",
+		"#1700000006",
+		"git status",
+	)
+	reader := NewReader([]string{zsh, bash})
+	commands, err := reader.ReadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		text  string
+		count int
+		stamp int64
+	}{
+		{"git status", 2, 1700000006},
+		{"make", 2, 1700000005},
+		{"ls", 3, 1700000004},
+	}
+	if len(commands) != len(want) {
+		t.Fatalf("commands = %q, want %d", texts(commands), len(want))
+	}
+	for i, w := range want {
+		cmd := commands[i]
+		if cmd.Text != w.text || cmd.Count != w.count || cmd.Timestamp.Unix() != w.stamp {
+			t.Errorf("command %d = %q count %d at %d, want %q count %d at %d",
+				i, cmd.Text, cmd.Count, cmd.Timestamp.Unix(), w.text, w.count, w.stamp)
+		}
+	}
+	if got := len(reader.Occurrences()); got != 7 {
+		t.Errorf("occurrences = %d, want 7", got)
+	}
+}
+
+// TestIncludePatterns checks that with include patterns set only commands
+// matching one of them are shown, and that excludes still win over them
+func TestIncludePatterns(t *testing.T) {
+	path := writeHistory(t, ".zsh_history",
+		": 1700000000:0;git status",
+		": 1700000001:0;kubectl get secrets",
+		": 1700000002:0;echo $SECRET",
+		": 1700000003:0;make",
+		": 1700000004:0;kubectl get pods",
+	)
+	tests := []struct {
+		name    string
+		exclude []string
+		include []string
+		want    string
+	}{
+		{"no patterns", nil, nil, "[kubectl get pods make echo $SECRET kubectl get secrets git status]"},
+		{"includes only", nil, []string{"^kubectl", "^git"}, "[kubectl get pods kubectl get secrets git status]"},
+		{"excludes win", []string{"(?i)secret"}, []string{"^kubectl", "^git"}, "[kubectl get pods git status]"},
+		{"no include matches", nil, []string{"^docker"}, "[]"},
+	}
+	for _, tt := range tests {
+		reader := NewReader([]string{path})
+		if err := reader.SetExcludePatterns(tt.exclude); err != nil {
+			t.Fatal(err)
+		}
+		if err := reader.SetIncludePatterns(tt.include); err != nil {
+			t.Fatal(err)
+		}
+		commands, err := reader.ReadHistory()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(texts(commands)); got != tt.want {
+			t.Errorf("%s: commands = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestKeepPatterns checks keep patterns show commands whatever the exclude
+// and include patterns say, and leave the rest to them
+func TestKeepPatterns(t *testing.T) {
+	path := writeHistory(t, ".zsh_history",
+		": 1700000000:0;git status",
+		": 1700000001:0;kubectl get secrets",
+		": 1700000002:0;echo $SECRET",
+		": 1700000003:0;make",
+		": 1700000004:0;ssh-keygen -t ed25519",
+	)
+	tests := []struct {
+		name    string
+		include []string
+		keep    []string
+		want    string
+	}{
+		{"excludes only", nil, nil, "[make git status]"},
+		{"keep overrides excludes", nil, []string{"^kubectl get secrets"}, "[make kubectl get secrets git status]"},
+		{"keep overrides includes", []string{"^git"}, []string{"^kubectl get secrets", "^ssh-keygen"}, "[ssh-keygen -t ed25519 kubectl get secrets git status]"},
+	}
+	for _, tt := range tests {
+		reader := NewReader([]string{path})
+		if err := reader.SetExcludePatterns([]string{"(?i)secret", "key"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := reader.SetIncludePatterns(tt.include); err != nil {
+			t.Fatal(err)
+		}
+		if err := reader.SetKeepPatterns(tt.keep); err != nil {
+			t.Fatal(err)
+		}
+		commands, err := reader.ReadHistory()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(texts(commands)); got != tt.want {
+			t.Errorf("%s: commands = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestSetPatternsRejectsInvalid checks an invalid pattern is reported and
+// leaves the patterns set before in place
+func TestSetPatternsRejectsInvalid(t *testing.T) {
+	path := writeHistory(t, ".zsh_history", ": 1700000000:0;kubectl get secrets", ": 1700000001:0;ls")
+	reader := NewReader([]string{path})
+	if err := reader.SetExcludePatterns([]string{"secret"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := reader.SetKeepPatterns([]string{"^kubectl"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := reader.SetIncludePatterns([]string{"^git", "("}); err == nil {
+		t.Error("SetIncludePatterns accepted an invalid pattern")
+	}
+	if err := reader.SetKeepPatterns([]string{"^git", "("}); err == nil {
+		t.Error("SetKeepPatterns accepted an invalid pattern")
+	}
+	if err := reader.SetExcludePatterns([]string{"["}); err == nil {
+		t.Error("SetExcludePatterns accepted an invalid pattern")
+	}
+
+	commands, err := reader.ReadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := texts(commands); len(got) != 2 {
+		t.Errorf("commands = %q, want both, as with the earlier patterns", got)
+	}
+}
+
+// TestMaxCommandLength checks an oversized line, such as a pasted SQL
+// dump, is truncated on a rune boundary and marked, or skipped
+func TestMaxCommandLength(t *testing.T) {
+	long := "psql -c \"INSERT INTO t VALUES ('" + strings.Repeat("é", 100000) + "')\""
+	path := writeHistory(t, ".zsh_history", ": 1700000000:0;ls", ": 1700000001:0;"+long, ": 1700000002:0;make")
+
+	reader := NewReader([]string{path})
+	reader.SetMaxCommandLength(4096, false)
+	commands, err := reader.ReadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != 3 {
+		t.Fatalf("commands = %d, want 3 with the long one truncated", len(commands))
+	}
+	cmd := commands[1]
+	if !cmd.Truncated || cmd.FullSize != len(long) {
+		t.Errorf("long command truncated %t with full size %d, want true and %d", cmd.Truncated, cmd.FullSize, len(long))
+	}
+	if n := utf8.RuneCountInString(cmd.Text); n != 4096 || !utf8.ValidString(cmd.Text) || !strings.HasPrefix(long, cmd.Text) {
+		t.Errorf("truncated text has %d runes (valid UTF-8 %t), want the first 4096 runes", n, utf8.ValidString(cmd.Text))
+	}
+	if commands[0].Truncated || commands[2].Truncated {
+		t.Errorf("short commands marked truncated: %+v", commands)
+	}
+
+	reader.SetMaxCommandLength(4096, true)
+	commands, err = reader.ReadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := texts(commands); fmt.Sprint(got) != "[make ls]" {
+		t.Errorf("commands with long ones skipped = %q, want [make ls]", got)
+	}
+
+	reader.SetMaxCommandLength(0, false)
+	commands, err = reader.ReadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != 3 || commands[1].Text != long || commands[1].Truncated {
+		t.Errorf("unlimited length changed the long command")
+	}
+}
+
+// readFile reads a history file with a new reader and returns its commands,
+// newest first
+func readFile(t *testing.T, name string, lines ...string) []Command {
+	t.Helper()
+	reader := NewReader([]string{writeHistory(t, name, lines...)})
+	commands, err := reader.ReadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return commands
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		metadata string
+		want     time.Duration
+		ok       bool
+	}{
+		{"1700000000:0", 0, true},
+		{"1700000000:5", 5 * time.Second, true},
+		{"1700000000: 90", 90 * time.Second, true},
+		{"1700000000:3600:1", time.Hour, true},
+		{"1700000000", 0, false},
+		{"1700000000:", 0, false},
+		{"1700000000::0", 0, false},
+		{"1700000000:-5", 0, false},
+		{"1700000000:abc", 0, false},
+		// Ten years still fits, a value past what a Duration holds doesn't
+		{"1700000000:315360000", 315360000 * time.Second, true},
+		{"1700000000:9223372036", 9223372036 * time.Second, true},
+		{"1700000000:9223372037", 0, false},
+		{"1700000000:99999999999999999999", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseDuration(strings.Split(tt.metadata, ":"))
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseDuration(%q) = %v, %v; want %v, %v", tt.metadata, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// TestZshDurations checks durations are read from zsh extended history,
+// with a zero duration known and a missing one unknown
+func TestZshDurations(t *testing.T) {
+	commands := readFile(t, ".zsh_history",
+		": 1700000000:0;ls",
+		": 1700000010:42;make test",
+		": 1700000100;git status",
+		": 1700000200:99999999999;sleep forever",
+	)
+
+	want := map[string]struct {
+		duration time.Duration
+		known    bool
+	}{
+		"ls":            {0, true},
+		"make test":     {42 * time.Second, true},
+		"git status":    {0, false},
+		"sleep forever": {0, false},
+	}
+	if len(commands) != len(want) {
+		t.Fatalf("commands = %q, want %d", texts(commands), len(want))
+	}
+	for _, cmd := range commands {
+		w := want[cmd.Text]
+		if cmd.Duration != w.duration || cmd.HasDuration != w.known {
+			t.Errorf("%q ran %v (known %v), want %v (known %v)", cmd.Text, cmd.Duration, cmd.HasDuration, w.duration, w.known)
+		}
+	}
+}
+
+// TestBashTimestampComments checks "#<epoch>" lines timestamp the command
+// after them and are never read as commands themselves, while commands
+// without one keep no timestamp and their place in the file
+func TestBashTimestampComments(t *testing.T) {
+	commands := readFile(t, ".bash_history",
+		"#1700000000",
+		"ls",
+		"make",
+		"#1700000100",
+		"git status",
+		"# a real comment",
+		"#1700000200",
+	)
+
+	want := []struct {
+		text  string
+		stamp int64
+	}{
+		{"# a real comment", 0},
+		{"git status", 1700000100},
+		{"make", 0},
+		{"ls", 1700000000},
+	}
+	if len(commands) != len(want) {
+		t.Fatalf("commands = %q, want %d", texts(commands), len(want))
+	}
+	for i, w := range want {
+		cmd := commands[i]
+		var stamp int64
+		if !cmd.Timestamp.IsZero() {
+			stamp = cmd.Timestamp.Unix()
+		}
+		if cmd.Text != w.text || stamp != w.stamp {
+			t.Errorf("command %d = %q at %d, want %q at %d", i, cmd.Text, stamp, w.text, w.stamp)
+		}
+	}
+}
+
+// TestZshMultiLine checks the lines of a multi-line zsh command are joined
+// into one command, deduplicated by the whole text
+func TestZshMultiLine(t *testing.T) {
+	lines := []string{
+		": 1700000000:0;for f in *.go; do\\",
+		"  gofmt -l $f\\",
+		"done",
+		": 1700000001:0;ls",
+		": 1700000002:0;for f in *.go; do\\",
+		"  gofmt -l $f\\",
+		"done",
+		": 1700000003:0;cat <<EOF\\",
+		"hello\\",
+		"EOF",
+	}
+	loop := "for f in *.go; do\n  gofmt -l $f\ndone"
+
+	for _, name := range []string{".zsh_history", "history.txt"} {
+		commands := readFile(t, name, lines...)
+		if got, want := texts(commands), []string{"cat <<EOF\nhello\nEOF", loop, "ls"}; fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: commands = %q, want %q", name, got, want)
+			continue
+		}
+		if commands[1].Count != 2 || commands[1].Timestamp.Unix() != 1700000002 {
+			t.Errorf("%s: loop run %d times, last at %d; want 2 at 1700000002", name, commands[1].Count, commands[1].Timestamp.Unix())
+		}
+	}
+
+	// A command whose first lines are before the lines kept is left out
+	reader := NewReader([]string{writeHistory(t, ".zsh_history", lines[:4]...)})
+	reader.SetMaxLines(3)
+	commands, err := reader.ReadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := texts(commands); fmt.Sprint(got) != "[ls]" {
+		t.Errorf("commands after a cut-off command = %q, want [ls]", got)
+	}
+}
+
+// TestTcshHistory checks tcsh histories saved with and without "#+<epoch>"
+// timestamp lines
+func TestTcshHistory(t *testing.T) {
+	tests := []struct {
+		file   string
+		want   []string
+		stamps []int64
+	}{
+		{"tcsh.history", []string{"make clean", "foreach f (*.c)", "setenv EDITOR vim", "ls -la"}, []int64{1700000180, 1700000120, 1700000060, 1700000000}},
+		{"tcsh_plain.history", []string{"make clean", "setenv EDITOR vim", "ls -la"}, []int64{0, 0, 0}},
+	}
+	for _, tt := range tests {
+		commands, err := NewReader([]string{filepath.Join("testdata", tt.file)}).ReadHistory()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := texts(commands); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: commands = %q, want %q", tt.file, got, tt.want)
+			continue
+		}
+		for i, cmd := range commands {
+			var stamp int64
+			if !cmd.Timestamp.IsZero() {
+				stamp = cmd.Timestamp.Unix()
+			}
+			if stamp != tt.stamps[i] {
+				t.Errorf("%s: %q at %d, want %d", tt.file, cmd.Text, stamp, tt.stamps[i])
+			}
+		}
+	}
+}
+
+// TestInvalidUTF8 checks stray bytes are replaced so the rest of a command
+// survives, while lines of nothing but garbage are dropped
+func TestInvalidUTF8(t *testing.T) {
+	commands := readFile(t, ".bash_history",
+		"cat caf\xe9.txt",
+		"\xff\xfe\xfd",
+		"ls \xc3",
+		"echo \xe2\x82 done",
+		"printf 'a\x00b'",
+		"vim r\xe9sum\xe9.md",
+		"git status",
+	)
+	want := []string{"git status", "vim r�sum�.md", "echo � done", "ls �", "cat caf�.txt"}
+	if got := texts(commands); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+	for _, cmd := range commands {
+		if !utf8.ValidString(cmd.Text) {
+			t.Errorf("%q is not valid UTF-8", cmd.Text)
+		}
+	}
+}
+
+// TestFilters checks each noise filter on its own, starting from the
+// defaults
+func TestFilters(t *testing.T) {
+	now := time.Now().Unix()
+	path := writeHistory(t, ".zsh_history",
+		fmt.Sprintf(": %d:0;make ancient", now-3*365*24*3600),
+		fmt.Sprintf(": %d:0;make old", now-40*24*3600),
+		fmt.Sprintf(": %d:0;ls -la", now-100),
+		fmt.Sprintf(": %d:0;g", now-90),
+		fmt.Sprintf(": %d:0;42", now-80),
+		fmt.Sprintf(": %d:0; git push", now-70),
+		fmt.Sprintf(": %d:0;printf 'a\x00b'", now-60),
+		fmt.Sprintf(": %d:0;make future", now+2*3600),
+	)
+
+	tests := []struct {
+		name   string
+		change func(f *Filters)
+		want   string
+	}{
+		{"defaults", func(*Filters) {}, "[g ls -la make old make ancient]"},
+		{"min_length", func(f *Filters) { f.MinLength = 2 }, "[ls -la make old make ancient]"},
+		{"drop_numeric", func(f *Filters) { f.DropNumeric = false }, "[42 g ls -la make old make ancient]"},
+		{"drop_spaced", func(f *Filters) { f.DropSpaced = false }, "[git push g ls -la make old make ancient]"},
+		{"drop_binary", func(f *Filters) { f.DropBinary = false }, "[printf 'a\x00b' g ls -la make old make ancient]"},
+		{"drop_future_timestamps", func(f *Filters) { f.DropFutureTimestamps = false }, "[make future g ls -la make old make ancient]"},
+		{"future_skew", func(f *Filters) { f.FutureSkew = 3 * time.Hour }, "[make future g ls -la make old make ancient]"},
+		{"max_age", func(f *Filters) { f.MaxAge = 30 * 24 * time.Hour }, "[g ls -la]"},
+		{"max_age off", func(f *Filters) { f.MaxAge = 0 }, "[g ls -la make old make ancient]"},
+	}
+	for _, tt := range tests {
+		filters := DefaultFilters()
+		tt.change(&filters)
+		reader := NewReader([]string{path})
+		reader.SetFilters(filters)
+		commands, err := reader.ReadHistory()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(texts(commands)); got != tt.want {
+			t.Errorf("%s: commands = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestDropSpacedKeepsDuplicates checks dropping a command typed with a
+// leading space keeps the same command typed without one, counting only
+// those runs
+func TestDropSpacedKeepsDuplicates(t *testing.T) {
+	now := time.Now().Unix()
+	files := map[string][]string{
+		".zsh_history": {
+			fmt.Sprintf(": %d:0;git push", now-300),
+			fmt.Sprintf(": %d:0; git push", now-200),
+			fmt.Sprintf(": %d:0;git push", now-100),
+			fmt.Sprintf(": %d:0;  git push", now-50),
+		},
+		".bash_history": {"git push", " git push", "git push", "\tgit push"},
+	}
+	for name, lines := range files {
+		path := writeHistory(t, name, lines...)
+		commands, err := NewReader([]string{path}).ReadHistory()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(commands) != 1 || commands[0].Text != "git push" || commands[0].Count != 2 {
+			t.Errorf("%s: commands = %+v, want git push run twice", name, commands)
+		}
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64 // 0 for invalid
+	}{
+		{"1234567890", 1234567890},     // 2009
+		{"2051222400", 2051222400},     // 2035
+		{"1700000000123", 1700000000},  // Milliseconds
+		{"1234567890999", 1234567890},  // Milliseconds in 2009
+		{" 1700000000 ", 1700000000},   // Padded
+		{"253402300799", 253402300799}, // The year 9999
+		{"253402300800", 0},            // Past the year 9999
+		{"99999999999999999", 0},       // Past it in milliseconds too
+		{"0", 0},
+		{"-1700000000", 0},
+		{"", 0},
+		{"17000000x0", 0},
+		{"1.7e9", 0},
+	}
+	for _, tt := range tests {
+		got := parseTimestamp(tt.value)
+		var unix int64
+		if !got.IsZero() {
+			unix = got.Unix()
+		}
+		if unix != tt.want {
+			t.Errorf("parseTimestamp(%q) = %d, want %d", tt.value, unix, tt.want)
+		}
+	}
+}
+
+// TestBadTimestamp checks an unparsable zsh timestamp is flagged and left
+// empty, rather than read as the time of reading
+func TestBadTimestamp(t *testing.T) {
+	reader := NewReader(nil)
+	for _, line := range []string{": abc:0;ls", ": 0:0;ls", ": 99999999999999999:0;ls"} {
+		cmd := reader.parseZshLine(line, 0)
+		if cmd.Text != "ls" || !cmd.Timestamp.IsZero() || !cmd.BadTimestamp {
+			t.Errorf("parseZshLine(%q) = %+v, want ls flagged with a bad timestamp", line, cmd)
+		}
+	}
+	if cmd := reader.parseZshLine(": 1234567890:0;ls", 0); cmd.BadTimestamp || cmd.Timestamp.Unix() != 1234567890 {
+		t.Errorf("parseZshLine of a 2009 timestamp = %+v", cmd)
+	}
+}
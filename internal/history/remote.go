@@ -0,0 +1,112 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// remotePrefix starts sources read from another host over SSH
+const remotePrefix = "ssh://"
+
+// DefaultRemoteTimeout is how long reading a remote source may take
+const DefaultRemoteTimeout = 10 * time.Second
+
+// IsRemote reports whether a source is read over SSH, e.g.
+// ssh://user@host/.zsh_history
+func IsRemote(source string) bool {
+	return strings.HasPrefix(source, remotePrefix)
+}
+
+// RemoteHost returns the host of a remote source, without user and port
+func RemoteHost(source string) string {
+	u, err := url.Parse(source)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// parseRemote splits a remote source into the ssh options that select the
+// port, the user@host destination and the path of the history file there.
+// The path is relative to the remote home directory unless it starts with a
+// second slash, as in ssh://host//var/lib/app/.bash_history. Users and
+// hosts starting with - are rejected, since ssh would take them for
+// options such as -oProxyCommand.
+func parseRemote(source string) (options []string, dest, path string, err error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if u.Hostname() == "" {
+		return nil, "", "", errors.New("missing host")
+	}
+	if strings.HasPrefix(u.Hostname(), "-") {
+		return nil, "", "", fmt.Errorf("invalid host %q", u.Hostname())
+	}
+
+	path = strings.TrimPrefix(u.Path, "/")
+	path = strings.TrimPrefix(path, "~/")
+	if path == "" {
+		return nil, "", "", errors.New("missing history file path")
+	}
+
+	if port := u.Port(); port != "" {
+		options = append(options, "-p", port)
+	}
+	dest = u.Hostname()
+	if u.User != nil {
+		if strings.HasPrefix(u.User.Username(), "-") {
+			return nil, "", "", fmt.Errorf("invalid user %q", u.User.Username())
+		}
+		dest = u.User.Username() + "@" + dest
+	}
+	return options, dest, path, nil
+}
+
+// fetchRemote reads a history file from another host with the ssh program.
+// ssh runs in batch mode, since a password prompt would garble the TUI, so
+// the host needs key or agent authentication.
+func (r *Reader) fetchRemote(source string) ([]byte, error) {
+	options, dest, path, err := parseRemote(source)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := r.remoteTimeout
+	if timeout <= 0 {
+		timeout = DefaultRemoteTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	args := []string{"-o", "BatchMode=yes", "-o", fmt.Sprintf("ConnectTimeout=%d", max(int(timeout/time.Second), 1))}
+	args = append(args, options...)
+	args = append(args, "--", dest, "cat", "--", shellQuote(path))
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ssh", args...)
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second // Don't wait for children still holding the output open
+	data, err := cmd.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("timed out after %s", timeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// shellQuote quotes s for the remote shell that ssh runs commands with
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,71 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseRemote(t *testing.T) {
+	tests := []struct {
+		source  string
+		options []string
+		dest    string
+		path    string
+	}{
+		{"ssh://host/.zsh_history", nil, "host", ".zsh_history"},
+		{"ssh://me@host:2222/~/.bash_history", []string{"-p", "2222"}, "me@host", ".bash_history"},
+		{"ssh://host//var/lib/app/.bash_history", nil, "host", "/var/lib/app/.bash_history"},
+	}
+	for _, tt := range tests {
+		options, dest, path, err := parseRemote(tt.source)
+		if err != nil {
+			t.Errorf("parseRemote(%q): %v", tt.source, err)
+			continue
+		}
+		if !slices.Equal(options, tt.options) || dest != tt.dest || path != tt.path {
+			t.Errorf("parseRemote(%q) = %q, %q, %q; want %q, %q, %q",
+				tt.source, options, dest, path, tt.options, tt.dest, tt.path)
+		}
+	}
+}
+
+func TestParseRemoteRejectsOptions(t *testing.T) {
+	for _, source := range []string{
+		"ssh://-oProxyCommand=touch%20pwned@x/.zsh_history",
+		"ssh://-oProxyCommand=touch%20pwned/.zsh_history",
+		"ssh://host",
+		"ssh:///.zsh_history",
+	} {
+		if _, _, _, err := parseRemote(source); err == nil {
+			t.Errorf("parseRemote(%q) succeeded, want an error", source)
+		}
+	}
+}
+
+// TestFetchRemoteEndsOptions checks the destination follows -- on the ssh
+// command line, using a fake ssh that prints its arguments
+func TestFetchRemoteEndsOptions(t *testing.T) {
+	dir := t.TempDir()
+	script := "#!/bin/sh\nprintf '%s\\n' \"$@\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "ssh"), []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+
+	reader := NewReader(nil)
+	out, err := reader.fetchRemote("ssh://me@host:2222/.zsh_history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := strings.Split(strings.TrimSpace(string(out)), "\n")
+	end := slices.Index(args, "--")
+	if end < 0 || end+1 >= len(args) || args[end+1] != "me@host" {
+		t.Fatalf("ssh arguments = %q, want -- before me@host", args)
+	}
+	if !slices.Contains(args[:end], "2222") {
+		t.Errorf("ssh arguments = %q, want the port before --", args)
+	}
+}
@@ -0,0 +1,54 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// SQLiteCommand is the program used to query SQLite history databases
+const SQLiteCommand = "sqlite3"
+
+// sqliteHeader starts every SQLite database file
+const sqliteHeader = "SQLite format 3\x00"
+
+// isSQLite reports whether src starts with the SQLite database header
+func isSQLite(src io.ReaderAt) bool {
+	header := make([]byte, len(sqliteHeader))
+	n, _ := src.ReadAt(header, 0)
+	return string(header[:n]) == sqliteHeader
+}
+
+// querySQLite runs a read-only query on a database and decodes the rows
+// into rows, a pointer to a slice of structs with json tags named like the
+// result columns. There is no SQLite driver in the build, so the database
+// is queried through the sqlite3 program.
+func querySQLite(filename, query string, rows any) error {
+	if _, err := exec.LookPath(SQLiteCommand); err != nil {
+		return fmt.Errorf("reading a SQLite history database needs the %s program", SQLiteCommand)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(SQLiteCommand, "-readonly", "-json", filename, query)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return errors.New(msg)
+		}
+		return err
+	}
+
+	// No rows print nothing rather than an empty array
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(out, rows); err != nil {
+		return fmt.Errorf("unexpected %s output: %w", SQLiteCommand, err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package history
+
+import (
+	"bytes"
+	"io"
+)
+
+// tailChunkSize is how much of a file is read at a time when looking for
+// the start of its last lines
+const tailChunkSize = 64 * 1024
+
+// tail describes where the last lines of a file start
+type tail struct {
+	offset       int64 // Byte offset of the first kept line
+	linesBefore  int   // Number of lines before offset
+	cutContinued bool  // Whether the line before offset ends with a backslash
+}
+
+// findTail finds the start of the last maxLines lines of a file of the
+// given size by reading it backwards, so files far larger than maxLines are
+// never scanned line by line. Lines before the tail are only counted, in
+// fixed-size chunks, to keep line numbers absolute.
+func findTail(file io.ReaderAt, size int64, maxLines int) (tail, error) {
+	// A file can't have more lines than bytes
+	if int64(maxLines) >= size {
+		return tail{}, nil
+	}
+
+	buf := make([]byte, tailChunkSize)
+	newlines := 0
+	end := size
+	for end > 0 {
+		start := max(end-tailChunkSize, 0)
+		chunk := buf[:end-start]
+		if _, err := file.ReadAt(chunk, start); err != nil && err != io.EOF {
+			return tail{}, err
+		}
+
+		for i := len(chunk) - 1; i >= 0; i-- {
+			// The newline ending the last line doesn't start another one
+			if chunk[i] != '\n' || start+int64(i) == size-1 {
+				continue
+			}
+			newlines++
+			if newlines == maxLines {
+				return newTail(file, start+int64(i)+1, buf)
+			}
+		}
+		end = start
+	}
+	return tail{}, nil
+}
+
+// newTail describes a tail starting at offset, which follows a newline
+func newTail(file io.ReaderAt, offset int64, buf []byte) (tail, error) {
+	t := tail{offset: offset}
+
+	// The scanner drops a carriage return before the newline too
+	last := make([]byte, min(offset, 3))
+	if _, err := file.ReadAt(last, offset-int64(len(last))); err != nil {
+		return tail{}, err
+	}
+	last = bytes.TrimSuffix(bytes.TrimSuffix(last, []byte{'\n'}), []byte{'\r'})
+	t.cutContinued = bytes.HasSuffix(last, []byte{'\\'})
+
+	for pos := int64(0); pos < offset; {
+		chunk := buf[:min(int64(len(buf)), offset-pos)]
+		n, err := file.ReadAt(chunk, pos)
+		t.linesBefore += bytes.Count(chunk[:n], []byte{'\n'})
+		if err != nil && err != io.EOF {
+			return tail{}, err
+		}
+		if n == 0 {
+			break
+		}
+		pos += int64(n)
+	}
+	return t, nil
+}
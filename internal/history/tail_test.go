@@ -0,0 +1,106 @@
+package history
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestFindTail checks the tail found from the end of a file starts where
+// splitting the whole file would put it
+func TestFindTail(t *testing.T) {
+	contents := []string{
+		"",
+		"ls\n",
+		"ls\nmake\ngit status\n",
+		"ls\nmake\ngit status",
+		"ls\r\nfor f in *; do\\\r\necho $f\\\r\ndone\r\nmake\r\n",
+		"\n\nls\n\n",
+	}
+	for _, content := range contents {
+		lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+		if content == "" {
+			lines = nil
+		}
+		for maxLines := 1; maxLines <= len(lines)+1; maxLines++ {
+			got, err := findTail(strings.NewReader(content), int64(len(content)), maxLines)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var want tail
+			if before := len(lines) - maxLines; before > 0 {
+				want.linesBefore = before
+				want.offset = int64(len(strings.Join(lines[:before], "\n")) + 1)
+				want.cutContinued = strings.HasSuffix(strings.TrimSuffix(lines[before-1], "\r"), "\\")
+			}
+			if got != want {
+				t.Errorf("findTail(%q, %d) = %+v, want %+v", content, maxLines, got, want)
+			}
+		}
+	}
+}
+
+// hugeHistory writes a zsh history of n lines once per benchmark
+func hugeHistory(b *testing.B, n int) string {
+	b.Helper()
+	path := filepath.Join(b.TempDir(), ".zsh_history")
+	file, err := os.Create(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	w := bufio.NewWriter(file)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(w, ": %d:0;git commit -m 'change %d'\n", 1700000000+i, i)
+	}
+	if err := w.Flush(); err != nil {
+		b.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+// BenchmarkReadTail reads the last 10000 lines of a 1M-line history; its
+// memory use depends on the lines kept, not the size of the file
+func BenchmarkReadTail(b *testing.B) {
+	path := hugeHistory(b, 1_000_000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reader := NewReader([]string{path})
+		reader.SetMaxLines(10000)
+		if _, err := reader.ReadHistory(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkReadAllLines reads every line of the same history into memory
+// and keeps the last 10000, as the reader did before it read the tail
+func BenchmarkReadAllLines(b *testing.B) {
+	path := hugeHistory(b, 1_000_000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		file, err := os.Open(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var lines []string
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(nil, maxLineSize)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			b.Fatal(err)
+		}
+		_ = lines[len(lines)-10000:]
+	}
+}
@@ -0,0 +1,136 @@
+package history
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// zshMeta is the byte zsh uses to escape special bytes in its history file
+const zshMeta = 0x83
+
+// zshMarker is the last byte zsh escapes with zshMeta
+const zshMarker = 0xa2
+
+// ZshEntry is a single raw entry of a zsh history file
+type ZshEntry struct {
+	Command   string // Command text, may contain newlines
+	Timestamp int64  // Unix time the command started, 0 if not recorded
+	Duration  int    // Seconds the command ran
+	Extended  bool   // Whether the entry is in extended ": start:duration;" format
+}
+
+// ReadZshEntries reads every entry of a zsh history file, joining
+// multi-line commands and decoding metafied bytes
+func ReadZshEntries(r io.Reader) ([]ZshEntry, error) {
+	var entries []ZshEntry
+	reader := bufio.NewReader(r)
+
+	for {
+		line, err := readZshLine(reader)
+		if line != "" {
+			entries = append(entries, parseZshEntry(unmetafy(line)))
+		}
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readZshLine reads one logical history line. A line ending in a backslash
+// continues on the next line, with the backslash replaced by a newline.
+func readZshLine(reader *bufio.Reader) (string, error) {
+	var b strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimSuffix(line, "\n")
+		if err == nil && strings.HasSuffix(line, "\\") {
+			b.WriteString(strings.TrimSuffix(line, "\\"))
+			b.WriteByte('\n')
+			continue
+		}
+		b.WriteString(line)
+		return b.String(), err
+	}
+}
+
+// parseZshEntry parses a decoded logical history line
+func parseZshEntry(line string) ZshEntry {
+	if !strings.HasPrefix(line, ": ") {
+		return ZshEntry{Command: line}
+	}
+
+	semiIndex := strings.Index(line, ";")
+	if semiIndex == -1 {
+		return ZshEntry{Command: line}
+	}
+
+	start, duration, found := strings.Cut(line[2:semiIndex], ":")
+	timestamp, err := strconv.ParseInt(strings.TrimSpace(start), 10, 64)
+	if !found || err != nil {
+		return ZshEntry{Command: line}
+	}
+	seconds, _ := strconv.Atoi(strings.TrimSpace(duration))
+
+	return ZshEntry{
+		Command:   line[semiIndex+1:],
+		Timestamp: timestamp,
+		Duration:  seconds,
+		Extended:  true,
+	}
+}
+
+// WriteZshEntries writes entries in zsh history format, escaping newlines
+// and metafying bytes the way zsh does
+func WriteZshEntries(w io.Writer, entries []ZshEntry) error {
+	writer := bufio.NewWriter(w)
+	for _, entry := range entries {
+		line := strings.ReplaceAll(entry.Command, "\n", "\\\n")
+		if entry.Extended {
+			line = fmt.Sprintf(": %d:%d;%s", entry.Timestamp, entry.Duration, line)
+		}
+		if _, err := writer.WriteString(metafy(line) + "\n"); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// unmetafy decodes zsh metafied text, where an escaped byte is stored as
+// zshMeta followed by the byte XOR 32
+func unmetafy(s string) string {
+	if strings.IndexByte(s, zshMeta) == -1 {
+		return s
+	}
+
+	decoded := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == zshMeta && i+1 < len(s) {
+			i++
+			decoded = append(decoded, s[i]^32)
+			continue
+		}
+		decoded = append(decoded, s[i])
+	}
+	return string(decoded)
+}
+
+// metafy encodes text for a zsh history file, escaping NUL and the bytes
+// zsh uses internally
+func metafy(s string) string {
+	encoded := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == 0 || (c >= zshMeta && c <= zshMarker) {
+			encoded = append(encoded, zshMeta, c^32)
+			continue
+		}
+		encoded = append(encoded, c)
+	}
+	return string(encoded)
+}
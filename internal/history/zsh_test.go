@@ -0,0 +1,119 @@
+package history
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// zshRoundTripEntries cover the text zsh escapes: multibyte characters
+// whose bytes fall in the metafied range, NUL, and multi-line commands
+var zshRoundTripEntries = []ZshEntry{
+	{Command: "ls -la", Timestamp: 1700000000, Duration: 0, Extended: true},
+	{Command: "echo 日本語 中文 русский", Timestamp: 1700000001, Duration: 2, Extended: true},
+	{Command: "echo 🎉 ü ß é ñ", Timestamp: 1700000002, Duration: 0, Extended: true},
+	{Command: "for f in *.txt; do\n  echo \"$f\"\ndone", Timestamp: 1700000003, Duration: 1, Extended: true},
+	{Command: "cat <<EOF\nпривет\nEOF", Timestamp: 1700000004, Duration: 0, Extended: true},
+	{Command: "ls \\\n  -la", Timestamp: 1700000005, Duration: 0, Extended: true},
+	{Command: "printf 'a\x00b'", Timestamp: 1700000006, Duration: 0, Extended: true},
+	{Command: "echo \x83\xa2 raw bytes", Timestamp: 1700000007, Duration: 0, Extended: true},
+	{Command: "git status"},
+	{Command: "echo 'два\nрядки'"},
+}
+
+func TestZshEntriesRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteZshEntries(&buf, zshRoundTripEntries); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ReadZshEntries(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(entries, zshRoundTripEntries) {
+		t.Errorf("entries after round trip:\n%+v\nwant\n%+v", entries, zshRoundTripEntries)
+	}
+}
+
+// TestZshEntriesRoundTripTwice checks writing what was read gives the same
+// bytes, so cleaning a file changes nothing but the removed entries
+func TestZshEntriesRoundTripTwice(t *testing.T) {
+	var first bytes.Buffer
+	if err := WriteZshEntries(&first, zshRoundTripEntries); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ReadZshEntries(bytes.NewReader(first.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var second bytes.Buffer
+	if err := WriteZshEntries(&second, entries); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Errorf("second write differs:\n%q\nfirst\n%q", second.Bytes(), first.Bytes())
+	}
+}
+
+// TestZshMetafiedAsWrittenByZsh checks bytes as zsh writes them: 日 is
+// E6 97 A5, and 0x97 is stored as 0x83 0xB7
+func TestZshMetafiedAsWrittenByZsh(t *testing.T) {
+	written := ": 1700000000:0;echo \xe6\x83\xb7\xa5\n"
+
+	entries, err := ReadZshEntries(strings.NewReader(written))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Command != "echo 日" {
+		t.Fatalf("entries = %+v, want echo 日", entries)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteZshEntries(&buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != written {
+		t.Errorf("written = %q, want %q", buf.String(), written)
+	}
+}
+
+// TestReaderReadsWrittenEntries checks the reader used by the TUI parses
+// the written file back to the same commands
+func TestReaderReadsWrittenEntries(t *testing.T) {
+	var buf bytes.Buffer
+	entries := zshRoundTripEntries[:6]
+	if err := WriteZshEntries(&buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	path := writeHistory(t, ".zsh_history", strings.TrimSuffix(buf.String(), "\n"))
+
+	reader := NewReader([]string{path})
+	commands, err := reader.ReadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != len(entries) {
+		t.Fatalf("commands = %q, want %d", texts(commands), len(entries))
+	}
+	for i, cmd := range commands {
+		entry := entries[len(entries)-1-i]
+		if cmd.Text != entry.Command || cmd.Timestamp.Unix() != entry.Timestamp {
+			t.Errorf("command %d = %q at %d, want %q at %d", i, cmd.Text, cmd.Timestamp.Unix(), entry.Command, entry.Timestamp)
+		}
+	}
+}
+
+// TestReaderUnmetafies checks the reader decodes commands as zsh writes
+// them, so they show as UTF-8 instead of being dropped as binary
+func TestReaderUnmetafies(t *testing.T) {
+	commands := readFile(t, ".zsh_history",
+		": 1700000000:0;cd ~/\xd0\xbf\xd1\x80\xd0\xbe\xd0\xb5\xd0\xba\xd1\x82\xd1\x83\xab",
+		": 1700000001:0;echo \xf0\x83\xbf\x83\xae\x83\xa9",
+		": 1700000002:0;touch \xc3\x83\xbcbersicht.txt",
+	)
+	want := []string{"touch Übersicht.txt", "echo 🎉", "cd ~/проекты"}
+	if got := texts(commands); !reflect.DeepEqual(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
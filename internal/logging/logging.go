@@ -0,0 +1,135 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxSize is the default size in bytes at which the log is rotated
+const DefaultMaxSize = 512 * 1024
+
+// maxRecent is how many warnings are kept in memory for the help screen
+const maxRecent = 20
+
+// Entry is a logged warning
+type Entry struct {
+	Time    time.Time
+	Message string
+}
+
+var (
+	mu      sync.Mutex
+	path          = DefaultPath()
+	maxSize int64 = DefaultMaxSize
+	verbose bool
+	recent  []Entry
+	count   int
+)
+
+// DefaultPath returns the default log file,
+// ~/.local/state/history-nav/history-nav.log
+func DefaultPath() string {
+	stateDir := os.Getenv("XDG_STATE_HOME")
+	if stateDir == "" {
+		homeDir, _ := os.UserHomeDir()
+		stateDir = filepath.Join(homeDir, ".local", "state")
+	}
+	return filepath.Join(stateDir, "history-nav", "history-nav.log")
+}
+
+// SetFile sets the log file and the size in bytes at which it is rotated
+// to FILE.1. An empty file keeps the default path and a size of 0 never
+// rotates.
+func SetFile(file string, size int64) {
+	mu.Lock()
+	defer mu.Unlock()
+	if file != "" {
+		path = file
+	}
+	if size >= 0 {
+		maxSize = size
+	}
+}
+
+// SetVerbose sets whether warnings are also printed to stderr. Only use it
+// when no TUI is running, since stderr writes corrupt the alt screen.
+func SetVerbose(v bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	verbose = v
+}
+
+// Path returns the log file warnings are written to
+func Path() string {
+	mu.Lock()
+	defer mu.Unlock()
+	return path
+}
+
+// Warnf logs a warning to the log file and keeps it for Recent
+func Warnf(format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	entry := Entry{Time: time.Now(), Message: message}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	count++
+	recent = append(recent, entry)
+	if len(recent) > maxRecent {
+		recent = recent[len(recent)-maxRecent:]
+	}
+
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", message)
+	}
+
+	// Keep one line per warning even if the message has line breaks
+	line := fmt.Sprintf("%s WARN %s\n", entry.Time.Format(time.RFC3339), strings.ReplaceAll(message, "\n", " "))
+	if err := appendLine(line); err != nil && !verbose {
+		// Losing the warning would be worse than a garbled line on screen
+		fmt.Fprintf(os.Stderr, "Warning: %s (failed to write %s: %v)\n", message, path, err)
+	}
+}
+
+// appendLine appends a line to the log file, rotating it first if the line
+// would take it past maxSize
+func appendLine(line string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(path); err == nil && maxSize > 0 && info.Size()+int64(len(line)) > maxSize {
+		if err := os.Rename(path, path+".1"); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(line); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Count returns the number of warnings logged by this process
+func Count() int {
+	mu.Lock()
+	defer mu.Unlock()
+	return count
+}
+
+// Recent returns the most recent warnings of this process, oldest first
+func Recent() []Entry {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]Entry(nil), recent...)
+}
@@ -0,0 +1,197 @@
+package redact
+
+import (
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ScrubMask replaces each secret found by Scrub
+const ScrubMask = "•••REDACTED•••"
+
+// Thresholds for tokens that look random enough to be secrets
+const (
+	minSecretLength = 20   // Shorter tokens are too likely to be words or IDs
+	minSecretBits   = 4.0  // Shannon entropy in bits per character
+	minHexLength    = 32   // Hex keys; shorter hex strings are hashes and IDs
+	minClassChanges = 0.33 // Case and digit switches per character; random base64 has about 0.6
+)
+
+// knownSecretPatterns match secrets by their shape regardless of entropy.
+// The capture group marks the secret.
+var knownSecretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/=-]{8,})`),
+	regexp.MustCompile(`\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`),
+	regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]*)`),
+}
+
+// tokenPattern splits a command into the candidates checked for entropy:
+// runs of base64 and hex characters with their padding. Paths stay whole so
+// they can be recognized, and KEY=value splits into its parts.
+var tokenPattern = regexp.MustCompile(`[A-Za-z0-9+/_.~-]+=*`)
+
+// hexDigestPrefixes mark hex strings that are content digests, e.g.
+// image@sha256:<hex>
+var hexDigestPrefixes = []string{"sha1:", "sha256:", "sha512:"}
+
+// hexDigestLengths are the lengths of SHA-1, SHA-256 and SHA-512 hex
+// digests: git commits and objects, sha256sum output, docker image IDs.
+// Bare ones are hashes; only as the value of KEY=<hex> or key:<hex> are
+// they taken for keys.
+var hexDigestLengths = []int{40, 64, 128}
+
+// Scrub returns text with each likely secret replaced by ScrubMask, and
+// whether any was found. Besides known shapes (bearer tokens, AWS key IDs,
+// JWTs) it flags long tokens that look random: high-entropy base64 that
+// keeps switching between upper case, lower case and digits, and long hex
+// strings other than digests, which are only flagged as the value of a key.
+// Paths and URLs are never flagged as a whole.
+func Scrub(text string) (string, bool) {
+	var spans []span
+	for _, regex := range knownSecretPatterns {
+		for _, match := range regex.FindAllStringSubmatchIndex(text, -1) {
+			spans = append(spans, span{match[2], match[3]})
+		}
+	}
+
+	for _, match := range tokenPattern.FindAllStringIndex(text, -1) {
+		if isRandomToken(text[match[0]:match[1]], text[:match[0]]) {
+			spans = append(spans, span{match[0], match[1]})
+		}
+	}
+	if len(spans) == 0 {
+		return text, false
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	var out strings.Builder
+	pos := 0
+	for _, s := range spans {
+		if s.start < pos {
+			// Overlaps a secret already masked
+			if s.end > pos {
+				pos = s.end
+			}
+			continue
+		}
+		out.WriteString(text[pos:s.start])
+		out.WriteString(ScrubMask)
+		pos = s.end
+	}
+	out.WriteString(text[pos:])
+	return out.String(), true
+}
+
+// isRandomToken reports whether token looks like a generated secret rather
+// than a word, path or hash. before is the text preceding the token.
+func isRandomToken(token, before string) bool {
+	token = strings.TrimRight(token, "=")
+	if len(token) < minSecretLength {
+		return false
+	}
+
+	// Paths, file names and host names are made of words
+	if strings.ContainsAny(token, "/.~") && !looksLikeBase64(token) {
+		return false
+	}
+
+	if isHex(token) {
+		if len(token) < minHexLength {
+			return false
+		}
+		for _, prefix := range hexDigestPrefixes {
+			if strings.HasSuffix(before, prefix) {
+				return false
+			}
+		}
+		if slices.Contains(hexDigestLengths, len(token)) {
+			return strings.HasSuffix(before, "=") || strings.HasSuffix(before, ":")
+		}
+		return true
+	}
+
+	// Identifiers like MyServer2024Main mix classes too, but in words
+	if !hasMixedClasses(token) || classChanges(token) < minClassChanges {
+		return false
+	}
+	return entropy(token) >= minSecretBits
+}
+
+// looksLikeBase64 reports whether a token with slashes or dots is base64
+// data rather than a path: it doesn't start like a path, has none of the
+// dots, dashes and underscores that separate words in names, and switches
+// between upper case, lower case and digits as often as random data does
+func looksLikeBase64(token string) bool {
+	if strings.HasPrefix(token, "/") || strings.HasPrefix(token, "~") || strings.ContainsAny(token, ".-_") {
+		return false
+	}
+	return classChanges(token) >= minClassChanges
+}
+
+// classChanges returns how often adjacent characters of s differ in class
+// (upper case, lower case, digit, other), as a fraction of its length
+func classChanges(s string) float64 {
+	class := func(c byte) int {
+		switch {
+		case 'A' <= c && c <= 'Z':
+			return 0
+		case 'a' <= c && c <= 'z':
+			return 1
+		case '0' <= c && c <= '9':
+			return 2
+		}
+		return 3
+	}
+	changes := 0
+	for i := 1; i < len(s); i++ {
+		if class(s[i]) != class(s[i-1]) {
+			changes++
+		}
+	}
+	return float64(changes) / float64(len(s))
+}
+
+// isHex reports whether s is made of hex digits only
+func isHex(s string) bool {
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// hasMixedClasses reports whether s has upper and lower case letters and
+// digits, as generated keys do and words and identifiers mostly don't
+func hasMixedClasses(s string) bool {
+	var upper, lower, digit bool
+	for _, c := range s {
+		switch {
+		case 'A' <= c && c <= 'Z':
+			upper = true
+		case 'a' <= c && c <= 'z':
+			lower = true
+		case '0' <= c && c <= '9':
+			digit = true
+		}
+	}
+	return upper && lower && digit
+}
+
+// entropy returns the Shannon entropy of s in bits per character
+func entropy(s string) float64 {
+	counts := make(map[rune]int)
+	n := 0
+	for _, c := range s {
+		counts[c]++
+		n++
+	}
+	var bits float64
+	for _, count := range counts {
+		p := float64(count) / float64(n)
+		bits -= p * math.Log2(p)
+	}
+	return bits
+}
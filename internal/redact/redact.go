@@ -0,0 +1,96 @@
+package redact
+
+import (
+	"regexp"
+	"sort"
+)
+
+// Mask replaces each secret in a command
+const Mask = "********"
+
+// DefaultPatterns match common secrets in commands. The capture groups mark
+// the secret itself; a pattern without groups masks the whole match.
+var DefaultPatterns = []string{
+	`(?i)bearer\s+([^\s"']+)`,
+	`(?i)(?:password|passwd|token|secret|api[_-]?key)\s*[=:]\s*["']?([^\s"']+)`,
+	`(?i)--(?:password|passwd|token|secret|api-key)[=\s]+["']?([^\s"']+)`,
+	`://[^/\s:@]+:([^/\s@]+)@`,
+	`\b(gh[pousr]_[A-Za-z0-9]{20,})`,
+	`\b(AKIA[0-9A-Z]{16})\b`,
+}
+
+// Redactor masks secrets in command text
+type Redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// New compiles patterns into a Redactor, skipping invalid ones (config
+// validation reports them)
+func New(patterns []string) *Redactor {
+	r := &Redactor{}
+	for _, pattern := range patterns {
+		if regex, err := regexp.Compile(pattern); err == nil {
+			r.patterns = append(r.patterns, regex)
+		}
+	}
+	return r
+}
+
+// span is a byte range of text to mask
+type span struct {
+	start, end int
+}
+
+// Redact returns text with every secret replaced by Mask. Secrets matched
+// by several patterns are masked once.
+func (r *Redactor) Redact(text string) string {
+	if r == nil {
+		return text
+	}
+
+	var spans []span
+	for _, regex := range r.patterns {
+		for _, match := range regex.FindAllStringSubmatchIndex(text, -1) {
+			if len(match) == 2 {
+				spans = append(spans, span{match[0], match[1]})
+				continue
+			}
+			for g := 2; g+1 < len(match); g += 2 {
+				// Skip groups that didn't participate or matched nothing
+				if match[g] >= 0 && match[g+1] > match[g] {
+					spans = append(spans, span{match[g], match[g+1]})
+				}
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return text
+	}
+
+	// Merge overlapping secrets so each is masked once
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	merged := spans[:1]
+	for _, s := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if s.start <= last.end {
+			last.end = max(last.end, s.end)
+			continue
+		}
+		merged = append(merged, s)
+	}
+
+	var out []byte
+	pos := 0
+	for _, s := range merged {
+		out = append(out, text[pos:s.start]...)
+		out = append(out, Mask...)
+		pos = s.end
+	}
+	out = append(out, text[pos:]...)
+	return string(out)
+}
+
+// Contains reports whether text has a secret that Redact would mask
+func (r *Redactor) Contains(text string) bool {
+	return r.Redact(text) != text
+}
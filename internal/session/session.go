@@ -0,0 +1,68 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// State represents the UI state persisted between runs
+type State struct {
+	Mode     string `yaml:"mode"`
+	Sort     string `yaml:"sort"`
+	Query    string `yaml:"query"`
+	Selected string `yaml:"selected"`
+}
+
+// Load reads the saved session state, returning nil if none exists
+func Load() (*State, error) {
+	data, err := os.ReadFile(getStatePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var state State
+	err = yaml.Unmarshal(data, &state)
+	if err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+// Save writes the session state to the state file
+func (s *State) Save() error {
+	statePath := getStatePath()
+
+	// Create state directory if it doesn't exist
+	err := os.MkdirAll(filepath.Dir(statePath), 0755)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(statePath, data, 0644)
+}
+
+// Dir returns the directory state is kept in between runs
+func Dir() string {
+	stateDir := os.Getenv("XDG_STATE_HOME")
+	if stateDir == "" {
+		homeDir, _ := os.UserHomeDir()
+		stateDir = filepath.Join(homeDir, ".local", "state")
+	}
+	return filepath.Join(stateDir, "history-nav")
+}
+
+// getStatePath returns the path to the session state file
+func getStatePath() string {
+	return filepath.Join(Dir(), "session.yaml")
+}
@@ -0,0 +1,155 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// remotePattern matches an scp-style [user@]host:path argument
+var remotePattern = regexp.MustCompile(`^(?:[\w.-]+@)?[\w.-]+:(.+)$`)
+
+// redirectPattern matches the operator of a redirection written without a
+// space before its target, like 2>/dev/null
+var redirectPattern = regexp.MustCompile(`^[0-9]*(?:>>|>|<)&?`)
+
+// Paths returns the arguments of a command line that look like file paths,
+// in order and without duplicates. An argument is a path if it is absolute,
+// starts with ~, ./, ../ or a $VAR/, is a remote host:path (of which only
+// the path is returned), ends in a slash, contains a slash and names a file with an extension, or exists
+// relative to dir (the current directory if dir is empty). Program names
+// and flags are skipped, except for the value of a --flag=/absolute/path.
+func Paths(line, dir string) []string {
+	var paths []string
+	seen := make(map[string]bool)
+
+	words := Split(line)
+	for i := 0; i < len(words); i++ {
+		i = programIndex(words, i)
+		if i < len(words) && IsOperator(words[i]) {
+			continue
+		}
+		for i++; i < len(words) && !IsOperator(words[i]); i++ {
+			path := pathArgument(words[i], dir)
+			if path != "" && !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+// pathArgument returns the path a single argument refers to, or "" if it
+// doesn't look like one
+func pathArgument(word, dir string) string {
+	if op := redirectPattern.FindString(word); op != "" {
+		word = word[len(op):]
+		if word == "/dev/null" {
+			return ""
+		}
+	}
+
+	// Flags only count when their value is unambiguously a path, so
+	// --log-level=info/debug is not one
+	if strings.HasPrefix(word, "-") {
+		_, value, found := strings.Cut(word, "=")
+		if !found {
+			return ""
+		}
+		if path := remotePath(value); path != "" {
+			return path
+		}
+		if anchored(value) {
+			return value
+		}
+		return ""
+	}
+
+	if path := remotePath(word); path != "" {
+		return path
+	}
+	if isPath(word, dir) {
+		return word
+	}
+	return ""
+}
+
+// isPath reports whether a word that isn't a flag looks like a file path
+func isPath(word, dir string) bool {
+	switch {
+	case word == "" || word == "." || word == "..":
+		return false
+	case strings.Contains(word, "://"):
+		// URLs are not paths
+		return false
+	case anchored(word):
+		return true
+	}
+
+	if strings.Contains(word, "/") {
+		// Sed expressions like s/old/new/g, which may end in a slash
+		if (strings.HasPrefix(word, "s/") || strings.HasPrefix(word, "y/")) && strings.Count(word, "/") >= 3 {
+			return false
+		}
+		if strings.HasSuffix(word, "/") {
+			return true
+		}
+		// A file name with an extension, but not a package like
+		// github.com/x/y@v1.2 or an image like ghcr.io/org/app:1.0
+		base := word[strings.LastIndex(word, "/")+1:]
+		if strings.Contains(base, ".") && !strings.ContainsAny(base, "@:") {
+			return true
+		}
+	}
+
+	return exists(word, dir)
+}
+
+// anchored reports whether word starts at a well-known directory
+func anchored(word string) bool {
+	for _, prefix := range []string{"/", "~", "./", "../"} {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+	return strings.HasPrefix(word, "$") && strings.Contains(word, "/")
+}
+
+// remotePath returns the path of an scp-style host:path, or "" if word
+// isn't one. The path must contain a slash, so host:8080 is not one, and
+// URLs are not remote paths.
+func remotePath(word string) string {
+	if strings.Contains(word, "://") {
+		return ""
+	}
+	match := remotePattern.FindStringSubmatch(word)
+	if match == nil || !strings.Contains(match[1], "/") {
+		return ""
+	}
+	return match[1]
+}
+
+// exists reports whether word names an existing file relative to dir
+func exists(word, dir string) bool {
+	if dir != "" {
+		word = filepath.Join(dir, word)
+	}
+	_, err := os.Stat(word)
+	return err == nil
+}
+
+// Quote quotes word for a POSIX shell if it contains characters that would
+// split or expand it. A leading ~/ or $VAR/ stays unquoted so it still
+// expands.
+func Quote(word string) string {
+	if word != "" && !strings.ContainsAny(word, " \t\n'\"\\|&;()<>*?[]{}!#`") {
+		return word
+	}
+	prefix := ""
+	if slash := strings.Index(word, "/"); slash > 0 && (word[0] == '~' || word[0] == '$') && !strings.ContainsAny(word[:slash], " '\"") {
+		prefix, word = word[:slash+1], word[slash+1:]
+	}
+	return prefix + "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
@@ -0,0 +1,81 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPaths(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Makefile"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		line string
+		want string
+	}{
+		{`cat /etc/hosts`, "[/etc/hosts]"},
+		{`cp "~/My Documents/report.pdf" './backup dir/'`, "[~/My Documents/report.pdf ./backup dir/]"},
+		{`vim My\ Notes/todo.md`, "[My Notes/todo.md]"},
+		{`server --log-level=info/debug`, "[]"},
+		{`server --config=/etc/app.yaml --log-level=info/debug`, "[/etc/app.yaml]"},
+		{`sed -i s/old/new/g src/main.go`, "[src/main.go]"},
+		{`sed 's/a b/c/' notes.txt`, "[]"},
+		{`curl -o out/page.html https://example.com/index.html`, "[out/page.html]"},
+		{`git clone git@github.com:org/repo.git`, "[org/repo.git]"},
+		{`make test 2>/dev/null`, "[]"},
+		{`./build.sh >logs/build.log 2>&1`, "[logs/build.log]"},
+		{`sort < data/input.csv >> ~/sorted.csv`, "[data/input.csv ~/sorted.csv]"},
+		{`scp deploy@web-1:/var/log/app.log ./logs/`, "[/var/log/app.log ./logs/]"},
+		{`scp build/app.tar.gz web-1:releases/`, "[build/app.tar.gz releases/]"},
+		{`rsync -a ~/src/ backup:/srv/src/ && ls /srv`, "[~/src/ /srv/src/ /srv]"},
+		{`ssh web-1:8080`, "[]"},
+		{`docker pull ghcr.io/org/app:1.0`, "[]"},
+		{`go install golang.org/x/tools/gopls@v0.16.0`, "[]"},
+		{`make -f Makefile`, "[Makefile]"},
+		{`cat /etc/hosts /etc/hosts`, "[/etc/hosts]"},
+		{`sudo vim /etc/fstab`, "[/etc/fstab]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(Paths(tt.line, dir)); got != tt.want {
+			t.Errorf("Paths(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+// TestPathArgument checks single arguments, including the values of flags
+// and redirections written without a space
+func TestPathArgument(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"/tmp/out.txt", "/tmp/out.txt"},
+		{"$HOME/.zshrc", "$HOME/.zshrc"},
+		{"--output=/tmp/out.txt", "/tmp/out.txt"},
+		{"--log-level=info/debug", ""},
+		{"--remote=host:/srv/app", "/srv/app"},
+		{"--url=https://example.com/a/b", ""},
+		{"-v", ""},
+		{"2>/dev/null", ""},
+		{">>~/notes.md", "~/notes.md"},
+		{"2>errors/run.log", "errors/run.log"},
+		{"user@host:~/dotfiles/", "~/dotfiles/"},
+		{"host:8080", ""},
+		{"http://localhost:8080/api", ""},
+		{"s/foo/bar/", ""},
+		{"y/abc/xyz/", ""},
+		{".", ""},
+		{"..", ""},
+		{"cmd/app/", "cmd/app/"},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		if got := pathArgument(tt.word, dir); got != tt.want {
+			t.Errorf("pathArgument(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
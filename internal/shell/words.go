@@ -0,0 +1,149 @@
+package shell
+
+import "strings"
+
+// Split splits a command line into words the way a POSIX shell would
+// before expansion: quotes group words and are removed, backslashes escape
+// the next character, and the control operators | || & && ; are separate
+// words. Unterminated quotes run to the end of the line.
+func Split(line string) []string {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes):
+			i++
+			if runes[i] != '\n' {
+				word.WriteRune(runes[i])
+			}
+			inWord = true
+
+		case r == '\'':
+			// Everything up to the closing quote is literal
+			inWord = true
+			for i++; i < len(runes) && runes[i] != '\''; i++ {
+				word.WriteRune(runes[i])
+			}
+
+		case r == '"':
+			// Backslash only escapes the characters special inside double quotes
+			inWord = true
+			for i++; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`", runes[i+1]) {
+					i++
+				}
+				word.WriteRune(runes[i])
+			}
+
+		case r == ' ' || r == '\t' || r == '\n':
+			endWord()
+
+		case r == '|' || r == '&' || r == ';':
+			endWord()
+			op := string(r)
+			if r != ';' && i+1 < len(runes) && runes[i+1] == r {
+				op += string(r)
+				i++
+			}
+			words = append(words, op)
+
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	endWord()
+
+	return words
+}
+
+// IsOperator reports whether a word from Split is a control operator
+func IsOperator(word string) bool {
+	switch word {
+	case "|", "||", "&", "&&", ";":
+		return true
+	}
+	return false
+}
+
+// wrappers run the command that follows their options. The value lists
+// the short options that take a separate argument, like sudo -u root.
+var wrappers = map[string]string{
+	"sudo":    "ugCDhprtU",
+	"doas":    "uC",
+	"env":     "uCS",
+	"nice":    "n",
+	"time":    "fo",
+	"xargs":   "IadEsnPL",
+	"command": "",
+	"builtin": "",
+	"exec":    "a",
+	"nohup":   "",
+}
+
+// Program returns the program a command line runs, skipping leading
+// variable assignments and wrappers like sudo or env with their options.
+// It returns "" if there is none.
+func Program(line string) string {
+	words := Split(line)
+	if i := programIndex(words, 0); i < len(words) {
+		return words[i]
+	}
+	return ""
+}
+
+// programIndex returns the index of the program word of the simple
+// command starting at words[start], or the index of the operator ending it
+// (or len(words)) if it has none
+func programIndex(words []string, start int) int {
+	for i := start; i < len(words); i++ {
+		word := words[i]
+		if IsOperator(word) {
+			return i
+		}
+		if isAssignment(word) {
+			continue
+		}
+
+		valueOptions, wrapper := wrappers[word]
+		if !wrapper {
+			return i
+		}
+		for i+1 < len(words) && strings.HasPrefix(words[i+1], "-") {
+			i++
+			option := words[i]
+			if option == "--" {
+				break
+			}
+			if len(option) == 2 && strings.ContainsRune(valueOptions, rune(option[1])) {
+				i++
+			}
+		}
+	}
+	return len(words)
+}
+
+// isAssignment reports whether word is a NAME=value assignment
+func isAssignment(word string) bool {
+	name, _, found := strings.Cut(word, "=")
+	if !found || name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r != '_' && !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && (i == 0 || !(r >= '0' && r <= '9')) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+)
+
+// DefaultHalfLife is how long it takes by default for the weight of a
+// command's uses to halve
+const DefaultHalfLife = 7 * 24 * time.Hour
+
+// Frecency scores a command by how often and how recently it was run: its
+// count, halved for every halfLife since it was last run. Commands without
+// a timestamp count as one halfLife old.
+func Frecency(cmd history.Command, halfLife time.Duration, now time.Time) float64 {
+	age := halfLife
+	if !cmd.Timestamp.IsZero() {
+		age = max(now.Sub(cmd.Timestamp), 0)
+	}
+	return float64(cmd.Count) * math.Exp2(-float64(age)/float64(halfLife))
+}
+
+// SortByFrecency returns a copy of commands ordered by Frecency, highest
+// first, keeping newer commands first among equal scores. A command run 50
+// times a week ago outranks one run once an hour ago with the default half
+// life.
+func SortByFrecency(commands []history.Command, halfLife time.Duration, now time.Time) []history.Command {
+	scores := make([]float64, len(commands))
+	order := make([]int, len(commands))
+	for i, cmd := range commands {
+		scores[i] = Frecency(cmd, halfLife, now)
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if scores[a] != scores[b] {
+			return scores[a] > scores[b]
+		}
+		return commands[a].NewerThan(commands[b])
+	})
+
+	sorted := make([]history.Command, len(commands))
+	for i, k := range order {
+		sorted[i] = commands[k]
+	}
+	return sorted
+}
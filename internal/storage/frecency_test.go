@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+)
+
+func TestFrecency(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		cmd  history.Command
+		want float64
+	}{
+		{history.Command{Count: 8, Timestamp: now}, 8},
+		{history.Command{Count: 8, Timestamp: now.Add(-DefaultHalfLife)}, 4},
+		{history.Command{Count: 8, Timestamp: now.Add(-3 * DefaultHalfLife)}, 1},
+		{history.Command{Count: 8}, 4},                                // No timestamp
+		{history.Command{Count: 8, Timestamp: now.Add(time.Hour)}, 8}, // Future
+	}
+	for _, tt := range tests {
+		if got := Frecency(tt.cmd, DefaultHalfLife, now); got != tt.want {
+			t.Errorf("Frecency(%+v) = %v, want %v", tt.cmd, got, tt.want)
+		}
+	}
+}
+
+// TestSortByFrecency checks a command run 50 times a week ago outranks one
+// run once an hour ago, unless the half life is short
+func TestSortByFrecency(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	commands := []history.Command{
+		{Text: "git log -1", Count: 1, Timestamp: now.Add(-time.Hour)},
+		{Text: "make test", Count: 50, Timestamp: now.Add(-7 * 24 * time.Hour)},
+		{Text: "ls", Count: 1, Timestamp: now.Add(-2 * time.Hour)},
+	}
+
+	got := texts(SortByFrecency(commands, DefaultHalfLife, now))
+	if fmt.Sprint(got) != "[make test git log -1 ls]" {
+		t.Errorf("default half life = %q, want make test first", got)
+	}
+	got = texts(SortByFrecency(commands, time.Hour, now))
+	if fmt.Sprint(got) != "[git log -1 ls make test]" {
+		t.Errorf("one hour half life = %q, want the recent commands first", got)
+	}
+
+	// Equal scores keep newer commands first
+	tied := []history.Command{{Text: "a", Count: 1, Position: 0}, {Text: "b", Count: 1, Position: 1}}
+	if got := texts(SortByFrecency(tied, DefaultHalfLife, now)); fmt.Sprint(got) != "[b a]" {
+		t.Errorf("tied commands = %q, want the newer first", got)
+	}
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+)
+
+// Scores of a fuzzy match, in the spirit of fzf: every matched character
+// scores, runs of them and the starts of words score more, and skipped
+// characters cost a little
+const (
+	fuzzyMatch       = 16 // Each matched character
+	fuzzyBoundary    = 8  // Character starting a word, e.g. the d of docker or ~/drops
+	fuzzyConsecutive = 6  // Character right after the previous match
+	fuzzyGapStart    = -3 // First character skipped between matches
+	fuzzyGapExtend   = -1 // Each further character skipped
+	fuzzyMaxLead     = 10 // Most lost for where the match starts in the command
+)
+
+// SearchFuzzy finds commands containing the query words as subsequences, so
+// "gcmsg" finds git commit -m "msg", and returns them best match first,
+// with ties newest first. Like Search, "|" separates alternatives, a command
+// scoring by its best one, and words starting with "-" exclude commands. limit caps the results (0 means unlimited).
+func (s *MemoryStorage) SearchFuzzy(query string, limit int) []history.Command {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	parsed := ParseQuery(query)
+	if parsed.Empty() {
+		return limitCommands(s.commands, limit)
+	}
+
+	type scored struct {
+		cmd   history.Command
+		score int
+	}
+	var results []scored
+	for _, cmd := range s.commands {
+		text := strings.ToLower(cmd.Text)
+		if parsed.excludes(strings.Fields(text)) {
+			continue
+		}
+		best, found := 0, len(parsed.Groups) == 0
+		for _, group := range parsed.Groups {
+			if score, ok := groupScore(text, group); ok && (!found || score > best) {
+				best, found = score, true
+			}
+		}
+		if found {
+			results = append(results, scored{cmd, best})
+		}
+	}
+
+	// Commands are stored newest first, so a stable sort keeps that order
+	// between equal scores
+	sort.SliceStable(results, func(i, j int) bool { return results[i].score > results[j].score })
+
+	commands := make([]history.Command, len(results))
+	for i, result := range results {
+		commands[i] = result.cmd
+	}
+	return limitCommands(commands, limit)
+}
+
+// groupScore returns the summed score of every word of a group in text, and
+// whether all of them match
+func groupScore(text string, group []string) (int, bool) {
+	total := 0
+	for _, word := range group {
+		score, ok := FuzzyScore(word, text)
+		if !ok {
+			return 0, false
+		}
+		total += score
+	}
+	return total, true
+}
+
+// FuzzyScore reports whether the characters of pattern appear in text in
+// order, and how well: the score is that of the best placement, favoring
+// runs of characters at word starts near the beginning. Both are compared
+// as given, so callers lowercase them for a case-insensitive match.
+func FuzzyScore(pattern, text string) (int, bool) {
+	p := []rune(pattern)
+	t := []rune(text)
+	if len(p) == 0 {
+		return 0, true
+	}
+	if len(p) > len(t) {
+		return 0, false
+	}
+
+	// prev[j] and cur[j] are the best scores with the previous and current
+	// pattern character matched at t[j], or noMatch
+	const noMatch = -1 << 30
+	prev := make([]int, len(t))
+	cur := make([]int, len(t))
+	for i := range p {
+		// Best score of the previous character two or more positions back,
+		// less the gap up to j
+		gapped := noMatch
+		for j := range t {
+			if i > 0 && j >= 2 {
+				gapped = max(gapped+fuzzyGapExtend, prev[j-2]+fuzzyGapStart)
+			}
+			cur[j] = noMatch
+			if t[j] != p[i] {
+				continue
+			}
+			score := fuzzyMatch
+			if j == 0 || isWordStart(t[j-1], t[j]) {
+				score += fuzzyBoundary
+			}
+			if i == 0 {
+				cur[j] = score - min(j, fuzzyMaxLead)
+				continue
+			}
+			before := gapped
+			if j >= 1 {
+				before = max(before, prev[j-1]+fuzzyConsecutive)
+			}
+			if before > noMatch/2 {
+				cur[j] = before + score
+			}
+		}
+		prev, cur = cur, prev
+	}
+
+	best := noMatch
+	for _, score := range prev {
+		best = max(best, score)
+	}
+	if best <= noMatch/2 {
+		return 0, false
+	}
+	return best, true
+}
+
+// isWordStart reports whether c starts a word given the character before
+// it: after a space, path separator or punctuation, or a letter after a
+// digit
+func isWordStart(prev, c rune) bool {
+	if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+		return true
+	}
+	return unicode.IsLetter(c) && unicode.IsDigit(prev)
+}
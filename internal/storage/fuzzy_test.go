@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+)
+
+func TestFuzzyScoreMatches(t *testing.T) {
+	tests := []struct {
+		pattern, text string
+		want          bool
+	}{
+		{"gcmsg", `git commit -m "msg"`, true},
+		{"dkrps", "docker ps -a", true},
+		{"", "ls", true},
+		{"sl", "ls", false},
+		{"lss", "ls", false},
+		{"ü", "echo über", true},
+	}
+	for _, tt := range tests {
+		if _, ok := FuzzyScore(tt.pattern, tt.text); ok != tt.want {
+			t.Errorf("FuzzyScore(%q, %q) matched %t, want %t", tt.pattern, tt.text, ok, tt.want)
+		}
+	}
+}
+
+// TestFuzzyScoreOrder checks the first text of each pair scores above the
+// second
+func TestFuzzyScoreOrder(t *testing.T) {
+	tests := []struct {
+		pattern, better, worse string
+	}{
+		{"dps", "docker ps -a", "mkdir ~/drops"},
+		{"gcmsg", `git commit -m "msg"`, "go clean -modcache; ls -g"},
+		{"make", "make test", "cmake ."},
+		{"test", "test.sh", "go test ./..."},
+		{"ps", "ps aux", "pip show"},
+	}
+	for _, tt := range tests {
+		better, ok1 := FuzzyScore(tt.pattern, tt.better)
+		worse, ok2 := FuzzyScore(tt.pattern, tt.worse)
+		if !ok1 || !ok2 || better <= worse {
+			t.Errorf("FuzzyScore(%q): %q = %d, %q = %d, want the first higher", tt.pattern, tt.better, better, tt.worse, worse)
+		}
+	}
+}
+
+func TestSearchFuzzy(t *testing.T) {
+	s := NewMemoryStorage()
+	s.Store([]history.Command{
+		{Text: "mkdir ~/drops", Position: 3, Count: 1},
+		{Text: "docker ps -a", Position: 2, Count: 1},
+		{Text: "ls -la", Position: 1, Count: 1},
+		{Text: "docker ps", Position: 0, Count: 1},
+	})
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"dps", "[docker ps -a docker ps mkdir ~/drops]"},
+		{"dps -a", "[docker ps mkdir ~/drops]"},
+		{"lsla | dkrps", "[docker ps -a docker ps ls -la]"},
+		{"zzz", "[]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(texts(s.SearchFuzzy(tt.query, 0))); got != tt.want {
+			t.Errorf("SearchFuzzy(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+
+	// Exact mode doesn't match subsequences
+	if got := s.Search("dkrps", 0); len(got) != 0 {
+		t.Errorf("Search(dkrps) = %q, want nothing", texts(got))
+	}
+}
@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+)
+
+// indexWords are the words random commands and queries are made of
+var indexWords = []string{
+	"git", "status", "commit", "-m", "\"fix\"", "push", "--force", "docker", "run",
+	"./build.sh", "ls", "-la", "kubectl", "get", "pods", "&&", "make", "test", "a",
+}
+
+// randomCommand returns a command of one to four random words
+func randomCommand(rng *rand.Rand, position int) history.Command {
+	words := make([]string, 1+rng.Intn(4))
+	for i := range words {
+		words[i] = indexWords[rng.Intn(len(indexWords))]
+	}
+	return history.Command{Text: strings.Join(words, " "), Position: position, Count: 1 + rng.Intn(3)}
+}
+
+// randomQuery returns a query of one or two words or word prefixes,
+// sometimes with an alternative or an excluded word
+func randomQuery(rng *rand.Rand) string {
+	word := func() string {
+		w := indexWords[rng.Intn(len(indexWords))]
+		return w[:1+rng.Intn(len(w))]
+	}
+	query := word()
+	switch rng.Intn(4) {
+	case 0:
+		query += " " + word()
+	case 1:
+		query += " | " + word()
+	case 2:
+		query += " -" + strings.TrimLeft(word(), "-")
+	}
+	return query
+}
+
+// postings returns the texts indexed under each term
+func postings(s *MemoryStorage) map[string][]string {
+	texts := make(map[int]string, len(s.ids))
+	for text, id := range s.ids {
+		texts[id] = text
+	}
+	result := make(map[string][]string, len(s.indexed))
+	for term, ids := range s.indexed {
+		for _, id := range ids {
+			result[term] = append(result[term], texts[id])
+		}
+		sort.Strings(result[term])
+	}
+	return result
+}
+
+// linearSearch is Search without the index: every command checked in full
+func linearSearch(s *MemoryStorage, query string) []string {
+	parsed := ParseQuery(query)
+	var results []string
+	for _, cmd := range s.GetAll() {
+		if parsed.Empty() || parsed.matches(strings.Fields(strings.ToLower(cmd.Text))) {
+			results = append(results, cmd.Text)
+		}
+	}
+	return results
+}
+
+// TestIncrementalIndexMatchesRebuild applies random sequences of Append and
+// Remove and checks the index and search results equal those of a storage
+// rebuilt from scratch with Store, and of a search without the index
+func TestIncrementalIndexMatchesRebuild(t *testing.T) {
+	for seed := int64(1); seed <= 50; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		incremental := NewMemoryStorage()
+		position := 0
+		var initial []history.Command
+		seen := make(map[string]bool)
+		for i := 0; i < 20; i++ {
+			cmd := randomCommand(rng, position)
+			position++
+			if !seen[cmd.Text] {
+				seen[cmd.Text] = true
+				initial = append(initial, cmd)
+			}
+		}
+		incremental.Store(initial)
+
+		for op := 0; op < 40; op++ {
+			if rng.Intn(3) == 0 {
+				all := incremental.GetAll()
+				if len(all) > 0 {
+					incremental.Remove(all[rng.Intn(len(all))].Text)
+				}
+			} else {
+				var added []history.Command
+				for i := rng.Intn(4); i >= 0; i-- {
+					added = append(added, randomCommand(rng, position))
+					position++
+				}
+				incremental.Append(added)
+			}
+
+			rebuilt := NewMemoryStorage()
+			rebuilt.Store(incremental.GetAll())
+			context := fmt.Sprintf("seed %d, operation %d", seed, op)
+
+			if got, want := postings(incremental), postings(rebuilt); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Fatalf("%s: incremental postings\n%v\nwant\n%v", context, got, want)
+			}
+			if !slices.Equal(incremental.terms, rebuilt.terms) {
+				t.Fatalf("%s: incremental terms %q, want %q", context, incremental.terms, rebuilt.terms)
+			}
+			for q := 0; q < 5; q++ {
+				query := randomQuery(rng)
+				got := texts(incremental.Search(query, 0))
+				if want := texts(rebuilt.Search(query, 0)); !slices.Equal(got, want) {
+					t.Fatalf("%s: Search(%q) = %q, rebuilt %q", context, query, got, want)
+				}
+				if want := linearSearch(incremental, query); !slices.Equal(got, want) {
+					t.Fatalf("%s: Search(%q) = %q, without index %q", context, query, got, want)
+				}
+			}
+		}
+	}
+}
+
+// TestSearchWithTotal checks search results stop at the limit while every
+// match is counted
+func TestSearchWithTotal(t *testing.T) {
+	s := NewMemoryStorage()
+	s.Store([]history.Command{
+		{Text: "git status", Position: 4, Count: 1},
+		{Text: "make test", Position: 3, Count: 1},
+		{Text: "git push", Position: 2, Count: 1},
+		{Text: "git log", Position: 1, Count: 1},
+		{Text: "ls", Position: 0, Count: 1},
+	})
+
+	tests := []struct {
+		query string
+		limit int
+		want  string
+		total int
+	}{
+		{"git", 0, "[git status git push git log]", 3},
+		{"git", 2, "[git status git push]", 3},
+		{"git", 5, "[git status git push git log]", 3},
+		{"git -push", 1, "[git status]", 2},
+		{"docker", 1, "[]", 0},
+		{"", 2, "[git status make test]", 5},
+	}
+	for _, tt := range tests {
+		results, total := s.SearchWithTotal(tt.query, tt.limit)
+		if got := fmt.Sprint(texts(results)); got != tt.want || total != tt.total {
+			t.Errorf("SearchWithTotal(%q, %d) = %s of %d, want %s of %d", tt.query, tt.limit, got, total, tt.want, tt.total)
+		}
+		if got := fmt.Sprint(texts(s.Search(tt.query, tt.limit))); got != tt.want {
+			t.Errorf("Search(%q, %d) = %s, want %s", tt.query, tt.limit, got, tt.want)
+		}
+	}
+}
+
+// texts returns the text of each command
+func texts(commands []history.Command) []string {
+	var result []string
+	for _, cmd := range commands {
+		result = append(result, cmd.Text)
+	}
+	return result
+}
+
+// BenchmarkSearch searches 20000 commands for a two-word query
+func BenchmarkSearch(b *testing.B) {
+	s := NewMemoryStorage()
+	s.Store(makeCommands(20000))
+	s.Append([]history.Command{{Text: "docker compose up -d", Position: 20000, Count: 1}})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Search("docker comp", 100)
+	}
+}
+
+// BenchmarkSearchCommon searches 20000 commands for a word all of them have
+func BenchmarkSearchCommon(b *testing.B) {
+	s := NewMemoryStorage()
+	s.Store(makeCommands(20000))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Search("git", 100)
+	}
+}
+
+// BenchmarkSearchWithoutIndex checks every one of 20000 commands for a
+// two-word query, as Search did before it used the postings
+func BenchmarkSearchWithoutIndex(b *testing.B) {
+	s := NewMemoryStorage()
+	s.Store(makeCommands(20000))
+	s.Append([]history.Command{{Text: "docker compose up -d", Position: 20000, Count: 1}})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		linearSearch(s, "docker comp")
+	}
+}
+
+// BenchmarkSearchWithTotal takes the 1000 newest of 20000 matches and
+// counts the rest, as the UI does with ui.max_items
+func BenchmarkSearchWithTotal(b *testing.B) {
+	s := NewMemoryStorage()
+	s.Store(makeCommands(20000))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.SearchWithTotal("git", 1000)
+	}
+}
+
+// BenchmarkSearchUnlimited copies all 20000 matches before taking the 1000
+// newest, as the UI did before passing ui.max_items to storage
+func BenchmarkSearchUnlimited(b *testing.B) {
+	s := NewMemoryStorage()
+	s.Store(makeCommands(20000))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		limitCommands(s.Search("git", 0), 1000)
+	}
+}
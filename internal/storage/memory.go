@@ -1,25 +1,64 @@
 package storage
 
 import (
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/4ndew/terminal-history-navigator/internal/history"
 )
 
-// Storage interface defines methods for storing and retrieving commands
+// Storage interface defines methods for storing and retrieving commands.
+// Implementations are safe for concurrent use, so a background refresh can
+// store commands while the UI reads them. Returned slices are copies the
+// caller owns.
 type Storage interface {
-	Store(commands []history.Command)
-	Search(query string) []history.Command
-	GetByFrequency() []history.Command
+	Store(commands []history.Command) MergeResult
+	Append(commands []history.Command) MergeResult
+	Search(query string, limit int) []history.Command
+	SearchWithTotal(query string, limit int) ([]history.Command, int)
+	SearchFuzzy(query string, limit int) []history.Command
+	GetByFrequency(minCount, limit int) []history.Command
+	GetByFrequencyWithTotal(minCount, limit int) ([]history.Command, int)
 	GetRecent(limit int) []history.Command
+	GetRecentWithTotal(limit int) ([]history.Command, int)
+	GetByExitStatus(failed bool, limit int) []history.Command
+	GetByTimeRange(from, to time.Time) []history.Command
+	SearchTimeRange(query string, from, to time.Time) []history.Command
 	GetAll() []history.Command
+	StoreOccurrences(occurrences []history.Command)
+	GetOccurrences(limit int) []history.Command
+	GetSessions(gap time.Duration) []Session
+	GetSuccessors(text string, limit int) []CountEntry
+	Remove(texts ...string) int
+	Pin(text string)
+	Unpin(text string)
+	IsPinned(text string) bool
+	GetPinned() []history.Command
+	CorrectQuery(query string) string
 }
 
 // MemoryStorage implements in-memory storage for commands
 type MemoryStorage struct {
-	commands []history.Command
-	indexed  map[string][]int // Maps words to command indices for fast search
+	mu          sync.RWMutex      // Guards every field below
+	commands    []history.Command // Sorted by position, newest first
+	byFrequency []history.Command // Cached GetByFrequency ordering, nil until needed
+	minCount    int               // Threshold byFrequency was computed for
+	indexed     map[string][]int  // Maps words to the document IDs of commands containing them
+	terms       []string          // Keys of indexed, sorted for prefix lookups
+	ids         map[string]int    // Maps command text to its document ID, stable across Append and Remove
+	order       []int             // Document ID of each command in commands
+	nextID      int
+	occurrences []history.Command // Every run of every command, before deduplication
+	pinned      []string          // Texts of pinned commands, in the order they were pinned
+}
+
+// MergeResult reports how Store or Append changed the stored commands
+type MergeResult struct {
+	Added   []string // Texts of commands that were not stored before
+	Updated int      // Commands already stored that were run again
 }
 
 // NewMemoryStorage creates a new in-memory storage instance
@@ -27,59 +66,276 @@ func NewMemoryStorage() *MemoryStorage {
 	return &MemoryStorage{
 		commands: make([]history.Command, 0),
 		indexed:  make(map[string][]int),
+		ids:      make(map[string]int),
 	}
 }
 
-// Store saves commands to memory and builds search index
-func (s *MemoryStorage) Store(commands []history.Command) {
-	s.commands = commands
+// Store saves commands to memory, sorted newest first, and builds the
+// search index. A command counts as updated if its count grew or it has a
+// newer timestamp than before.
+func (s *MemoryStorage) Store(commands []history.Command) MergeResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := make(map[string]history.Command, len(s.commands))
+	for _, cmd := range s.commands {
+		previous[cmd.Text] = cmd
+	}
+
+	var result MergeResult
+	for _, cmd := range commands {
+		old, found := previous[cmd.Text]
+		switch {
+		case !found:
+			result.Added = append(result.Added, cmd.Text)
+		case cmd.Count > old.Count || cmd.Timestamp.After(old.Timestamp):
+			result.Updated++
+		}
+	}
+
+	s.commands = make([]history.Command, len(commands))
+	copy(s.commands, commands)
+
+	s.sortCommands()
+	s.byFrequency = nil
 	s.buildIndex()
+	return result
 }
 
-// Search finds commands matching the query string with improved word matching
-func (s *MemoryStorage) Search(query string) []history.Command {
-	if query == "" {
-		return s.GetRecent(1000) // Return recent commands if no query
+// StoreOccurrences saves the non-deduplicated command runs used for
+// session grouping
+func (s *MemoryStorage) StoreOccurrences(occurrences []history.Command) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.occurrences = occurrences
+}
+
+// GetOccurrences returns every stored run of every command, newest first,
+// each as its own entry with a count of one (limit 0 means unlimited)
+func (s *MemoryStorage) GetOccurrences(limit int) []history.Command {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	occurrences := s.occurrences
+	if limit > 0 && limit < len(occurrences) {
+		occurrences = occurrences[:limit]
 	}
+	result := make([]history.Command, len(occurrences))
+	copy(result, occurrences)
+	return result
+}
 
-	query = strings.ToLower(query)
-	queryWords := strings.Fields(query)
+// GetSessions groups the stored occurrences into sessions separated by
+// pauses longer than gap, newest first
+func (s *MemoryStorage) GetSessions(gap time.Duration) []Session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return GroupSessions(s.occurrences, gap)
+}
 
-	// Find commands that contain ALL query words as whole words or prefixes
-	var results []history.Command
+// GetSuccessors returns the commands that most often ran right after text,
+// ignoring successors seen only once
+func (s *MemoryStorage) GetSuccessors(text string, limit int) []CountEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Successors(s.occurrences, text, 2, limit)
+}
 
+// sortCommands orders the stored commands newest first, so read paths
+// never have to sort
+func (s *MemoryStorage) sortCommands() {
+	sort.SliceStable(s.commands, func(i, j int) bool {
+		return s.commands[i].NewerThan(s.commands[j])
+	})
+}
+
+// Append merges commands, such as those appended to the history files since
+// the last read, into the storage without rebuilding the index. A command
+// already stored has its count summed and keeps the metadata of its newest
+// occurrence; new commands are indexed individually.
+func (s *MemoryStorage) Append(commands []history.Command) MergeResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result MergeResult
+	if len(commands) == 0 {
+		return result
+	}
+
+	positions := make(map[string]int, len(s.commands))
+	for i, cmd := range s.commands {
+		positions[cmd.Text] = i
+	}
+	stored := len(s.commands)
+	updated := make(map[string]bool)
+
+	for _, cmd := range commands {
+		i, found := positions[cmd.Text]
+		if !found {
+			positions[cmd.Text] = len(s.commands)
+			s.commands = append(s.commands, cmd)
+			s.indexCommand(cmd.Text)
+			result.Added = append(result.Added, cmd.Text)
+			continue
+		}
+
+		// A command repeated within commands is still one update
+		if i < stored {
+			updated[cmd.Text] = true
+		}
+		existing := &s.commands[i]
+		count := existing.Count + cmd.Count
+		if cmd.NewerThan(*existing) {
+			*existing = cmd
+		}
+		existing.Count = count
+	}
+
+	s.sortCommands()
+	s.reposition()
+	s.byFrequency = nil
+	result.Updated = len(updated)
+	return result
+}
+
+// Remove deletes the commands with the given texts, their occurrences and
+// their postings, returning how many were removed
+func (s *MemoryStorage) Remove(texts ...string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	remove := make(map[string]bool, len(texts))
+	for _, text := range texts {
+		remove[text] = true
+	}
+
+	kept := make([]history.Command, 0, len(s.commands))
+	removed := 0
 	for _, cmd := range s.commands {
-		cmdText := strings.ToLower(cmd.Text)
+		if remove[cmd.Text] {
+			s.unindexCommand(cmd.Text)
+			removed++
+			continue
+		}
+		kept = append(kept, cmd)
+	}
+	s.commands = kept
+
+	if removed > 0 {
+		s.reposition()
+		s.byFrequency = nil
 
-		if s.commandMatchesQuery(cmdText, queryWords) {
-			results = append(results, cmd)
+		occurrences := make([]history.Command, 0, len(s.occurrences))
+		for _, cmd := range s.occurrences {
+			if !remove[cmd.Text] {
+				occurrences = append(occurrences, cmd)
+			}
 		}
+		s.occurrences = occurrences
 	}
+	return removed
+}
 
-	// Sort by position (newest first - higher position = newer)
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Position > results[j].Position
-	})
+// Search finds commands matching the query string with improved word matching,
+// returning at most limit results (0 means unlimited)
+func (s *MemoryStorage) Search(query string, limit int) []history.Command {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
+	results, _ := s.search(query, limit, false)
 	return results
 }
 
-// commandMatchesQuery checks if a command matches all query words
-func (s *MemoryStorage) commandMatchesQuery(cmdText string, queryWords []string) bool {
-	cmdWords := strings.Fields(cmdText)
+// SearchWithTotal finds commands matching the query like Search, returning
+// at most limit of them (0 means unlimited) and how many match in all
+func (s *MemoryStorage) SearchWithTotal(query string, limit int) ([]history.Command, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.search(query, limit, true)
+}
+
+// search returns at most limit commands matching the query, newest first.
+// With count set it checks every command to return the number of matches,
+// otherwise it stops at the limit and the number returned is a lower bound.
+func (s *MemoryStorage) search(query string, limit int, count bool) ([]history.Command, int) {
+	if query == "" {
+		return limitCommands(s.commands, limit), len(s.commands) // Return recent commands if no query
+	}
+
+	parsed := ParseQuery(query)
+	if parsed.Empty() {
+		return limitCommands(s.commands, limit), len(s.commands)
+	}
 
-	// Check if command contains ALL query words
-	for _, queryWord := range queryWords {
-		if !s.commandContainsWord(cmdWords, queryWord) {
-			return false
+	// Look up the commands that may contain all words of any group in the
+	// postings, then check each in full, newest first. With only excluded
+	// words every command is checked.
+	var candidates []bool
+	if len(parsed.Groups) > 0 {
+		candidates = s.candidates(parsed.Groups)
+	}
+	var results []history.Command
+	total := 0
+	for position, id := range s.order {
+		if candidates != nil && !candidates[id] {
+			continue
+		}
+		if limit > 0 && len(results) == limit && !count {
+			break
+		}
+		cmd := s.commands[position]
+		if parsed.matches(strings.Fields(strings.ToLower(cmd.Text))) {
+			total++
+			if limit == 0 || len(results) < limit {
+				results = append(results, cmd)
+			}
 		}
 	}
 
-	return true
+	return results, total
+}
+
+// candidates returns which document IDs are indexed under a prefix of every
+// word of at least one group. This is a superset of the commands the groups
+// match: every word a command matches by is indexed as it was typed and
+// cleaned.
+func (s *MemoryStorage) candidates(groups [][]string) []bool {
+	found := make([]bool, s.nextID)
+	for _, group := range groups {
+		ids := s.withPrefix(group[0])
+		for _, word := range group[1:] {
+			next := s.withPrefix(word)
+			for id := range ids {
+				ids[id] = ids[id] && next[id]
+			}
+		}
+		for id, ok := range ids {
+			found[id] = found[id] || ok
+		}
+	}
+	return found
+}
+
+// withPrefix returns which document IDs are indexed under a term starting
+// with prefix
+func (s *MemoryStorage) withPrefix(prefix string) []bool {
+	ids := make([]bool, s.nextID)
+	start, _ := slices.BinarySearch(s.terms, prefix)
+	for _, term := range s.terms[start:] {
+		if !strings.HasPrefix(term, prefix) {
+			break
+		}
+		for _, id := range s.indexed[term] {
+			ids[id] = true
+		}
+	}
+	return ids
 }
 
 // commandContainsWord checks if command contains a word as whole word or prefix
-func (s *MemoryStorage) commandContainsWord(cmdWords []string, queryWord string) bool {
+func commandContainsWord(cmdWords []string, queryWord string) bool {
 	for _, cmdWord := range cmdWords {
 		// Clean command word of common shell characters
 		cleanCmdWord := cleanWord(cmdWord)
@@ -101,25 +357,44 @@ func (s *MemoryStorage) commandContainsWord(cmdWords []string, queryWord string)
 	return false
 }
 
-// GetByFrequency returns commands sorted by usage frequency
-func (s *MemoryStorage) GetByFrequency() []history.Command {
+// GetByFrequency returns commands used at least minCount times sorted by
+// usage frequency, returning at most limit results (0 means unlimited)
+func (s *MemoryStorage) GetByFrequency(minCount, limit int) []history.Command {
+	commands, _ := s.GetByFrequencyWithTotal(minCount, limit)
+	return commands
+}
+
+// GetByFrequencyWithTotal returns commands like GetByFrequency, and how
+// many it would return without the limit
+func (s *MemoryStorage) GetByFrequencyWithTotal(minCount, limit int) ([]history.Command, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.byFrequency == nil || s.minCount != minCount {
+		s.byFrequency = s.sortByFrequency(minCount)
+		s.minCount = minCount
+	}
+	return limitCommands(s.byFrequency, limit), len(s.byFrequency)
+}
+
+// sortByFrequency returns a copy of the commands ordered for GetByFrequency
+func (s *MemoryStorage) sortByFrequency(minCount int) []history.Command {
 	commands := make([]history.Command, len(s.commands))
 	copy(commands, s.commands)
 
-	// Filter commands with count > 1 to show only frequently used ones
+	// Keep commands used at least minCount times
 	var frequentCommands []history.Command
+	repeated := false
 	for _, cmd := range commands {
-		if cmd.Count > 1 {
+		if cmd.Count >= minCount {
 			frequentCommands = append(frequentCommands, cmd)
 		}
+		repeated = repeated || cmd.Count > 1
 	}
 
-	// If no frequent commands, fallback to all commands sorted by simulated frequency
-	if len(frequentCommands) == 0 {
-		// Simulate frequency based on command characteristics
-		for i := range commands {
-			commands[i].Count = s.calculateSimulatedFrequency(commands[i])
-		}
+	// If no command was ever repeated, list them all rather than nothing;
+	// with equal counts they come out newest first
+	if !repeated {
 		frequentCommands = commands
 	}
 
@@ -129,107 +404,312 @@ func (s *MemoryStorage) GetByFrequency() []history.Command {
 		if frequentCommands[i].Count != frequentCommands[j].Count {
 			return frequentCommands[i].Count > frequentCommands[j].Count
 		}
-		// Secondary sort by position - higher position (more recent) first
-		return frequentCommands[i].Position > frequentCommands[j].Position
+		// Secondary sort by recency - more recent first
+		return frequentCommands[i].NewerThan(frequentCommands[j])
 	})
 
+	// Never nil, so an empty result is cached too
+	if frequentCommands == nil {
+		frequentCommands = []history.Command{}
+	}
 	return frequentCommands
 }
 
-// calculateSimulatedFrequency simulates frequency based on command patterns
-func (s *MemoryStorage) calculateSimulatedFrequency(cmd history.Command) int {
-	// Base frequency
-	freq := 1
+// SortByCount returns a copy of commands ordered by usage count, most
+// used first, keeping newer commands first among equal counts
+func SortByCount(commands []history.Command) []history.Command {
+	sorted := make([]history.Command, len(commands))
+	copy(sorted, commands)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].NewerThan(sorted[j])
+	})
+	return sorted
+}
 
-	// Boost common development commands
-	commonPatterns := []string{
-		"git", "make", "npm", "yarn", "docker", "cd", "ls", "vim", "code",
-		"python", "node", "go", "cargo", "mvn", "gradle", "pip", "brew",
+// BoostDirectory returns a copy of commands with those run in dir moved to
+// the front, keeping the order within both groups
+func BoostDirectory(commands []history.Command, dir string) []history.Command {
+	boosted := make([]history.Command, 0, len(commands))
+	for _, cmd := range commands {
+		if cmd.Directory == dir {
+			boosted = append(boosted, cmd)
+		}
+	}
+	for _, cmd := range commands {
+		if cmd.Directory != dir {
+			boosted = append(boosted, cmd)
+		}
 	}
+	return boosted
+}
 
-	for _, pattern := range commonPatterns {
-		if strings.Contains(strings.ToLower(cmd.Text), pattern) {
-			freq += 2
-			break
+// FilterByDirectory returns the commands run in dir
+func FilterByDirectory(commands []history.Command, dir string) []history.Command {
+	var filtered []history.Command
+	for _, cmd := range commands {
+		if cmd.Directory == dir {
+			filtered = append(filtered, cmd)
 		}
 	}
+	return filtered
+}
 
-	// Boost short, likely-repeated commands
-	if len(cmd.Text) < 10 {
-		freq += 1
+// FilterByExitStatus returns the commands whose last run failed (exited
+// non-zero), or succeeded if failed is false. Commands without a recorded
+// exit code are in neither list.
+func FilterByExitStatus(commands []history.Command, failed bool) []history.Command {
+	var filtered []history.Command
+	for _, cmd := range commands {
+		if cmd.HasExit && (cmd.ExitCode != 0) == failed {
+			filtered = append(filtered, cmd)
+		}
 	}
+	return filtered
+}
 
-	// Commands that don't have parameters are likely used more often
-	if !strings.Contains(cmd.Text, " ") {
-		freq += 1
+// FilterByTime returns the commands timestamped within [since, until].
+// A zero bound is open. Commands without a timestamp are excluded when
+// either bound is set.
+func FilterByTime(commands []history.Command, since, until time.Time) []history.Command {
+	if since.IsZero() && until.IsZero() {
+		return commands
 	}
 
-	return freq
+	var filtered []history.Command
+	for _, cmd := range commands {
+		if cmd.Timestamp.IsZero() {
+			continue
+		}
+		if !since.IsZero() && cmd.Timestamp.Before(since) {
+			continue
+		}
+		if !until.IsZero() && cmd.Timestamp.After(until) {
+			continue
+		}
+		filtered = append(filtered, cmd)
+	}
+	return filtered
 }
 
-// GetRecent returns the most recently used commands (newest first)
+// GetRecent returns the most recently used commands (newest first), returning
+// at most limit results (0 means unlimited)
 func (s *MemoryStorage) GetRecent(limit int) []history.Command {
-	commands := make([]history.Command, len(s.commands))
-	copy(commands, s.commands)
+	commands, _ := s.GetRecentWithTotal(limit)
+	return commands
+}
 
-	// Sort by position (highest position first - newest commands)
-	sort.Slice(commands, func(i, j int) bool {
-		return commands[i].Position > commands[j].Position
-	})
+// GetRecentWithTotal returns the most recently used commands like
+// GetRecent, and how many commands are stored
+func (s *MemoryStorage) GetRecentWithTotal(limit int) ([]history.Command, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return limitCommands(s.commands, limit), len(s.commands)
+}
 
-	if limit > 0 && limit < len(commands) {
-		commands = commands[:limit]
+// GetByExitStatus returns the commands whose last run failed, or succeeded
+// if failed is false, newest first, returning at most limit results (0
+// means unlimited). Commands without a recorded exit code are left out.
+func (s *MemoryStorage) GetByExitStatus(failed bool, limit int) []history.Command {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matching []history.Command
+	for _, cmd := range s.commands {
+		if cmd.HasExit && (cmd.ExitCode != 0) == failed {
+			matching = append(matching, cmd)
+			if limit > 0 && len(matching) == limit {
+				break
+			}
+		}
 	}
+	return matching
+}
 
-	return commands
+// GetByTimeRange returns every run timestamped within [from, to], oldest
+// first so it reads as a timeline. A zero bound is open. A run without a
+// timestamp, or with an unreadable one, is placed at the run before it in
+// the same file and marked Inferred; it is left out if no run before it
+// has a timestamp. Without stored occurrences, the commands are placed at
+// their last run.
+func (s *MemoryStorage) GetByTimeRange(from, to time.Time) []history.Command {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.searchTimeRange("", from, to)
+}
+
+// SearchTimeRange returns the runs matching the query, as in Search, that
+// are timestamped within [from, to], oldest first like GetByTimeRange
+func (s *MemoryStorage) SearchTimeRange(query string, from, to time.Time) []history.Command {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.searchTimeRange(query, from, to)
+}
+
+// searchTimeRange implements SearchTimeRange for callers holding the lock
+func (s *MemoryStorage) searchTimeRange(query string, from, to time.Time) []history.Command {
+	runs := s.occurrences
+	if len(runs) == 0 {
+		runs = s.commands
+	}
+	parsed := ParseQuery(query)
+
+	var matching []history.Command
+	previous := make(map[string]time.Time) // Last timestamp seen in each source
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+		if run.Timestamp.IsZero() {
+			run.Timestamp = previous[run.Source]
+			run.Inferred = !run.Timestamp.IsZero()
+		} else {
+			previous[run.Source] = run.Timestamp
+		}
+		if run.Timestamp.IsZero() ||
+			!from.IsZero() && run.Timestamp.Before(from) ||
+			!to.IsZero() && run.Timestamp.After(to) {
+			continue
+		}
+		if !parsed.Empty() && !parsed.matches(strings.Fields(strings.ToLower(run.Text))) {
+			continue
+		}
+		matching = append(matching, run)
+	}
+
+	// Runs from several files are merged by position where some lack a
+	// timestamp, which can leave timestamped ones out of order
+	sort.SliceStable(matching, func(i, j int) bool { return matching[i].Timestamp.Before(matching[j].Timestamp) })
+	return matching
 }
 
 // GetAll returns all stored commands (sorted by position, newest first)
 func (s *MemoryStorage) GetAll() []history.Command {
-	commands := make([]history.Command, len(s.commands))
-	copy(commands, s.commands)
-
-	// Sort by position (highest position first - newest commands)
-	sort.Slice(commands, func(i, j int) bool {
-		return commands[i].Position > commands[j].Position
-	})
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return limitCommands(s.commands, 0)
+}
 
-	return commands
+// limitCommands returns a copy of at most limit commands (0 means
+// unlimited), so callers can keep it after the lock is released
+func limitCommands(commands []history.Command, limit int) []history.Command {
+	if limit > 0 && limit < len(commands) {
+		commands = commands[:limit]
+	}
+	return slices.Clone(commands)
 }
 
-// buildIndex creates a search index for fast text searching
+// buildIndex creates a search index for fast text searching, assigning
+// every command a fresh document ID
 func (s *MemoryStorage) buildIndex() {
 	s.indexed = make(map[string][]int)
+	s.ids = make(map[string]int, len(s.commands))
+	s.nextID = 0
 
-	for i, cmd := range s.commands {
-		// Index individual words from the command
-		words := strings.Fields(strings.ToLower(cmd.Text))
-
-		for _, word := range words {
-			// Clean word of common shell characters
-			word = cleanWord(word)
-			if word == "" {
-				continue
-			}
+	for _, cmd := range s.commands {
+		s.addPostings(cmd.Text)
+	}
 
-			if _, exists := s.indexed[word]; !exists {
-				s.indexed[word] = make([]int, 0)
-			}
-			s.indexed[word] = append(s.indexed[word], i)
+	s.terms = make([]string, 0, len(s.indexed))
+	for term := range s.indexed {
+		s.terms = append(s.terms, term)
+	}
+	slices.Sort(s.terms)
+	s.reposition()
+}
+
+// indexCommand adds a command to the index built by buildIndex
+func (s *MemoryStorage) indexCommand(text string) {
+	for _, term := range s.addPostings(text) {
+		i, _ := slices.BinarySearch(s.terms, term)
+		s.terms = slices.Insert(s.terms, i, term)
+	}
+}
+
+// addPostings assigns a document ID to a command and adds its postings,
+// returning the terms that weren't indexed before
+func (s *MemoryStorage) addPostings(text string) []string {
+	id := s.nextID
+	s.nextID++
+	s.ids[text] = id
+
+	var added []string
+	for _, term := range indexTerms(text) {
+		if _, found := s.indexed[term]; !found {
+			added = append(added, term)
 		}
+		s.indexed[term] = append(s.indexed[term], id)
+	}
+	return added
+}
 
-		// Also index command prefixes for partial matching (only first 10 chars)
-		cmdLower := strings.ToLower(cmd.Text)
-		for j := 1; j <= len(cmdLower) && j <= 10; j++ {
-			prefix := cmdLower[:j]
-			if _, exists := s.indexed[prefix]; !exists {
-				s.indexed[prefix] = make([]int, 0)
+// unindexCommand removes a command's postings and document ID
+func (s *MemoryStorage) unindexCommand(text string) {
+	id, found := s.ids[text]
+	if !found {
+		return
+	}
+	delete(s.ids, text)
+
+	for _, term := range indexTerms(text) {
+		postings := s.indexed[term]
+		for i, posting := range postings {
+			if posting == id {
+				postings = append(postings[:i], postings[i+1:]...)
+				break
 			}
-			s.indexed[prefix] = append(s.indexed[prefix], i)
 		}
+		if len(postings) == 0 {
+			delete(s.indexed, term)
+			if i, found := slices.BinarySearch(s.terms, term); found {
+				s.terms = slices.Delete(s.terms, i, i+1)
+			}
+		} else {
+			s.indexed[term] = postings
+		}
+	}
+}
+
+// reposition records the document ID of each command, after they were
+// sorted or some were removed
+func (s *MemoryStorage) reposition() {
+	s.order = slices.Grow(s.order[:0], len(s.commands))
+	for _, cmd := range s.commands {
+		s.order = append(s.order, s.ids[cmd.Text])
 	}
 }
 
+// indexTerms returns the distinct words and short prefixes a command is
+// indexed under
+func indexTerms(text string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	add := func(term string) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+
+	// Index individual words from the command, cleaned of common shell
+	// characters and as typed, since Search matches prefixes of both
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		if cleaned := cleanWord(word); cleaned != "" {
+			add(cleaned)
+			add(word)
+		}
+	}
+
+	// Also index command prefixes for partial matching (only first 10 chars)
+	cmdLower := strings.ToLower(text)
+	for j := 1; j <= len(cmdLower) && j <= 10; j++ {
+		add(cmdLower[:j])
+	}
+
+	return terms
+}
+
 // cleanWord removes common shell characters from words
 func cleanWord(word string) string {
 	// Remove common shell characters
@@ -253,6 +733,8 @@ func cleanWord(word string) string {
 
 // GetStats returns storage statistics
 func (s *MemoryStorage) GetStats() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return map[string]int{
 		"total_commands": len(s.commands),
 		"unique_words":   len(s.indexed),
@@ -0,0 +1,350 @@
+package storage
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+)
+
+// makeCommands returns n commands "git commit -m <i>", the highest position
+// newest
+func makeCommands(n int) []history.Command {
+	commands := make([]history.Command, n)
+	for i := range commands {
+		commands[i] = history.Command{
+			Text:      fmt.Sprintf("git commit -m %d", i),
+			Position:  i,
+			Timestamp: time.Unix(1700000000+int64(i), 0),
+			Count:     1,
+		}
+	}
+	return commands
+}
+
+// TestConcurrentStoreAndSearch hammers Store, Append and Remove while other
+// goroutines search and list, as a background refresh does while the UI
+// reads; run with -race
+func TestConcurrentStoreAndSearch(t *testing.T) {
+	s := NewMemoryStorage()
+	s.Store(makeCommands(100))
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				switch i % 3 {
+				case 0:
+					s.Store(makeCommands(100 + i))
+				case 1:
+					s.Append([]history.Command{{Text: fmt.Sprintf("make build-%d-%d", w, i), Position: 1000 + i, Count: 1}})
+				case 2:
+					s.Remove(fmt.Sprintf("git commit -m %d", i))
+				}
+			}
+		}(w)
+	}
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				for _, cmd := range s.Search("git commit", 50) {
+					if cmd.Text == "" {
+						t.Error("search returned an empty command")
+						return
+					}
+				}
+				s.SearchFuzzy("gcm", 20)
+				s.GetRecent(20)
+				s.GetByFrequency(1, 20)
+				s.GetAll()
+				s.CorrectQuery("gti")
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The stored commands stay sorted newest first
+	all := s.GetAll()
+	for i := 1; i < len(all); i++ {
+		if all[i].Position > all[i-1].Position {
+			t.Fatalf("commands out of order at %d: %d after %d", i, all[i].Position, all[i-1].Position)
+		}
+	}
+}
+
+// TestReturnedSlicesAreCopies checks callers can change results without
+// touching the stored commands
+func TestReturnedSlicesAreCopies(t *testing.T) {
+	s := NewMemoryStorage()
+	s.Store(makeCommands(3))
+
+	recent := s.GetRecent(0)
+	recent[0].Text = "changed"
+	if got := s.GetRecent(1)[0].Text; got != "git commit -m 2" {
+		t.Errorf("GetRecent after changing a result = %q, want git commit -m 2", got)
+	}
+}
+
+// TestGetByTimeRangeInfersTimestamps checks runs without a timestamp are
+// placed at the run before them in their file and flagged, and left out
+// when no run before them has one
+func TestGetByTimeRangeInfersTimestamps(t *testing.T) {
+	at := func(seconds int64) time.Time { return time.Unix(1700000000+seconds, 0) }
+	s := NewMemoryStorage()
+	s.StoreOccurrences([]history.Command{
+		{Text: "a4", Source: "a", Timestamp: at(200)},
+		{Text: "b2", Source: "b", Timestamp: at(150)},
+		{Text: "a3", Source: "a", BadTimestamp: true},
+		{Text: "a2", Source: "a"},
+		{Text: "b1", Source: "b"},
+		{Text: "a1", Source: "a", Timestamp: at(100)},
+	})
+
+	tests := []struct {
+		from, to time.Time
+		want     []string
+	}{
+		{time.Time{}, time.Time{}, []string{"a1", "a2", "a3", "b2", "a4"}},
+		{at(120), time.Time{}, []string{"b2", "a4"}},
+		{time.Time{}, at(120), []string{"a1", "a2", "a3"}},
+	}
+	for _, tt := range tests {
+		runs := s.GetByTimeRange(tt.from, tt.to)
+		var got []string
+		for _, run := range runs {
+			got = append(got, run.Text)
+			inferred := run.Text == "a2" || run.Text == "a3"
+			if run.Inferred != inferred || inferred && !run.Timestamp.Equal(at(100)) {
+				t.Errorf("%s at %v inferred %v, want inferred %v", run.Text, run.Timestamp, run.Inferred, inferred)
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("GetByTimeRange(%v, %v) = %q, want %q", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+// BenchmarkGetRecent reads the 100 newest of 20000 commands, which are
+// kept sorted when stored
+func BenchmarkGetRecent(b *testing.B) {
+	s := NewMemoryStorage()
+	s.Store(makeCommands(20000))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.GetRecent(100)
+	}
+}
+
+// BenchmarkGetRecentSorting copies and sorts 20000 commands before taking
+// the 100 newest, as GetRecent did on every call before
+func BenchmarkGetRecentSorting(b *testing.B) {
+	s := NewMemoryStorage()
+	s.Store(makeCommands(20000))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		commands := slices.Clone(s.commands)
+		sort.SliceStable(commands, func(i, j int) bool {
+			return commands[i].NewerThan(commands[j])
+		})
+		limitCommands(commands, 100)
+	}
+}
+
+// BenchmarkGetAll copies all of 20000 commands
+func BenchmarkGetAll(b *testing.B) {
+	s := NewMemoryStorage()
+	s.Store(makeCommands(20000))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.GetAll()
+	}
+}
+
+// BenchmarkGetByFrequency reads the 100 most used of 20000 commands from
+// the ordering cached on the first call
+func BenchmarkGetByFrequency(b *testing.B) {
+	s := NewMemoryStorage()
+	s.Store(makeCommands(20000))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.GetByFrequency(1, 100)
+	}
+}
+
+// TestMergeResult checks which commands Store and Append report as new or
+// updated
+func TestMergeResult(t *testing.T) {
+	at := func(minute int) time.Time { return time.Date(2026, 10, 13, 14, minute, 0, 0, time.UTC) }
+	s := NewMemoryStorage()
+
+	result := s.Store([]history.Command{
+		{Text: "ls", Timestamp: at(0), Count: 1},
+		{Text: "make", Timestamp: at(1), Count: 2},
+	})
+	if fmt.Sprint(result.Added) != "[ls make]" || result.Updated != 0 {
+		t.Errorf("first Store = %+v, want ls and make added", result)
+	}
+
+	result = s.Store([]history.Command{
+		{Text: "ls", Timestamp: at(0), Count: 1},
+		{Text: "make", Timestamp: at(1), Count: 2},
+	})
+	if len(result.Added) != 0 || result.Updated != 0 {
+		t.Errorf("Store of the same commands = %+v, want no change", result)
+	}
+
+	result = s.Store([]history.Command{
+		{Text: "ls", Timestamp: at(2), Count: 1},
+		{Text: "make", Timestamp: at(1), Count: 3},
+		{Text: "git status", Timestamp: at(3), Count: 1},
+	})
+	if fmt.Sprint(result.Added) != "[git status]" || result.Updated != 2 {
+		t.Errorf("Store with a newer run and a higher count = %+v, want git status added and 2 updated", result)
+	}
+
+	result = s.Append([]history.Command{
+		{Text: "make", Timestamp: at(4), Count: 1},
+		{Text: "go test", Timestamp: at(5), Count: 1},
+		{Text: "make", Timestamp: at(6), Count: 1},
+		{Text: "go test", Timestamp: at(7), Count: 1},
+	})
+	if fmt.Sprint(result.Added) != "[go test]" || result.Updated != 1 {
+		t.Errorf("Append = %+v, want go test added once and make updated once", result)
+	}
+	if got := s.GetRecent(2); got[0].Text != "go test" || got[0].Count != 2 || got[1].Text != "make" || got[1].Count != 5 {
+		t.Errorf("after Append = %+v, want go test run twice and make five times", got)
+	}
+
+	if result := s.Append(nil); len(result.Added) != 0 || result.Updated != 0 {
+		t.Errorf("empty Append = %+v, want no change", result)
+	}
+}
+
+// TestGetByExitStatus checks failed and succeeded commands are listed
+// newest first, leaving out commands without an exit code
+func TestGetByExitStatus(t *testing.T) {
+	s := NewMemoryStorage()
+	s.Store([]history.Command{
+		{Text: "make test", Position: 5, Count: 1, ExitCode: 2, HasExit: true},
+		{Text: "ls", Position: 4, Count: 1},
+		{Text: "git push", Position: 3, Count: 1, ExitCode: 0, HasExit: true},
+		{Text: "false", Position: 2, Count: 1, ExitCode: 1, HasExit: true},
+		{Text: "cd /nope", Position: 1, Count: 1, ExitCode: 0},
+		{Text: "curl example.com", Position: 0, Count: 1, ExitCode: 130, HasExit: true},
+	})
+
+	tests := []struct {
+		failed bool
+		limit  int
+		want   string
+	}{
+		{true, 0, "[make test false curl example.com]"},
+		{true, 2, "[make test false]"},
+		{false, 0, "[git push]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(texts(s.GetByExitStatus(tt.failed, tt.limit))); got != tt.want {
+			t.Errorf("GetByExitStatus(%t, %d) = %s, want %s", tt.failed, tt.limit, got, tt.want)
+		}
+	}
+}
+
+// TestGetByFrequency checks commands are ranked by their real counts, ties
+// newest first, and that a history without repeats lists everything
+func TestGetByFrequency(t *testing.T) {
+	s := NewMemoryStorage()
+	s.Store([]history.Command{
+		{Text: "vim notes.md", Position: 5, Count: 1},
+		{Text: "make test", Position: 4, Count: 7},
+		{Text: "ls", Position: 3, Count: 12},
+		{Text: "git status", Position: 2, Count: 7},
+		{Text: "kubectl get pods", Position: 1, Count: 2},
+		{Text: "git push", Position: 0, Count: 1},
+	})
+
+	tests := []struct {
+		minCount, limit int
+		want            string
+	}{
+		{1, 0, "[ls make test git status kubectl get pods vim notes.md git push]"},
+		{2, 0, "[ls make test git status kubectl get pods]"},
+		{7, 0, "[ls make test git status]"},
+		{2, 2, "[ls make test]"},
+		{20, 0, "[]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(texts(s.GetByFrequency(tt.minCount, tt.limit))); got != tt.want {
+			t.Errorf("GetByFrequency(%d, %d) = %s, want %s", tt.minCount, tt.limit, got, tt.want)
+		}
+	}
+
+	s.Store([]history.Command{
+		{Text: "ls", Position: 1, Count: 1},
+		{Text: "make", Position: 0, Count: 1},
+	})
+	if got := fmt.Sprint(texts(s.GetByFrequency(2, 0))); got != "[ls make]" {
+		t.Errorf("GetByFrequency without repeats = %s, want every command newest first", got)
+	}
+}
+
+// TestSortByCount checks search results ordered by frequency put the most
+// used first, ties newest first, without changing the results passed in
+func TestSortByCount(t *testing.T) {
+	s := NewMemoryStorage()
+	s.Store([]history.Command{
+		{Text: "git log", Position: 5, Count: 3},
+		{Text: "make test", Position: 4, Count: 9},
+		{Text: "git commit", Position: 3, Count: 7},
+		{Text: "git add", Position: 2, Count: 3},
+		{Text: "git push", Position: 1, Count: 1},
+		{Text: "git status", Position: 0, Count: 12},
+	})
+
+	results := s.Search("git", 0)
+	before := fmt.Sprint(texts(results))
+	if got := fmt.Sprint(texts(SortByCount(results))); got != "[git status git commit git log git add git push]" {
+		t.Errorf("SortByCount(search git) = %s, want most used first", got)
+	}
+	if after := fmt.Sprint(texts(results)); after != before {
+		t.Errorf("SortByCount changed its input from %s to %s", before, after)
+	}
+}
+
+// TestWithTotal checks the recent and frequency lists stop at the limit
+// while reporting how long they are in all
+func TestWithTotal(t *testing.T) {
+	s := NewMemoryStorage()
+	s.Store([]history.Command{
+		{Text: "ls", Position: 3, Count: 1},
+		{Text: "make test", Position: 2, Count: 4},
+		{Text: "git status", Position: 1, Count: 2},
+		{Text: "git push", Position: 0, Count: 1},
+	})
+
+	tests := []struct {
+		name  string
+		list  func(limit int) ([]history.Command, int)
+		limit int
+		want  string
+		total int
+	}{
+		{"recent", s.GetRecentWithTotal, 2, "[ls make test]", 4},
+		{"recent", s.GetRecentWithTotal, 0, "[ls make test git status git push]", 4},
+		{"frequency", func(limit int) ([]history.Command, int) { return s.GetByFrequencyWithTotal(2, limit) }, 1, "[make test]", 2},
+		{"frequency", func(limit int) ([]history.Command, int) { return s.GetByFrequencyWithTotal(1, limit) }, 3, "[make test git status ls]", 4},
+	}
+	for _, tt := range tests {
+		commands, total := tt.list(tt.limit)
+		if got := fmt.Sprint(texts(commands)); got != tt.want || total != tt.total {
+			t.Errorf("%s with limit %d = %s of %d, want %s of %d", tt.name, tt.limit, got, total, tt.want, tt.total)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+	"gopkg.in/yaml.v3"
+)
+
+// pinsFile is the layout of the file pinned commands are kept in
+type pinsFile struct {
+	Pinned []string `yaml:"pinned"`
+}
+
+// Pin marks a command as pinned, after the ones pinned before it. Pinning
+// a pinned command does nothing.
+func (s *MemoryStorage) Pin(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !slices.Contains(s.pinned, text) {
+		s.pinned = append(s.pinned, text)
+	}
+}
+
+// Unpin removes the pin of a command
+func (s *MemoryStorage) Unpin(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pinned = slices.DeleteFunc(s.pinned, func(pinned string) bool { return pinned == text })
+}
+
+// IsPinned reports whether a command is pinned
+func (s *MemoryStorage) IsPinned(text string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Contains(s.pinned, text)
+}
+
+// GetPinned returns the pinned commands in the order they were pinned. A
+// pinned command no longer in the history is returned with only its text.
+func (s *MemoryStorage) GetPinned() []history.Command {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored := make(map[string]history.Command, len(s.pinned))
+	for _, text := range s.pinned {
+		stored[text] = history.Command{Text: text}
+	}
+	for _, cmd := range s.commands {
+		if _, ok := stored[cmd.Text]; ok {
+			stored[cmd.Text] = cmd
+		}
+	}
+
+	pinned := make([]history.Command, len(s.pinned))
+	for i, text := range s.pinned {
+		pinned[i] = stored[text]
+	}
+	return pinned
+}
+
+// LoadPins reads the texts of pinned commands from a file, returning none
+// if it doesn't exist
+func LoadPins(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var file pinsFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	return file.Pinned, nil
+}
+
+// SavePins writes the texts of pinned commands to a file, creating its
+// directory if needed
+func SavePins(path string, texts []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(pinsFile{Pinned: texts})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,117 @@
+package storage
+
+import "strings"
+
+// Query is a parsed search query: it matches a command containing every
+// word of at least one group and none of the excluded words. Groups are
+// separated by "|", so "rsync | scp" matches commands with either word.
+type Query struct {
+	Groups  [][]string
+	Exclude []string // Words after a "-", as in "git push -force"
+}
+
+// ParseQuery parses a search query. Words are lowercased, and "|" may stand
+// alone or join words directly ("rsync|scp"). Words starting with "-"
+// exclude commands containing them, in any group; a "-" with nothing after
+// it is ignored. Empty groups are dropped.
+func ParseQuery(query string) Query {
+	var q Query
+	var group []string
+	endGroup := func() {
+		if len(group) > 0 {
+			q.Groups = append(q.Groups, group)
+		}
+		group = nil
+	}
+
+	for _, field := range strings.Fields(strings.ToLower(query)) {
+		for i, word := range strings.Split(field, "|") {
+			if i > 0 {
+				endGroup()
+			}
+			if excluded, ok := strings.CutPrefix(word, "-"); ok {
+				if excluded = strings.TrimLeft(excluded, "-"); excluded != "" {
+					q.Exclude = append(q.Exclude, excluded)
+				}
+			} else if word != "" {
+				group = append(group, word)
+			}
+		}
+	}
+	endGroup()
+
+	return q
+}
+
+// AnyWord returns the query with every word in a group of its own, so a
+// command matches if it contains any of the words
+func (q Query) AnyWord() Query {
+	any := Query{Exclude: q.Exclude}
+	for _, group := range q.Groups {
+		for _, word := range group {
+			any.Groups = append(any.Groups, []string{word})
+		}
+	}
+	return any
+}
+
+// String formats the query so that ParseQuery returns it unchanged
+func (q Query) String() string {
+	groups := make([]string, len(q.Groups))
+	for i, group := range q.Groups {
+		groups[i] = strings.Join(group, " ")
+	}
+	query := strings.Join(groups, " | ")
+	for _, word := range q.Exclude {
+		query = strings.TrimSpace(query + " -" + word)
+	}
+	return query
+}
+
+// Empty reports whether the query has no words
+func (q Query) Empty() bool {
+	return len(q.Groups) == 0 && len(q.Exclude) == 0
+}
+
+// excludes reports whether a command contains an excluded word. Leading
+// dashes of the command's words are ignored, so "-force" excludes commands
+// with --force-with-lease.
+func (q Query) excludes(cmdWords []string) bool {
+	for _, word := range q.Exclude {
+		if commandContainsWord(cmdWords, word) {
+			return true
+		}
+		for _, cmdWord := range cmdWords {
+			if strings.HasPrefix(strings.TrimLeft(cmdWord, "-"), word) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matches reports whether the words of a command satisfy the query
+func (q Query) matches(cmdWords []string) bool {
+	if q.excludes(cmdWords) {
+		return false
+	}
+	if len(q.Groups) == 0 {
+		return true
+	}
+	for _, group := range q.Groups {
+		if groupMatches(cmdWords, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupMatches reports whether the command contains every word of a group
+func groupMatches(cmdWords []string, group []string) bool {
+	for _, word := range group {
+		if !commandContainsWord(cmdWords, word) {
+			return false
+		}
+	}
+	return true
+}
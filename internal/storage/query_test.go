@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  Query
+	}{
+		{"", Query{}},
+		{"Git Push", Query{Groups: [][]string{{"git", "push"}}}},
+		{"rsync | scp", Query{Groups: [][]string{{"rsync"}, {"scp"}}}},
+		{"rsync|scp", Query{Groups: [][]string{{"rsync"}, {"scp"}}}},
+		{"git push | git pull", Query{Groups: [][]string{{"git", "push"}, {"git", "pull"}}}},
+		{"| rsync || scp |", Query{Groups: [][]string{{"rsync"}, {"scp"}}}},
+		// A negation belongs to no group, so it excludes from every one
+		{"rsync | scp -dry", Query{Groups: [][]string{{"rsync"}, {"scp"}}, Exclude: []string{"dry"}}},
+		{"-dry|rsync", Query{Groups: [][]string{{"rsync"}}, Exclude: []string{"dry"}}},
+		{"git push --force", Query{Groups: [][]string{{"git", "push"}}, Exclude: []string{"force"}}},
+		{"git - push", Query{Groups: [][]string{{"git", "push"}}}},
+	}
+	for _, tt := range tests {
+		if got := ParseQuery(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+// TestQueryString checks String and AnyWord give queries that parse back
+// to the same groups
+func TestQueryString(t *testing.T) {
+	q := ParseQuery("git push | scp -force")
+	if got := q.String(); got != "git push | scp -force" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := ParseQuery(q.String()); !reflect.DeepEqual(got, q) {
+		t.Errorf("ParseQuery(String()) = %+v, want %+v", got, q)
+	}
+
+	any := q.AnyWord()
+	want := Query{Groups: [][]string{{"git"}, {"push"}, {"scp"}}, Exclude: []string{"force"}}
+	if !reflect.DeepEqual(any, want) || any.String() != "git | push | scp -force" {
+		t.Errorf("AnyWord() = %+v formatted %q, want %+v", any, any.String(), want)
+	}
+}
+
+// TestSearchOr checks Search matches any "|" group, with negations applied
+// to all of them
+func TestSearchOr(t *testing.T) {
+	s := NewMemoryStorage()
+	s.Store([]history.Command{
+		{Text: "rsync -av src/ host:dst/", Position: 3, Count: 1},
+		{Text: "scp file.txt host:", Position: 2, Count: 1},
+		{Text: "rsync --dry-run src/ host:dst/", Position: 1, Count: 1},
+		{Text: "cp file.txt backup/", Position: 0, Count: 1},
+	})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"rsync scp", nil},
+		{"rsync | scp", []string{"rsync -av src/ host:dst/", "scp file.txt host:", "rsync --dry-run src/ host:dst/"}},
+		{"rsync | scp -dry", []string{"rsync -av src/ host:dst/", "scp file.txt host:"}},
+		{"scp file | cp backup", []string{"scp file.txt host:", "cp file.txt backup/"}},
+	}
+	for _, tt := range tests {
+		if got := texts(s.Search(tt.query, 0)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+// TestSearchExclude checks commands must have every positive word and none
+// of the negative ones
+func TestSearchExclude(t *testing.T) {
+	s := NewMemoryStorage()
+	s.Store([]history.Command{
+		{Text: "git push --force-with-lease", Position: 3, Count: 1},
+		{Text: "git push origin main", Position: 2, Count: 1},
+		{Text: "git push -f", Position: 1, Count: 1},
+		{Text: "git pull", Position: 0, Count: 1},
+	})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"git push", []string{"git push --force-with-lease", "git push origin main", "git push -f"}},
+		{"git push -force", []string{"git push origin main", "git push -f"}},
+		{"git push -force -f", []string{"git push origin main"}},
+		{"-force git", []string{"git push origin main", "git push -f", "git pull"}},
+		{"git pull -", []string{"git pull"}},
+		{"git pull --", []string{"git pull"}},
+		{"-git", nil},
+	}
+	for _, tt := range tests {
+		if got := texts(s.Search(tt.query, 0)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
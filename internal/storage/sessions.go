@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+)
+
+// Session is a run of commands with no pause longer than the session gap
+type Session struct {
+	Start    time.Time
+	End      time.Time
+	Commands []history.Command // Every run in order, oldest first
+}
+
+// GroupSessions splits timestamped occurrences into sessions wherever
+// consecutive commands are more than gap apart, returning the newest
+// session first. Commands without a timestamp are ignored.
+func GroupSessions(occurrences []history.Command, gap time.Duration) []Session {
+	var timed []history.Command
+	for _, cmd := range occurrences {
+		if !cmd.Timestamp.IsZero() {
+			timed = append(timed, cmd)
+		}
+	}
+	sort.SliceStable(timed, func(i, j int) bool {
+		return timed[i].Timestamp.Before(timed[j].Timestamp)
+	})
+
+	var sessions []Session
+	for _, cmd := range timed {
+		if n := len(sessions); n > 0 && cmd.Timestamp.Sub(sessions[n-1].End) <= gap {
+			sessions[n-1].End = cmd.Timestamp
+			sessions[n-1].Commands = append(sessions[n-1].Commands, cmd)
+			continue
+		}
+		sessions = append(sessions, Session{
+			Start:    cmd.Timestamp,
+			End:      cmd.Timestamp,
+			Commands: []history.Command{cmd},
+		})
+	}
+
+	// Newest session first
+	for i, j := 0, len(sessions)-1; i < j; i, j = i+1, j-1 {
+		sessions[i], sessions[j] = sessions[j], sessions[i]
+	}
+	return sessions
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+)
+
+// Stats summarizes command usage
+type Stats struct {
+	Total         int          `json:"total"`  // Commands run, counting repeats
+	Unique        int          `json:"unique"` // Distinct commands
+	TopCommands   []CountEntry `json:"top_commands"`
+	TopFirstWords []CountEntry `json:"top_first_words"`
+	Hours         [24]int      `json:"hours"`          // Commands by local hour of their last run
+	WithExitCode  int          `json:"with_exit_code"` // Commands with a recorded exit code
+	Failed        int          `json:"failed"`         // Commands whose last run exited non-zero
+	First         *time.Time   `json:"first,omitempty"`
+	Last          *time.Time   `json:"last,omitempty"`
+}
+
+// CountEntry is a text with its number of occurrences
+type CountEntry struct {
+	Text  string `json:"text"`
+	Count int    `json:"count"`
+}
+
+// FailureRate returns the fraction of commands with an exit code that
+// failed, or 0 if no exit codes were recorded
+func (s Stats) FailureRate() float64 {
+	if s.WithExitCode == 0 {
+		return 0
+	}
+	return float64(s.Failed) / float64(s.WithExitCode)
+}
+
+// Stats returns usage statistics for the stored commands, listing at most
+// top entries in each ranking
+func (s *MemoryStorage) Stats(top int) Stats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return ComputeStats(s.commands, top)
+}
+
+// ComputeStats returns usage statistics for commands, listing at most top
+// entries in each ranking (0 means unlimited)
+func ComputeStats(commands []history.Command, top int) Stats {
+	var stats Stats
+	firstWords := make(map[string]int)
+
+	for _, cmd := range commands {
+		count := cmd.Count
+		if count < 1 {
+			count = 1
+		}
+		stats.Total += count
+		stats.Unique++
+		stats.TopCommands = append(stats.TopCommands, CountEntry{Text: cmd.Text, Count: count})
+
+		if fields := strings.Fields(cmd.Text); len(fields) > 0 {
+			firstWords[fields[0]] += count
+		}
+
+		if cmd.HasExit {
+			stats.WithExitCode++
+			if cmd.ExitCode != 0 {
+				stats.Failed++
+			}
+		}
+
+		if !cmd.Timestamp.IsZero() {
+			t := cmd.Timestamp
+			stats.Hours[t.Local().Hour()]++
+			if stats.First == nil || t.Before(*stats.First) {
+				stats.First = &t
+			}
+			if stats.Last == nil || t.After(*stats.Last) {
+				stats.Last = &t
+			}
+		}
+	}
+
+	for word, count := range firstWords {
+		stats.TopFirstWords = append(stats.TopFirstWords, CountEntry{Text: word, Count: count})
+	}
+
+	stats.TopCommands = topEntries(stats.TopCommands, top)
+	stats.TopFirstWords = topEntries(stats.TopFirstWords, top)
+	return stats
+}
+
+// topEntries sorts entries by count (ties alphabetically) and keeps the
+// first limit of them (0 means unlimited)
+func topEntries(entries []CountEntry, limit int) []CountEntry {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].Text < entries[j].Text
+	})
+
+	if limit > 0 && limit < len(entries) {
+		entries = entries[:limit]
+	}
+	return entries
+}
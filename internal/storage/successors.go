@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"sort"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+)
+
+// Successors returns the commands that most often ran immediately after
+// text, most frequent first. Only commands that followed it at least
+// minSupport times are returned, at most limit of them (0 means unlimited).
+// Occurrences are ordered per source file, so commands from different
+// shells' histories are never treated as adjacent.
+func Successors(occurrences []history.Command, text string, minSupport, limit int) []CountEntry {
+	bySource := make(map[string][]history.Command)
+	for _, cmd := range occurrences {
+		bySource[cmd.Source] = append(bySource[cmd.Source], cmd)
+	}
+
+	counts := make(map[string]int)
+	for _, commands := range bySource {
+		sort.SliceStable(commands, func(i, j int) bool {
+			return commands[i].Position < commands[j].Position
+		})
+		for i := 0; i+1 < len(commands); i++ {
+			next := commands[i+1].Text
+			if commands[i].Text == text && next != text {
+				counts[next]++
+			}
+		}
+	}
+
+	var entries []CountEntry
+	for next, count := range counts {
+		if count >= minSupport {
+			entries = append(entries, CountEntry{Text: next, Count: count})
+		}
+	}
+	return topEntries(entries, limit)
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+)
+
+// runs returns the commands run in order in source, newest first as
+// ReadHistory returns them
+func runs(source string, texts ...string) []history.Command {
+	commands := make([]history.Command, len(texts))
+	for i, text := range texts {
+		commands[len(texts)-1-i] = history.Command{Text: text, Position: i, Source: source, Count: 1}
+	}
+	return commands
+}
+
+// successorOccurrences is a session where make test follows git pull four
+// times, make build three times across two files and go vet once. The go
+// vet in the third file would follow git pull if files were merged.
+func successorOccurrences() []history.Command {
+	var occurrences []history.Command
+	occurrences = append(occurrences, runs("zsh",
+		"git pull", "make test",
+		"git pull", "make test",
+		"git pull", "make test",
+		"git pull", "make build",
+		"git pull", "git pull", "make test",
+		"git pull", "go vet",
+	)...)
+	occurrences = append(occurrences, runs("bash", "git pull", "make build", "git pull", "make build", "deploy")...)
+	occurrences = append(occurrences, runs("fish", "go vet")...)
+	return occurrences
+}
+
+func TestSuccessors(t *testing.T) {
+	occurrences := successorOccurrences()
+	tests := []struct {
+		text       string
+		minSupport int
+		limit      int
+		want       string
+	}{
+		{"git pull", 2, 0, "[{make test 4} {make build 3}]"},
+		{"git pull", 1, 0, "[{make test 4} {make build 3} {go vet 1}]"},
+		{"git pull", 1, 1, "[{make test 4}]"},
+		{"git pull", 5, 0, "[]"},
+		{"make test", 1, 0, "[{git pull 4}]"},
+		{"make build", 2, 0, "[{git pull 2}]"},
+		{"deploy", 1, 0, "[]"},
+	}
+	for _, tt := range tests {
+		got := fmt.Sprint(Successors(occurrences, tt.text, tt.minSupport, tt.limit))
+		if got != tt.want {
+			t.Errorf("Successors(%q, min %d, limit %d) = %s, want %s", tt.text, tt.minSupport, tt.limit, got, tt.want)
+		}
+	}
+}
+
+// TestGetSuccessors checks the storage ignores successors seen only once
+func TestGetSuccessors(t *testing.T) {
+	s := NewMemoryStorage()
+	s.StoreOccurrences(successorOccurrences())
+
+	if got := fmt.Sprint(s.GetSuccessors("git pull", 3)); got != "[{make test 4} {make build 3}]" {
+		t.Errorf("GetSuccessors(git pull) = %s, want make test then make build", got)
+	}
+	if got := fmt.Sprint(s.GetSuccessors("git pull", 1)); got != "[{make test 4}]" {
+		t.Errorf("GetSuccessors(git pull, limit 1) = %s, want make test", got)
+	}
+	if got := s.GetSuccessors("go vet", 3); len(got) != 0 {
+		t.Errorf("GetSuccessors(go vet) = %v, want none", got)
+	}
+}
@@ -0,0 +1,117 @@
+package storage
+
+import "strings"
+
+// typoAlphabet holds the characters tried when inserting or substituting
+// one character of a query word
+const typoAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789-_./"
+
+// minTypoLength is the shortest word corrected; shorter words have too
+// many neighbours for a correction to be meaningful
+const minTypoLength = 3
+
+// CorrectQuery returns the query with each word that isn't indexed
+// replaced by the most used indexed word at edit distance 1 (one character
+// missing, extra, changed, or two adjacent characters swapped). Words with
+// no such neighbour are kept. Only the edits of each word are looked up, so
+// the cost doesn't depend on the size of the vocabulary.
+func (s *MemoryStorage) CorrectQuery(query string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// The index also holds the first characters of each command, which
+	// aren't words to correct to, so candidates are counted only in the
+	// commands that have them as a whole word
+	var texts map[int]string
+	wordCount := func(term string) int {
+		ids := s.indexed[term]
+		if len(ids) == 0 {
+			return 0
+		}
+		if texts == nil {
+			texts = make(map[int]string, len(s.ids))
+			for text, id := range s.ids {
+				texts[id] = text
+			}
+		}
+		count := 0
+		for _, id := range ids {
+			if hasWord(texts[id], term) {
+				count++
+			}
+		}
+		return count
+	}
+
+	words := strings.Fields(strings.ToLower(query))
+	changed := false
+	for i, word := range words {
+		// Excluded words are left alone; correcting one would change which
+		// commands are hidden
+		if _, known := s.indexed[word]; known || len(word) < minTypoLength || strings.HasPrefix(word, "-") {
+			continue
+		}
+
+		best, bestCount := "", 0
+		for _, candidate := range singleEdits(word) {
+			if count := wordCount(candidate); count > bestCount {
+				best, bestCount = candidate, count
+			}
+		}
+		if best != "" {
+			words[i] = best
+			changed = true
+		}
+	}
+
+	if !changed {
+		return query
+	}
+	return strings.Join(words, " ")
+}
+
+// hasWord reports whether text has word, as typed or cleaned of shell
+// characters like the index has it
+func hasWord(text, word string) bool {
+	for _, field := range strings.Fields(strings.ToLower(text)) {
+		if field == word || cleanWord(field) == word {
+			return true
+		}
+	}
+	return false
+}
+
+// singleEdits returns every string at edit distance 1 from word, counting a
+// swap of two adjacent characters as one edit
+func singleEdits(word string) []string {
+	runes := []rune(word)
+	var edits []string
+
+	for i := range runes {
+		// Deletion
+		edits = append(edits, string(runes[:i])+string(runes[i+1:]))
+
+		// Transposition
+		if i+1 < len(runes) && runes[i] != runes[i+1] {
+			swapped := []rune(word)
+			swapped[i], swapped[i+1] = swapped[i+1], swapped[i]
+			edits = append(edits, string(swapped))
+		}
+
+		// Substitution
+		for _, c := range typoAlphabet {
+			if c != runes[i] {
+				edits = append(edits, string(runes[:i])+string(c)+string(runes[i+1:]))
+			}
+		}
+	}
+
+	// Insertion
+	for i := 0; i <= len(runes); i++ {
+		for _, c := range typoAlphabet {
+			edits = append(edits, string(runes[:i])+string(c)+string(runes[i:]))
+		}
+	}
+
+	return edits
+}
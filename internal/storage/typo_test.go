@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+)
+
+func TestCorrectQuery(t *testing.T) {
+	s := NewMemoryStorage()
+	s.Store([]history.Command{
+		{Text: "docker compose up -d", Position: 3, Count: 1},
+		{Text: "docker ps", Position: 2, Count: 1},
+		{Text: "dockerd --debug", Position: 1, Count: 1},
+		{Text: "kubectl get pods", Position: 0, Count: 1},
+	})
+
+	tests := []struct {
+		name, query, want string
+	}{
+		{"transposed", "dokcer ps", "docker ps"},
+		{"missing", "dcker", "docker"},
+		{"extra", "dockker", "docker"},
+		{"changed", "kubectl get pids", "kubectl get pods"},
+		{"most used neighbour", "dockre", "docker"},
+		{"uppercase", "Dokcer", "docker"},
+		{"known words", "docker compose", "docker compose"},
+		{"too short", "pz", "pz"},
+		{"two edits", "dkocre", "dkocre"},
+		{"negated", "docker -cmopose", "docker -cmopose"},
+	}
+	for _, tt := range tests {
+		if got := s.CorrectQuery(tt.query); got != tt.want {
+			t.Errorf("%s: CorrectQuery(%q) = %q, want %q", tt.name, tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package suggest
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+	"github.com/4ndew/terminal-history-navigator/internal/shell"
+)
+
+// Thresholds below which a cluster is considered noise
+const (
+	MinUses     = 5 // Fewest runs of all the cluster's commands together
+	MinVariants = 3 // Fewest distinct commands in a cluster
+	maxSlots    = 3 // Most varying arguments in a template
+	maxExamples = 3
+)
+
+// Suggestion is a template inferred from commands that differ only in a
+// few arguments
+type Suggestion struct {
+	Command  string   // Template with {{name}} slots, e.g. kubectl -n {{n}} logs {{arg}}
+	Name     string   // Program and literal subcommand, e.g. kubectl logs
+	Program  string   // First word of the commands
+	Uses     int      // Runs of all the matching commands together
+	Variants int      // Distinct matching commands
+	Examples []string // Most used matching commands
+}
+
+// subcommandPattern matches words that may be subcommands rather than
+// file names or values
+var subcommandPattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// member is a command of a cluster, split into words
+type member struct {
+	words []string
+	count int
+	text  string
+}
+
+// Templates clusters commands by skeleton and returns suggested templates,
+// most used first, at most limit of them (0 means unlimited).
+//
+// Commands share a skeleton if they have the same number of words and the
+// same program, flags, control operators and subcommand (the first
+// argument that isn't a flag's value). Other arguments that vary within a
+// cluster become slots.
+func Templates(commands []history.Command, limit int) []Suggestion {
+	clusters := make(map[string][]member)
+	var keys []string
+	for _, cmd := range commands {
+		words := shell.Split(cmd.Text)
+		if len(words) < 2 {
+			continue
+		}
+		key := skeleton(words)
+		if _, ok := clusters[key]; !ok {
+			keys = append(keys, key)
+		}
+		clusters[key] = append(clusters[key], member{words: words, count: max(cmd.Count, 1), text: cmd.Text})
+	}
+
+	var suggestions []Suggestion
+	for _, key := range keys {
+		if suggestion, ok := suggestTemplate(clusters[key]); ok {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Uses > suggestions[j].Uses
+	})
+	if limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// skeleton returns the words that must match for commands to share a
+// template, with "_" for arguments that may vary
+func skeleton(words []string) string {
+	parts := make([]string, len(words))
+	for i := range words {
+		if literal(words, i) {
+			parts[i] = words[i]
+		} else {
+			parts[i] = "_"
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// literal reports whether the word at i is part of the skeleton: the
+// program, a flag, an operator or the subcommand
+func literal(words []string, i int) bool {
+	word := words[i]
+	if i == 0 || shell.IsOperator(word) || isFlag(word) {
+		return true
+	}
+	return i == subcommandIndex(words)
+}
+
+// isFlag reports whether word is an option like -n or --all
+func isFlag(word string) bool {
+	return len(word) > 1 && strings.HasPrefix(word, "-") && !isNumber(word)
+}
+
+// flagValue reports whether the word at i follows a flag that takes it as
+// its value, like the namespace in -n prod. A flag followed by another
+// flag, or written with =, takes no separate value.
+func flagValue(words []string, i int) bool {
+	if i == 0 {
+		return false
+	}
+	prev := words[i-1]
+	return isFlag(prev) && !strings.Contains(prev, "=") && !isFlag(words[i])
+}
+
+// subcommandIndex returns the index of the subcommand, like logs in
+// kubectl -n prod logs api: the first argument that is neither a flag nor
+// a flag's value, if it is a plain lowercase word. It returns -1 if there
+// is none.
+func subcommandIndex(words []string) int {
+	for i := 1; i < len(words); i++ {
+		if shell.IsOperator(words[i]) {
+			return -1
+		}
+		if !isFlag(words[i]) && !flagValue(words, i) {
+			if subcommandPattern.MatchString(words[i]) {
+				return i
+			}
+			return -1
+		}
+	}
+	return -1
+}
+
+// suggestTemplate turns a cluster into a template if it is used often
+// enough and varies in at least one argument
+func suggestTemplate(members []member) (Suggestion, bool) {
+	distinct := make(map[string]bool)
+	uses := 0
+	for _, m := range members {
+		distinct[m.text] = true
+		uses += m.count
+	}
+	if uses < MinUses || len(distinct) < MinVariants {
+		return Suggestion{}, false
+	}
+
+	words := members[0].words
+	var slots []int
+	for i := range words {
+		for _, m := range members[1:] {
+			if m.words[i] != words[i] {
+				slots = append(slots, i)
+				break
+			}
+		}
+	}
+	// Too many varying arguments means the commands only look alike
+	if len(slots) == 0 || len(slots) > maxSlots || len(slots)*2 > len(words) {
+		return Suggestion{}, false
+	}
+
+	parts := make([]string, len(words))
+	for i, word := range words {
+		parts[i] = shell.Quote(word)
+		if shell.IsOperator(word) {
+			parts[i] = word
+		}
+	}
+	used := make(map[string]int)
+	for _, i := range slots {
+		name := slotName(members, i)
+		used[name]++
+		if used[name] > 1 {
+			name += strconv.Itoa(used[name])
+		}
+		parts[i] = "{{" + name + "}}"
+	}
+
+	name := words[0]
+	if sub := subcommandIndex(words); sub > 0 && !isSlot(slots, sub) {
+		name += " " + words[sub]
+	}
+
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].count > members[j].count
+	})
+	var examples []string
+	for _, m := range members {
+		if len(examples) == maxExamples {
+			break
+		}
+		examples = append(examples, m.text)
+	}
+
+	return Suggestion{
+		Command:  strings.Join(parts, " "),
+		Name:     name,
+		Program:  words[0],
+		Uses:     uses,
+		Variants: len(distinct),
+		Examples: examples,
+	}, true
+}
+
+// isSlot reports whether position i varies
+func isSlot(slots []int, i int) bool {
+	for _, slot := range slots {
+		if slot == i {
+			return true
+		}
+	}
+	return false
+}
+
+// slotName names a varying argument after the flag it is the value of, or
+// after what its values look like
+func slotName(members []member, i int) string {
+	words := members[0].words
+	if flagValue(words, i) {
+		if name := strings.TrimLeft(words[i-1], "-"); name != "" {
+			return name
+		}
+	}
+
+	numbers, paths := true, true
+	for _, m := range members {
+		numbers = numbers && isNumber(m.words[i])
+		paths = paths && strings.Contains(m.words[i], "/")
+	}
+	switch {
+	case numbers:
+		return "number"
+	case paths:
+		return "path"
+	}
+	return "arg"
+}
+
+// isNumber reports whether word is an integer, possibly negative
+func isNumber(word string) bool {
+	_, err := strconv.Atoi(word)
+	return err == nil
+}
+
+// Describe summarizes how often a suggestion's commands were used
+func (s Suggestion) Describe() string {
+	return fmt.Sprintf("Suggested from %d uses of %d similar commands", s.Uses, s.Variants)
+}
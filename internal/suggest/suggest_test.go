@@ -0,0 +1,82 @@
+package suggest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+)
+
+// corpus returns commands run count times each, given as text and count
+// pairs
+func corpus(pairs ...any) []history.Command {
+	var commands []history.Command
+	for i := 0; i < len(pairs); i += 2 {
+		commands = append(commands, history.Command{Text: pairs[i].(string), Count: pairs[i+1].(int)})
+	}
+	return commands
+}
+
+// noise is commands that look alike but are too rare or too uniform to be
+// worth a template
+var noise = corpus(
+	// Used often, but only two variants
+	"docker run alpine", 10,
+	"docker run ubuntu", 10,
+	// Three variants, but four uses together
+	"cd /srv/a", 1,
+	"cd /srv/b", 1,
+	"cd /srv/c", 2,
+	// Every argument varies
+	"cp a.txt b.txt", 3,
+	"cp c.txt d.txt", 3,
+	"cp e.txt f.txt", 3,
+	// Single words and different subcommands don't cluster
+	"ls", 20,
+	"git status", 8,
+	"git log", 8,
+	"git diff", 8,
+)
+
+func TestTemplates(t *testing.T) {
+	commands := append(corpus(
+		"kubectl -n prod logs api", 3,
+		"git checkout feature/login", 3,
+		"sleep 5", 1,
+		"kubectl -n staging logs web", 2,
+		"git checkout feature/signup", 2,
+		"sleep 10", 2,
+		"kubectl -n prod logs worker", 1,
+		"git checkout fix/crash", 2,
+		"sleep 30", 2,
+	), noise...)
+
+	suggestions := Templates(commands, 0)
+	var got []string
+	for _, s := range suggestions {
+		got = append(got, fmt.Sprintf("%s (%s) %d/%d", s.Command, s.Name, s.Uses, s.Variants))
+	}
+	want := []string{
+		"git checkout {{path}} (git checkout) 7/3",
+		"kubectl -n {{n}} logs {{arg}} (kubectl logs) 6/3",
+		"sleep {{number}} (sleep) 5/3",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("Templates() = %q, want %q", got, want)
+	}
+
+	if examples := fmt.Sprint(suggestions[1].Examples); examples != "[kubectl -n prod logs api kubectl -n staging logs web kubectl -n prod logs worker]" {
+		t.Errorf("examples = %s, want the most used first", examples)
+	}
+	if limited := Templates(commands, 2); len(limited) != 2 || limited[1].Name != "kubectl logs" {
+		t.Errorf("Templates(limit 2) = %+v, want the two most used", limited)
+	}
+}
+
+// TestTemplatesIgnoresNoise checks rare or barely varying commands give no
+// suggestions
+func TestTemplatesIgnoresNoise(t *testing.T) {
+	if suggestions := Templates(noise, 0); len(suggestions) != 0 {
+		t.Errorf("Templates(noise) = %+v, want none", suggestions)
+	}
+}
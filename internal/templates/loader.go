@@ -1,6 +1,7 @@
 package templates
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
@@ -58,15 +59,75 @@ func (l *Loader) Load() ([]Template, error) {
 		return nil, err
 	}
 
-	// Sort templates by category, then by name
-	sort.Slice(templateData.Templates, func(i, j int) bool {
-		if templateData.Templates[i].Category != templateData.Templates[j].Category {
-			return templateData.Templates[i].Category < templateData.Templates[j].Category
+	Sort(templateData.Templates)
+	return templateData.Templates, nil
+}
+
+// Sort sorts templates by category, then by name
+func Sort(templates []Template) {
+	sort.Slice(templates, func(i, j int) bool {
+		if templates[i].Category != templates[j].Category {
+			return templates[i].Category < templates[j].Category
 		}
-		return templateData.Templates[i].Name < templateData.Templates[j].Name
+		return templates[i].Name < templates[j].Name
 	})
+}
 
-	return templateData.Templates, nil
+// Add appends a template to the templates file, creating the file with the
+// defaults first if it doesn't exist. Comments and existing entries are
+// kept as they are.
+func (l *Loader) Add(template Template) error {
+	if _, err := os.Stat(l.templatePath); os.IsNotExist(err) {
+		if err := l.createDefaultTemplates(); err != nil {
+			return err
+		}
+	}
+
+	data, err := os.ReadFile(l.templatePath)
+	if err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s is not a YAML mapping", l.templatePath)
+	}
+
+	var entry yaml.Node
+	if err := entry.Encode(template); err != nil {
+		return err
+	}
+
+	var list *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "templates" {
+			list = root.Content[i+1]
+		}
+	}
+	switch {
+	case list == nil:
+		root.Content = append(root.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "templates"},
+			&yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{&entry}})
+	case list.Kind == yaml.SequenceNode:
+		list.Content = append(list.Content, &entry)
+	default:
+		// An empty "templates:" is a null scalar
+		*list = yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{&entry}}
+	}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(l.templatePath, out, 0644)
 }
 
 // createDefaultTemplates creates a default templates file
@@ -0,0 +1,87 @@
+package timefmt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Default is the format used when none is configured
+const Default = "short"
+
+// Presets maps the named formats accepted in place of a Go layout
+var Presets = map[string]string{
+	"iso":    "2006-01-02 15:04",
+	"short":  "Jan 2 15:04",
+	"recent": "", // Day name within the last week, date otherwise
+}
+
+// recentLayout and olderLayout are used by the "recent" preset
+const (
+	recentLayout = "Mon 15:04"
+	olderLayout  = "Jan 2 2006"
+)
+
+// Formatter formats command timestamps for display
+type Formatter struct {
+	layout string // Go layout, "" for the "recent" preset
+	width  int    // Widest rendered timestamp in terminal columns
+}
+
+// New returns a formatter for a preset name or a Go layout string such as
+// "02/01 15:04". Layouts without any date or time element are rejected.
+func New(spec string) (Formatter, error) {
+	layout, preset := Presets[spec]
+	if !preset {
+		reference := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+		if spec == "" || reference.Format(spec) == spec {
+			return Formatter{}, fmt.Errorf("%q has no date or time elements", spec)
+		}
+		layout = spec
+	}
+
+	f := Formatter{layout: layout}
+	f.width = f.measure()
+	return f, nil
+}
+
+// Format formats t in local time. now decides which entries count as
+// recent for the "recent" preset.
+func (f Formatter) Format(t, now time.Time) string {
+	t = t.Local()
+	if f.layout != "" {
+		return t.Format(f.layout)
+	}
+	if age := now.Sub(t); age >= 0 && age < 6*24*time.Hour {
+		return t.Format(recentLayout)
+	}
+	return t.Format(olderLayout)
+}
+
+// Width returns the widest a formatted timestamp can be, in terminal
+// columns, so columns can be sized before formatting
+func (f Formatter) Width() int {
+	return f.width
+}
+
+// measure returns the widest rendering of the formatter's layouts
+func (f Formatter) measure() int {
+	if f.layout == "" {
+		return max(layoutWidth(recentLayout), layoutWidth(olderLayout))
+	}
+	return layoutWidth(f.layout)
+}
+
+// layoutWidth renders layout for sample times covering every month,
+// weekday and two-digit day and hour, and returns the widest result
+func layoutWidth(layout string) int {
+	widest := 0
+	for month := time.January; month <= time.December; month++ {
+		for day := 21; day <= 27; day++ {
+			sample := time.Date(2006, month, day, 22, 59, 59, 999999999, time.UTC)
+			widest = max(widest, runewidth.StringWidth(sample.Format(layout)))
+		}
+	}
+	return widest
+}
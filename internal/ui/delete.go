@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"fmt"
+)
+
+// DeleteFunc removes a command from the history files, returning how many
+// entries were removed
+type DeleteFunc func(text string) (int, error)
+
+// deleteConfirm is a deletion waiting for x to be pressed again
+type deleteConfirm struct {
+	text  string
+	files bool // Whether the command is already gone from the list and the files are next
+}
+
+// SetFileDeleter sets how commands deleted with x are also removed from
+// the history files. Without one, x only deletes from the list.
+func (m *Model) SetFileDeleter(del DeleteFunc) {
+	m.fileDelete = del
+}
+
+// handleDeleteKey deletes the selected command in steps, each confirmed by
+// pressing x again: the first x asks, the second removes the command from
+// the list, and a third also removes it from the history files when that
+// is allowed
+func (m *Model) handleDeleteKey() {
+	// The selection moved on when the command left the list, so the last
+	// x confirms the earlier command rather than the selected one
+	if confirm := m.confirmDelete; confirm != nil && confirm.files {
+		m.confirmDelete = nil
+		removed, err := m.fileDelete(confirm.text)
+		if err != nil {
+			m.setError(fmt.Sprintf("Removed %d entries, but: %v", removed, err))
+			return
+		}
+		m.setStatus(fmt.Sprintf("Removed %d entries from the history files", removed))
+		return
+	}
+
+	if !m.showsCommands() || m.cursor >= m.getItemCount() {
+		return
+	}
+	text := m.itemCommand(m.cursor)
+	if text == "" {
+		return
+	}
+
+	if m.confirmDelete == nil || m.confirmDelete.text != text {
+		m.confirmDelete = &deleteConfirm{text: text}
+		m.setStatus(fmt.Sprintf("Press x again to delete '%s'", truncateString(m.displayText(text), 40)))
+		return
+	}
+
+	m.deleteFromList(text)
+	if m.fileDelete == nil {
+		m.confirmDelete = nil
+		m.setStatus("Deleted from the list until quit; the history files are unchanged")
+		return
+	}
+	m.confirmDelete = &deleteConfirm{text: text, files: true}
+	m.setStatus("Deleted from the list; press x again to also remove it from the history files")
+}
+
+// deleteFromList removes a command from storage and keeps it out after
+// refreshes, leaving the cursor at the same position
+func (m *Model) deleteFromList(text string) {
+	if m.deleted == nil {
+		m.deleted = make(map[string]bool)
+	}
+	m.deleted[text] = true
+	m.storage.Remove(text)
+	if m.storage.IsPinned(text) {
+		m.storage.Unpin(text)
+		if err := m.savePins(); err != nil {
+			m.setError("Cannot save pins: " + err.Error())
+		}
+	}
+
+	cursor := m.cursor
+	m.loadCommands()
+	m.cursor = max(min(cursor, m.getItemCount()-1), 0)
+}
+
+// removeDeleted removes the commands deleted with x from storage again
+// after a refresh read them back from the files, returning the texts that
+// are still new
+func (m *Model) removeDeleted(added []string) []string {
+	if len(m.deleted) == 0 {
+		return added
+	}
+	texts := make([]string, 0, len(m.deleted))
+	for text := range m.deleted {
+		texts = append(texts, text)
+	}
+	m.storage.Remove(texts...)
+
+	var kept []string
+	for _, text := range added {
+		if !m.deleted[text] {
+			kept = append(kept, text)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultEditor is used when neither $VISUAL nor $EDITOR is set
+const defaultEditor = "vi"
+
+// editorDoneMsg is sent when the editor exits
+type editorDoneMsg struct {
+	err error
+}
+
+// openSourceLine opens the history file the selected command was read
+// from in $VISUAL or $EDITOR, at the line of its newest occurrence. The
+// list is shown again unchanged when the editor exits.
+func (m *Model) openSourceLine() tea.Cmd {
+	if m.cursor >= len(m.filteredCmds) {
+		return nil
+	}
+	cmd := m.filteredCmds[m.cursor]
+	if cmd.Source == history.StdinSource {
+		m.setError("This command was piped to stdin, so there is no file to open")
+		return nil
+	}
+	if history.IsRemote(cmd.Source) {
+		m.setError(fmt.Sprintf("This command was read from %s over SSH, so there is no local file to open", history.RemoteHost(cmd.Source)))
+		return nil
+	}
+	if cmd.Source == "" || cmd.Line == 0 {
+		m.setError("This command was not read from a history file, so there is nothing to open")
+		return nil
+	}
+	if _, err := os.Stat(cmd.Source); err != nil {
+		m.setError(fmt.Sprintf("Cannot open %s: %v", cmd.Source, err))
+		return nil
+	}
+
+	editor := editorCommand()
+	args := append(editor[1:], "+"+strconv.Itoa(cmd.Line), cmd.Source)
+	return tea.ExecProcess(exec.Command(editor[0], args...), func(err error) tea.Msg {
+		return editorDoneMsg{err: err}
+	})
+}
+
+// editorCommand returns the user's editor split into program and arguments,
+// e.g. "code -w"
+func editorCommand() []string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(name)); len(fields) > 0 {
+			return fields
+		}
+	}
+	return []string{defaultEditor}
+}
+
+// handleEditorDone reports an editor that failed to start or exited
+// with an error
+func (m Model) handleEditorDone(msg editorDoneMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.setError(fmt.Sprintf("Editor failed: %v", msg.err))
+	}
+	return m, nil
+}
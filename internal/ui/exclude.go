@@ -0,0 +1,133 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ExcludeFunc saves an exclude pattern so it applies to future reads
+type ExcludeFunc func(pattern string) error
+
+// excludePrompt is the pattern being edited before it is excluded
+type excludePrompt struct {
+	value       string
+	suggestions []string // Pre-filled patterns tab cycles through
+	suggestion  int
+	err         string
+}
+
+// SetExcludeHandler sets how patterns added with X are saved
+func (m *Model) SetExcludeHandler(save ExcludeFunc) {
+	m.excludeSave = save
+}
+
+// excludeSuggestions returns patterns for excluding commands like text:
+// the first word anchored at the start, and the whole command literally
+func excludeSuggestions(text string) []string {
+	literal := "^" + regexp.QuoteMeta(text) + "$"
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return []string{literal}
+	}
+
+	anchored := "^" + regexp.QuoteMeta(fields[0]) + `(\s|$)`
+	return []string{anchored, literal}
+}
+
+// startExcludePrompt opens the prompt pre-filled for the current command
+func (m *Model) startExcludePrompt() {
+	if m.mode == TemplatesMode || m.cursor >= m.getItemCount() {
+		return
+	}
+	text := m.itemCommand(m.cursor)
+	if text == "" {
+		return
+	}
+	if m.excludeSave == nil {
+		m.setError("Excluding commands is not available")
+		return
+	}
+
+	suggestions := excludeSuggestions(text)
+	m.excludePrompt = &excludePrompt{
+		value:       suggestions[0],
+		suggestions: suggestions,
+	}
+}
+
+// handleExcludeKeys edits the exclude pattern prompt
+func (m Model) handleExcludeKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	prompt := m.excludePrompt
+
+	switch msg.String() {
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+
+	case "esc":
+		m.excludePrompt = nil
+		return m, nil
+
+	case "tab":
+		prompt.suggestion = (prompt.suggestion + 1) % len(prompt.suggestions)
+		prompt.value = prompt.suggestions[prompt.suggestion]
+		prompt.err = ""
+		return m, nil
+
+	case "enter":
+		m.applyExclude()
+		return m, nil
+
+	default:
+		if value, ok := editText(prompt.value, msg); ok {
+			prompt.value = value
+			prompt.err = ""
+		}
+		return m, nil
+	}
+}
+
+// applyExclude saves the prompt's pattern and removes matching commands
+// without re-reading the history files
+func (m *Model) applyExclude() {
+	pattern := m.excludePrompt.value
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		m.excludePrompt.err = err.Error()
+		return
+	}
+	if err := m.excludeSave(pattern); err != nil {
+		m.excludePrompt.err = fmt.Sprintf("failed to save: %v", err)
+		return
+	}
+	m.excludePrompt = nil
+
+	var matching []string
+	for _, cmd := range m.storage.GetAll() {
+		if regex.MatchString(cmd.Text) {
+			matching = append(matching, cmd.Text)
+		}
+	}
+	removed := m.storage.Remove(matching...)
+	m.loadCommands()
+	m.setStatus(fmt.Sprintf("Excluded %d commands matching %s", removed, pattern))
+}
+
+// renderExcludePrompt renders the exclude pattern prompt
+func (m Model) renderExcludePrompt() string {
+	prompt := m.excludePrompt
+	line := m.theme.searchStyle.Render("Exclude pattern: ") + prompt.value + m.theme.glyphs.cursor
+	hint := "enter: add to exclude_patterns | esc: cancel"
+	if len(prompt.suggestions) > 1 {
+		hint = "tab: other suggestion | " + hint
+	}
+
+	lines := []string{line, m.theme.footerStyle.Render(hint)}
+	if prompt.err != "" {
+		lines = append(lines, m.theme.errorStyle.Render("Error: "+prompt.err))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,77 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/muesli/reflow/truncate"
+)
+
+// minInlineHeight is the fewest rows an inline layout uses: header, two
+// items and the footer
+const minInlineHeight = 4
+
+// SetInlineHeight renders the UI in rows lines below the prompt instead of
+// on the alternate screen. With rows 0, percent of the terminal height is
+// used instead.
+func (m *Model) SetInlineHeight(rows, percent int) {
+	m.inline = true
+	m.inlineRows = rows
+	m.inlinePercent = percent
+}
+
+// inlineHeight returns the height of the inline layout on a terminal with
+// the given number of rows
+func (m *Model) inlineHeight(terminalRows int) int {
+	height := m.inlineRows
+	if height == 0 {
+		height = terminalRows * m.inlinePercent / 100
+	}
+	height = max(height, minInlineHeight)
+	if terminalRows > 0 {
+		height = min(height, terminalRows)
+	}
+	return height
+}
+
+// inlineView lays out the compact inline view: the header, the list and a
+// single footer line, cut to the height so the rows below the prompt never
+// scroll
+func (m Model) inlineView() string {
+	var content, footer string
+	switch {
+	case m.showHelp:
+		return clipLines(m.renderHelp(), m.height)
+	case m.onboarding != nil:
+		return clipLines(m.renderOnboarding(), m.height)
+	case m.sourcePicker != nil:
+		content = m.renderSourcePicker()
+	case m.templateSuggest != nil:
+		content = m.renderTemplateSuggestions()
+	default:
+		content = m.renderMainContent()
+	}
+
+	switch {
+	case m.excludePrompt != nil:
+		footer = m.renderExcludePrompt()
+	case m.sourcePrompt != nil:
+		footer = m.renderSourcePrompt()
+	default:
+		footer = m.renderFooter()
+	}
+	footer = clipLines(footer, 1)
+	footer = truncate.StringWithTail(footer, uint(max(m.width, 1)), m.theme.glyphs.ellipsis)
+
+	header := truncate.StringWithTail(m.renderHeader(), uint(max(m.width, 1)), m.theme.glyphs.ellipsis)
+	content = clipLines(content, m.height-2)
+	return header + "\n" + content + "\n" + footer
+}
+
+// clipLines keeps the first n lines of text
+func clipLines(text string, n int) string {
+	lines := strings.Split(text, "\n")
+	if len(lines) > n {
+		lines = lines[:max(n, 0)]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/4ndew/terminal-history-navigator/internal/shell"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// shellBuiltins are common builtins, which have no man page of their own
+var shellBuiltins = map[string]bool{
+	"cd": true, "export": true, "alias": true, "unalias": true, "source": true,
+	".": true, "set": true, "unset": true, "history": true, "fg": true,
+	"bg": true, "jobs": true, "exit": true, "type": true, "hash": true,
+	"pushd": true, "popd": true, "dirs": true, "read": true, "eval": true,
+	"ulimit": true, "umask": true, "setopt": true, "unsetopt": true,
+	"bindkey": true, "typeset": true, "declare": true, "local": true,
+	"trap": true, "wait": true, "disown": true, "autoload": true,
+}
+
+// builtinPages are the man pages documenting builtins on various systems
+var builtinPages = []string{"builtin", "bash-builtins", "zshbuiltins"}
+
+// manDoneMsg is sent when the man page viewer exits
+type manDoneMsg struct {
+	err error
+}
+
+// openManPage shows the man page of the selected command's program with
+// man, which uses $MANPAGER or $PAGER. The list is shown again unchanged
+// when the pager exits.
+func (m *Model) openManPage() tea.Cmd {
+	if m.cursor >= m.getItemCount() {
+		return nil
+	}
+	program := shell.Program(m.itemCommand(m.cursor))
+	if program == "" {
+		m.setError("No program found in this command")
+		return nil
+	}
+	if _, err := exec.LookPath("man"); err != nil {
+		m.setError("man is not installed")
+		return nil
+	}
+
+	page := program
+	if shellBuiltins[program] {
+		page = ""
+		for _, candidate := range builtinPages {
+			if manPageExists(candidate) {
+				page = candidate
+				break
+			}
+		}
+		if page == "" {
+			m.setError(fmt.Sprintf("%s is a shell builtin, try 'help %s' in bash or 'run-help %s' in zsh", program, program, program))
+			return nil
+		}
+	} else if !manPageExists(page) {
+		m.setError("No manual entry for " + program)
+		return nil
+	}
+
+	return tea.ExecProcess(exec.Command("man", page), func(err error) tea.Msg {
+		return manDoneMsg{err: err}
+	})
+}
+
+// manPageExists reports whether man can find a page, without showing it
+func manPageExists(page string) bool {
+	return exec.Command("man", "-w", page).Run() == nil
+}
+
+// handleManDone reports a man page viewer that failed
+func (m Model) handleManDone(msg manDoneMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.setError(fmt.Sprintf("man failed: %v", msg.err))
+	}
+	return m, nil
+}
@@ -2,11 +2,17 @@ package ui
 
 import (
 	"fmt"
+	"os"
+	"regexp"
+	"time"
 
 	"github.com/4ndew/terminal-history-navigator/internal/config"
 	"github.com/4ndew/terminal-history-navigator/internal/history"
+	"github.com/4ndew/terminal-history-navigator/internal/redact"
+	"github.com/4ndew/terminal-history-navigator/internal/session"
 	"github.com/4ndew/terminal-history-navigator/internal/storage"
 	"github.com/4ndew/terminal-history-navigator/internal/templates"
+	"github.com/4ndew/terminal-history-navigator/internal/timefmt"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
@@ -17,6 +23,105 @@ const (
 	HistoryMode ViewMode = iota
 	TemplatesMode
 	SearchMode
+	SessionsMode
+	SuggestionsMode
+)
+
+// String returns the name of the view mode
+func (v ViewMode) String() string {
+	switch v {
+	case TemplatesMode:
+		return "templates"
+	case SearchMode:
+		return "search"
+	case SessionsMode:
+		return "sessions"
+	case SuggestionsMode:
+		return "suggestions"
+	default:
+		return "history"
+	}
+}
+
+// ParseViewMode converts a mode name to a ViewMode
+func ParseViewMode(name string) (ViewMode, bool) {
+	switch name {
+	case "history":
+		return HistoryMode, true
+	case "templates":
+		return TemplatesMode, true
+	case "search":
+		return SearchMode, true
+	}
+	return HistoryMode, false
+}
+
+// SortMode represents the ordering of history commands
+type SortMode int
+
+const (
+	SortRecent SortMode = iota
+	SortFrequency
+)
+
+// String returns the name of the sort mode
+func (s SortMode) String() string {
+	if s == SortFrequency {
+		return "frequency"
+	}
+	return "recent"
+}
+
+// TimeScope limits history to commands run within a recent period
+type TimeScope int
+
+const (
+	ScopeAll TimeScope = iota
+	ScopeToday
+	ScopeWeek
+	ScopeMonth
+)
+
+// String returns the description of the time scope
+func (t TimeScope) String() string {
+	switch t {
+	case ScopeToday:
+		return "today"
+	case ScopeWeek:
+		return "last 7 days"
+	case ScopeMonth:
+		return "last 30 days"
+	default:
+		return "all time"
+	}
+}
+
+// Since returns the start of the scope relative to now, or zero for all time
+func (t TimeScope) Since(now time.Time) time.Time {
+	switch t {
+	case ScopeToday:
+		year, month, day := now.Date()
+		return time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+	case ScopeWeek:
+		return now.AddDate(0, 0, -7)
+	case ScopeMonth:
+		return now.AddDate(0, 0, -30)
+	default:
+		return time.Time{}
+	}
+}
+
+// Next returns the scope that follows t when cycling
+func (t TimeScope) Next() TimeScope {
+	return (t + 1) % (ScopeMonth + 1)
+}
+
+// Outcome records why the TUI quit
+type Outcome int
+
+const (
+	OutcomeCancelled Outcome = iota // Quit without selecting anything
+	OutcomeSelected                 // A command was printed or copied
 )
 
 // Model represents the TUI application state
@@ -29,76 +134,510 @@ type Model struct {
 	// Current state
 	commands     []history.Command // All available commands
 	filteredCmds []history.Command // Filtered commands for display
+	totalMatches int               // Matching commands before the MaxItems cap
 	mode         ViewMode
-	cursor       int
-	searchQuery  string
+	sortMode     SortMode
+	timeScope    TimeScope
+	sessions     []storage.Session // Sessions listed in SessionsMode
+	openSession  int               // Index of the expanded session, -1 when listing sessions
+	suggestFor   string            // Command whose likely successors SuggestionsMode lists
+	returnMode   ViewMode          // Mode to return to when leaving SuggestionsMode
+	returnCursor int
+	workingDir   string // Directory the navigator was started in
+	dirAware     bool   // Whether commands from workingDir are listed first
+	dirOnly      bool   // Whether only commands from workingDir are listed
+	everyRun     bool   // Whether every run is listed instead of one entry per command
+
+	// Whether long items are cut to one line instead of wrapped
+	truncateLines bool
+
+	// Per-mode accent colors
+	theme theme
+
+	// Timestamp column, shown when any command has a timestamp
+	times         timefmt.Formatter
+	hasTimestamps bool
+
+	// First-run setup, shown instead of the list while set
+	onboarding *onboarding
+
+	// Sources hidden with S for the rest of the run
+	hiddenSources map[string]bool
+	sourcePicker  *sourcePicker
+
+	// Adding exclude patterns with X
+	excludeSave   ExcludeFunc
+	pinsSave      PinsFunc
+	fileDelete    DeleteFunc
+	excludePrompt *excludePrompt
+
+	// Templates suggested from history, opened with i in templates mode
+	templateSave    TemplateSaveFunc
+	templateSuggest *templateSuggestions
+
+	// Adding sources from the empty history list
+	sourcesSave  OnboardingFunc
+	sourcePrompt *sourcePrompt
+
+	// Re-reading history with r or on a timer
+	refresh         RefreshFunc
+	refreshInterval time.Duration
+	refreshing      bool
+	changes         <-chan struct{}      // Changes to history files on disk
+	arrivals        map[string]time.Time // Commands new since a refresh, marked until the time
+	cursor          int
+	scrollStart     int // First visible item with edge scrolling
+	searchQuery     string
+	correctedQuery  string // Query whose results are shown instead when searchQuery had none
+	matchAny        bool   // Whether search matches any query word instead of all
+	fuzzy           bool   // Whether search matches words as subsequences, best match first
+	pinnedOnly      bool   // Whether history mode lists only pinned commands
+	truncated       bool   // Whether older history was left out, so counts are partial
+	minCount        int    // Fewest uses for a command to be listed by frequency
 
 	// UI state
 	width    int
 	height   int
 	showHelp bool
+	quitting bool // Set on quit so the final frame is blank
+
+	// Inline mode draws below the prompt in a fixed number of rows
+	inline        bool
+	inlineRows    int
+	inlinePercent int
+
+	// Picker mode selects a command for printing instead of copying it
+	pickerMode     bool
+	selection      string
+	pickedCommand  *history.Command
+	pickedTemplate *templates.Template
+	outcome        Outcome
 
 	// Status messages
 	statusMsg string
 	errorMsg  string
+
+	// Secrets masked for display; nil when redaction is off
+	redactor    *redact.Redactor
+	confirmCopy string // Command waiting for a second enter to copy unmasked
+
+	confirmDelete *deleteConfirm  // Deletion waiting for another x
+	deleted       map[string]bool // Commands deleted with x, kept out after refreshes
+
+	// Pending clipboard clear after copying a sensitive command
+	sensitivePatterns []*regexp.Regexp
+	clearText         string
+	clearBackend      string // Backend that copied clearText, which must clear it
+	clearStatus       string
+	clearDeadline     time.Time
+	clearID           int
 }
 
-// NewModel creates a new TUI model
-func NewModel(store storage.Storage, templateList []templates.Template, cfg *config.Config) Model {
+// NewModel creates a new TUI model, restoring the saved session if given
+func NewModel(store storage.Storage, templateList []templates.Template, cfg *config.Config, state *session.State) Model {
 	model := Model{
-		storage:   store,
-		templates: templateList,
-		config:    cfg,
-		mode:      HistoryMode,
-		cursor:    0,
-		width:     80,
-		height:    24,
+		storage:     store,
+		templates:   templateList,
+		config:      cfg,
+		mode:        HistoryMode,
+		cursor:      0,
+		openSession: -1,
+		width:       80,
+		height:      24,
+	}
+
+	// Invalid patterns are reported by config validation at startup
+	for _, pattern := range cfg.Clipboard.SensitivePatterns {
+		if regex, err := regexp.Compile(pattern); err == nil {
+			model.sensitivePatterns = append(model.sensitivePatterns, regex)
+		}
+	}
+
+	model.truncateLines = cfg.UI.LineMode == "truncate"
+	model.theme = newTheme(cfg)
+	model.minCount = cfg.UI.FrequencyMinCount
+	model.everyRun = !cfg.Dedupe
+	model.fuzzy = cfg.UI.FuzzySearch
+
+	// An invalid format was reported and reset by config validation
+	model.times, _ = timefmt.New(cfg.UI.TimeFormat)
+
+	if cfg.Security.Redact {
+		model.redactor = redact.New(cfg.Security.RedactPatterns)
 	}
 
-	// Load initial commands
-	model.loadCommands()
+	// Commands run in the start directory are listed first
+	if cwd, err := os.Getwd(); err == nil {
+		model.workingDir = cwd
+	}
+
+	// Load initial commands in the saved or configured start mode
+	if state != nil {
+		model.restoreSession(state)
+	} else {
+		model.applyStartMode()
+	}
 
 	return model
 }
 
 // Init initializes the model (required by bubbletea)
 func (m Model) Init() tea.Cmd {
-	return nil
+	if m.refresh == nil {
+		return nil
+	}
+	var cmds []tea.Cmd
+	if m.refreshInterval > 0 {
+		cmds = append(cmds, refreshTick(m.refreshInterval))
+	}
+	if m.changes != nil {
+		cmds = append(cmds, waitForChange(m.changes))
+	}
+	return tea.Batch(cmds...)
 }
 
 // loadCommands loads commands based on current mode and filters
 func (m *Model) loadCommands() {
 	// Always load all commands from storage first
 	m.commands = m.storage.GetAll()
-
+	m.correctedQuery = ""
+	m.dirAware = m.config.UI.DirectoryBoost && m.workingDir != "" && hasDirectories(m.commands)
+	m.hasTimestamps = hasTimestamps(m.commands)
+
+	// MaxItems limits every list; 0 means unlimited. Storage stops at the
+	// limit when it returns the list as shown, and counts every match so
+	// the footer can report how many were cut off. The sort mode applies
+	// to search results as well.
+	limit := m.config.UI.MaxItems
+	fetch := m.storageLimit(limit)
+	total := 0
 	switch m.mode {
-	case HistoryMode:
+	case HistoryMode, SearchMode:
+		// Frequency counts group runs by command even when every run is
+		// listed otherwise
 		if m.searchQuery != "" {
-			m.filteredCmds = m.storage.Search(m.searchQuery)
+			m.filteredCmds, total = m.searchWithCorrection(fetch)
+			if m.sortMode == SortFrequency {
+				m.filteredCmds = storage.SortByCount(m.filteredCmds)
+			} else if m.everyRun {
+				m.filteredCmds = m.runsOf(m.filteredCmds)
+			} else if m.frecencyRanked() {
+				m.filteredCmds = storage.SortByFrecency(m.filteredCmds, m.config.UI.FrecencyHalfLife, time.Now())
+			}
+		} else if m.pinnedOnly && m.mode == HistoryMode {
+			m.filteredCmds = m.storage.GetPinned()
+		} else if m.sortMode == SortFrequency && m.mode == HistoryMode {
+			m.filteredCmds, total = m.storage.GetByFrequencyWithTotal(m.minCount, fetch)
+		} else if m.everyRun {
+			m.filteredCmds = m.storage.GetOccurrences(0)
 		} else {
-			m.filteredCmds = m.storage.GetRecent(m.config.UI.MaxItems)
+			m.filteredCmds, total = m.storage.GetRecentWithTotal(fetch)
 		}
 	case TemplatesMode:
 		// Templates are handled separately, clear filtered commands
 		m.filteredCmds = []history.Command{}
-	case SearchMode:
-		// Search mode uses the same data as history mode
-		m.filteredCmds = m.storage.Search(m.searchQuery)
+	case SessionsMode:
+		m.loadSessions()
+	case SuggestionsMode:
+		// Suggestions are computed once when the mode is entered
+		return
+	}
+
+	// Commands run in the working directory come first, when known
+	if m.dirAware && m.showsCommands() && m.mode != SessionsMode {
+		if m.dirOnly {
+			m.filteredCmds = storage.FilterByDirectory(m.filteredCmds, m.workingDir)
+		} else if m.sortMode == SortRecent && !m.fuzzyRanked() {
+			m.filteredCmds = storage.BoostDirectory(m.filteredCmds, m.workingDir)
+		}
+	}
+
+	// Pinned commands stay on top of the chronological history
+	if m.mode == HistoryMode && m.searchQuery == "" && !m.pinnedOnly && m.sortMode == SortRecent && !m.everyRun {
+		m.filteredCmds = m.pinnedFirst(m.filteredCmds)
+	}
+
+	if m.showsCommands() {
+		m.filteredCmds = m.filterHiddenSources(m.filteredCmds)
+	}
+
+	// Commands without a timestamp are hidden while a time scope is active
+	if m.timeScope != ScopeAll && m.showsCommands() {
+		m.filteredCmds = storage.FilterByTime(m.filteredCmds, m.timeScope.Since(time.Now()), time.Time{})
+	}
+
+	// Storage counted the matches past the limit, which lists it doesn't
+	// provide leave at 0
+	m.totalMatches = len(m.filteredCmds)
+	if fetch > 0 {
+		m.totalMatches = max(total, m.totalMatches)
+	}
+	if limit > 0 && limit < len(m.filteredCmds) {
+		m.filteredCmds = m.filteredCmds[:limit]
 	}
 
 	// Reset cursor if it's out of bounds
-	if m.cursor >= len(m.filteredCmds) && m.mode != TemplatesMode {
+	if m.cursor >= m.getItemCount() {
 		m.cursor = 0
 	}
-	if m.cursor >= len(m.templates) && m.mode == TemplatesMode {
-		m.cursor = 0
+}
+
+// runsOf returns every run of the given commands, newest first
+func (m *Model) runsOf(commands []history.Command) []history.Command {
+	texts := make(map[string]bool, len(commands))
+	for _, cmd := range commands {
+		texts[cmd.Text] = true
 	}
+	var runs []history.Command
+	for _, run := range m.storage.GetOccurrences(0) {
+		if texts[run.Text] {
+			runs = append(runs, run)
+		}
+	}
+	return runs
 }
 
-// getCurrentItem returns the currently selected item text
-func (m *Model) getCurrentItem() string {
+// effectiveQuery returns the query passed to storage, with every word made
+// an alternative when matching any word
+func (m *Model) effectiveQuery() string {
+	if !m.matchAny {
+		return m.searchQuery
+	}
+	return storage.ParseQuery(m.searchQuery).AnyWord().String()
+}
+
+// fuzzyRanked reports whether the list is in fuzzy match order, which
+// other orderings leave alone
+func (m *Model) fuzzyRanked() bool {
+	return m.fuzzy && m.searchQuery != "" && !m.everyRun
+}
+
+// frecencyRanked reports whether search results are ordered by how often
+// and how recently each command ran rather than by time alone
+func (m *Model) frecencyRanked() bool {
+	return m.config.UI.SearchOrder == "frecency" && m.searchQuery != "" && !m.fuzzy && !m.everyRun
+}
+
+// storageLimit returns the limit to pass to storage: MaxItems when storage
+// returns commands in the order shown and none are filtered out afterwards,
+// else 0 so every match can be reordered or filtered first
+func (m *Model) storageLimit(limit int) int {
+	// Time scopes, hidden sources and the working directory filter or
+	// reorder whatever storage returns, and every run is listed from the
+	// occurrences
+	if m.timeScope != ScopeAll || len(m.hiddenSources) > 0 || m.dirAware || m.everyRun {
+		return 0
+	}
+	// Search results are reranked unless listed newest first
+	if m.searchQuery != "" && (m.sortMode == SortFrequency || m.frecencyRanked() || m.fuzzy) {
+		return 0
+	}
+	return limit
+}
+
+// search runs the query in the current search mode, returning at most
+// limit matches (0 means unlimited) and how many there are in all
+func (m *Model) search(query string, limit int) ([]history.Command, int) {
+	if m.fuzzy {
+		// Every command is scored to rank fuzzy matches, so take them all
+		results := m.storage.SearchFuzzy(query, 0)
+		return results, len(results)
+	}
+	return m.storage.SearchWithTotal(query, limit)
+}
+
+// searchWithCorrection searches for the query and, with typo tolerance on
+// and nothing found, retries with misspelled words corrected
+func (m *Model) searchWithCorrection(limit int) ([]history.Command, int) {
+	query := m.effectiveQuery()
+	results, total := m.search(query, limit)
+	if total > 0 || !m.config.UI.TypoTolerance {
+		return results, total
+	}
+
+	corrected := m.storage.CorrectQuery(query)
+	if corrected == query {
+		return results, total
+	}
+	m.correctedQuery = corrected
+	return m.search(corrected, limit)
+}
+
+// hasTimestamps reports whether any command has a recorded timestamp
+func hasTimestamps(commands []history.Command) bool {
+	for _, cmd := range commands {
+		if !cmd.Timestamp.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+// hasDirectories reports whether any command records its directory
+func hasDirectories(commands []history.Command) bool {
+	for _, cmd := range commands {
+		if cmd.Directory != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// loadSessions groups history into sessions, filling filteredCmds with the
+// commands of the expanded session if there is one
+func (m *Model) loadSessions() {
+	m.sessions = m.storage.GetSessions(m.config.UI.SessionGap)
+
+	// Hide sessions that ended before the time scope
+	if m.timeScope != ScopeAll {
+		since := m.timeScope.Since(time.Now())
+		var recent []storage.Session
+		for _, session := range m.sessions {
+			if !session.End.Before(since) {
+				recent = append(recent, session)
+			}
+		}
+		m.sessions = recent
+	}
+
+	if m.openSession >= len(m.sessions) {
+		m.openSession = -1
+	}
+	m.filteredCmds = []history.Command{}
+	if m.openSession >= 0 {
+		m.filteredCmds = m.sessions[m.openSession].Commands
+	}
+}
+
+// showsCommands reports whether the current view lists history commands
+func (m *Model) showsCommands() bool {
+	switch m.mode {
+	case HistoryMode, SearchMode:
+		return true
+	case SessionsMode:
+		return m.openSession >= 0
+	}
+	return false
+}
+
+// applyStartMode switches to the configured start mode and initial query
+func (m *Model) applyStartMode() {
+	mode, ok := ParseViewMode(m.config.UI.StartMode)
+	if !ok {
+		mode = HistoryMode
+	}
+	m.mode = mode
+	m.searchQuery = m.config.UI.StartQuery
+	m.loadCommands()
+}
+
+// restoreSession applies a saved session state to the model
+func (m *Model) restoreSession(state *session.State) {
+	if state.Sort == SortFrequency.String() {
+		m.sortMode = SortFrequency
+	}
+
+	mode, ok := ParseViewMode(state.Mode)
+	if !ok {
+		mode = HistoryMode
+	}
+	m.mode = mode
+	if mode == SearchMode {
+		m.searchQuery = state.Query
+	}
+	m.loadCommands()
+
+	// Restore selection only if the command still exists, otherwise stay at the top
+	m.cursor = 0
+	if state.Selected == "" {
+		return
+	}
 	switch m.mode {
 	case HistoryMode, SearchMode:
+		for i, cmd := range m.filteredCmds {
+			if cmd.Text == state.Selected {
+				m.cursor = i
+				return
+			}
+		}
+	case TemplatesMode:
+		for i, template := range m.templates {
+			if template.Command == state.Selected {
+				m.cursor = i
+				return
+			}
+		}
+	}
+}
+
+// SetPickerMode makes enter select the current item and quit instead of
+// copying it to the clipboard
+func (m *Model) SetPickerMode(enabled bool) {
+	m.pickerMode = enabled
+}
+
+// ShowSkippedSources reports history sources that could not be read, e.g.
+// an unreachable remote host, in the status line
+func (m *Model) ShowSkippedSources(skipped []error) {
+	switch len(skipped) {
+	case 0:
+	case 1:
+		m.setError(fmt.Sprintf("Skipped %v", skipped[0]))
+	default:
+		m.setError(fmt.Sprintf("Skipped %v (and %d more sources)", skipped[0], len(skipped)-1))
+	}
+}
+
+// Selection returns the item selected in picker mode, or "" if cancelled
+func (m Model) Selection() string {
+	return m.selection
+}
+
+// PickedCommand returns the history command selected in picker mode
+func (m Model) PickedCommand() (history.Command, bool) {
+	if m.pickedCommand == nil {
+		return history.Command{}, false
+	}
+	return *m.pickedCommand, true
+}
+
+// PickedTemplate returns the template selected in picker mode
+func (m Model) PickedTemplate() (templates.Template, bool) {
+	if m.pickedTemplate == nil {
+		return templates.Template{}, false
+	}
+	return *m.pickedTemplate, true
+}
+
+// Outcome returns whether a command was selected before the TUI quit
+func (m Model) Outcome() Outcome {
+	return m.outcome
+}
+
+// SessionState returns the current UI state for persisting between runs
+func (m Model) SessionState() *session.State {
+	return &session.State{
+		Mode:     m.mode.String(),
+		Sort:     m.sortMode.String(),
+		Query:    m.searchQuery,
+		Selected: m.getCurrentItem(),
+	}
+}
+
+// isSensitive checks if a command matches any sensitive pattern
+func (m Model) isSensitive(text string) bool {
+	for _, pattern := range m.sensitivePatterns {
+		if pattern.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// getCurrentItem returns the currently selected item text
+func (m Model) getCurrentItem() string {
+	switch m.mode {
+	case HistoryMode, SearchMode, SessionsMode, SuggestionsMode:
 		if len(m.filteredCmds) == 0 || m.cursor >= len(m.filteredCmds) {
 			return ""
 		}
@@ -114,6 +653,20 @@ func (m *Model) getCurrentItem() string {
 	return ""
 }
 
+// itemCommand returns the command of the item at index i, or "" if the
+// item isn't a command (e.g. a session)
+func (m *Model) itemCommand(i int) string {
+	switch m.mode {
+	case TemplatesMode:
+		return m.templates[i].Command
+	case SessionsMode:
+		if m.openSession < 0 {
+			return ""
+		}
+	}
+	return m.filteredCmds[i].Text
+}
+
 // moveUp moves the cursor up
 func (m *Model) moveUp() {
 	if m.cursor > 0 {
@@ -123,15 +676,7 @@ func (m *Model) moveUp() {
 
 // moveDown moves the cursor down
 func (m *Model) moveDown() {
-	maxItems := 0
-	switch m.mode {
-	case HistoryMode, SearchMode:
-		maxItems = len(m.filteredCmds)
-	case TemplatesMode:
-		maxItems = len(m.templates)
-	}
-
-	if m.cursor < maxItems-1 {
+	if m.cursor < m.getItemCount()-1 {
 		m.cursor++
 	}
 }
@@ -142,6 +687,40 @@ func (m *Model) setSearchQuery(query string) {
 	m.loadCommands()
 }
 
+// toggleSortMode switches between frequency and chronological order
+func (m *Model) toggleSortMode() {
+	m.cursor = 0
+	if m.sortMode == SortFrequency {
+		m.sortMode = SortRecent
+		m.loadCommands()
+		m.setStatus("Sorted chronologically (newest first)")
+	} else {
+		m.sortMode = SortFrequency
+		m.loadCommands()
+		m.setStatus("Sorted by frequency")
+	}
+}
+
+// changeMinCount raises or lowers the frequency threshold by delta,
+// keeping the cursor on the same command if it is still listed
+func (m *Model) changeMinCount(delta int) {
+	if m.minCount+delta < 1 {
+		return
+	}
+	m.minCount += delta
+
+	selected := m.getCurrentItem()
+	m.loadCommands()
+	m.selectItem(selected)
+}
+
+// setTimeScope applies a time scope and reloads commands
+func (m *Model) setTimeScope(scope TimeScope) {
+	m.timeScope = scope
+	m.cursor = 0
+	m.loadCommands()
+}
+
 // switchToHistoryMode switches to history view mode
 func (m *Model) switchToHistoryMode() {
 	m.mode = HistoryMode
@@ -151,6 +730,68 @@ func (m *Model) switchToHistoryMode() {
 	m.statusMsg = "" // Clear status to show normal mode
 }
 
+// switchToSessionsMode switches to the list of sessions
+func (m *Model) switchToSessionsMode() {
+	m.mode = SessionsMode
+	m.cursor = 0
+	m.openSession = -1
+	m.searchQuery = ""
+	m.loadCommands()
+	m.statusMsg = ""
+}
+
+// openCurrentSession expands the session under the cursor
+func (m *Model) openCurrentSession() {
+	if m.cursor >= len(m.sessions) {
+		return
+	}
+	m.openSession = m.cursor
+	m.cursor = 0
+	m.loadCommands()
+}
+
+// closeSession returns from an expanded session to the session list
+func (m *Model) closeSession() {
+	m.cursor = m.openSession
+	m.openSession = -1
+	m.loadCommands()
+}
+
+// showSuggestions lists the commands that usually follow the selected one,
+// returning false if there are none
+func (m *Model) showSuggestions() bool {
+	text := m.getCurrentItem()
+	if text == "" || m.mode == TemplatesMode {
+		return false
+	}
+
+	successors := m.storage.GetSuccessors(text, 3)
+	if len(successors) == 0 {
+		return false
+	}
+
+	m.returnMode = m.mode
+	m.returnCursor = m.cursor
+	m.suggestFor = text
+	m.mode = SuggestionsMode
+	m.cursor = 0
+	m.filteredCmds = make([]history.Command, 0, len(successors))
+	for _, successor := range successors {
+		m.filteredCmds = append(m.filteredCmds, history.Command{Text: successor.Text, Count: successor.Count})
+	}
+	return true
+}
+
+// closeSuggestions returns to the mode the suggestions were opened from
+func (m *Model) closeSuggestions() {
+	m.mode = m.returnMode
+	m.loadCommands()
+	m.cursor = m.returnCursor
+	if m.cursor >= m.getItemCount() {
+		m.cursor = 0
+	}
+}
+
 // switchToTemplatesMode switches to templates view mode
 func (m *Model) switchToTemplatesMode() {
 	m.mode = TemplatesMode
@@ -191,36 +832,67 @@ func (m *Model) clearMessages() {
 	m.errorMsg = ""
 }
 
-// getVisibleItems returns the items that should be visible on screen
-func (m *Model) getVisibleItems() ([]string, int) {
-	var items []string
-	var selectedIndex int
+// displayText returns command text as shown on screen: control characters
+// made visible and, with redaction on, secrets masked
+func (m *Model) displayText(text string) string {
+	return m.redactor.Redact(sanitizeText(text))
+}
+
+// commandText returns the display text of a command, noting if it was
+// truncated when read
+func (m *Model) commandText(cmd history.Command) string {
+	text := m.displayText(cmd.Text)
+	if cmd.Truncated {
+		text += fmt.Sprintf("%s (truncated, %s)", m.theme.glyphs.ellipsis, formatSize(cmd.FullSize))
+	}
+	return text
+}
+
+// formatSize formats a size in bytes as B, KB or MB
+func formatSize(bytes int) string {
+	switch {
+	case bytes >= 1024*1024:
+		return fmt.Sprintf("%.1fMB", float64(bytes)/(1024*1024))
+	case bytes >= 1024:
+		return fmt.Sprintf("%dKB", bytes/1024)
+	default:
+		return fmt.Sprintf("%dB", bytes)
+	}
+}
 
+// itemText returns the display text of the item at index i
+func (m *Model) itemText(i int) string {
 	switch m.mode {
 	case HistoryMode, SearchMode:
-		for _, cmd := range m.filteredCmds {
-			item := cmd.Text
-			// Show frequency count if sorted by frequency and count > 1
-			if m.statusMsg == "Sorted by frequency" && cmd.Count > 1 {
-				item = fmt.Sprintf("[%dx] %s", cmd.Count, cmd.Text)
-			}
-			items = append(items, item)
+		cmd := m.filteredCmds[i]
+		// Show frequency count if sorted by frequency and count > 1
+		text := m.commandText(cmd)
+		if m.sortMode == SortFrequency && cmd.Count > 1 {
+			return fmt.Sprintf("[%dx] %s", cmd.Count, text)
 		}
-		selectedIndex = m.cursor
+		return text
+
+	case SessionsMode:
+		if m.openSession >= 0 {
+			return m.commandText(m.filteredCmds[i])
+		}
+		return formatSession(m.sessions[i], m.theme.glyphs)
+
+	case SuggestionsMode:
+		cmd := m.filteredCmds[i]
+		return fmt.Sprintf("[%dx] %s", cmd.Count, m.commandText(cmd))
 
 	case TemplatesMode:
-		for _, template := range m.templates {
-			// Format: "Name - Command (Description)"
-			item := template.Name + " - " + template.Command
-			if template.Description != "" {
-				item += " (" + template.Description + ")"
-			}
-			items = append(items, item)
+		// Format: "Name - Command (Description)"
+		template := m.templates[i]
+		item := template.Name + " - " + template.Command
+		if template.Description != "" {
+			item += " (" + template.Description + ")"
 		}
-		selectedIndex = m.cursor
+		return sanitizeText(item)
 	}
 
-	return items, selectedIndex
+	return ""
 }
 
 // getItemCount returns the total number of items in current mode
@@ -230,12 +902,33 @@ func (m *Model) getItemCount() int {
 		return len(m.filteredCmds)
 	case TemplatesMode:
 		return len(m.templates)
+	case SessionsMode:
+		if m.openSession >= 0 {
+			return len(m.filteredCmds)
+		}
+		return len(m.sessions)
+	case SuggestionsMode:
+		return len(m.filteredCmds)
 	}
 	return 0
 }
 
-// resize updates the model dimensions
+// formatSession describes a session, e.g. "Tue Nov 14 19:02–20:41 · 57 commands"
+func formatSession(session storage.Session, g glyphs) string {
+	end := session.End.Local().Format("15:04")
+	if session.End.Local().YearDay() != session.Start.Local().YearDay() {
+		end = session.End.Local().Format("Mon 15:04")
+	}
+	return fmt.Sprintf("%s%s%s%s%d commands",
+		session.Start.Local().Format("Mon Jan 2 15:04"), g.dash, end, g.sep, len(session.Commands))
+}
+
+// resize updates the model dimensions. In inline mode the height is the
+// configured part of the terminal.
 func (m *Model) resize(width, height int) {
 	m.width = width
 	m.height = height
+	if m.inline {
+		m.height = m.inlineHeight(height)
+	}
 }
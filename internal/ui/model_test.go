@@ -0,0 +1,239 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4ndew/terminal-history-navigator/internal/config"
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+	"github.com/4ndew/terminal-history-navigator/internal/storage"
+	"github.com/4ndew/terminal-history-navigator/internal/templates"
+)
+
+// newTestModel returns a model over a few commands and templates, with
+// the config changed by configure
+func newTestModel(configure func(cfg *config.Config)) Model {
+	store := storage.NewMemoryStorage()
+	store.Store([]history.Command{
+		{Text: "git status", Position: 2, Count: 1},
+		{Text: "make test", Position: 1, Count: 1},
+		{Text: "git push", Position: 0, Count: 1},
+	})
+	templateList := []templates.Template{{Name: "Build", Command: "go build ./..."}}
+
+	cfg := config.DefaultConfig()
+	configure(cfg)
+	return NewModel(store, templateList, cfg, nil)
+}
+
+func TestStartMode(t *testing.T) {
+	tests := []struct {
+		mode, query string
+		want        ViewMode
+		commands    int
+	}{
+		{"", "", HistoryMode, 3},
+		{"history", "", HistoryMode, 3},
+		{"templates", "", TemplatesMode, 3},
+		{"search", "git", SearchMode, 2},
+		{"unknown", "", HistoryMode, 3},
+	}
+	for _, tt := range tests {
+		m := newTestModel(func(cfg *config.Config) {
+			cfg.UI.StartMode = tt.mode
+			cfg.UI.StartQuery = tt.query
+		})
+		if m.mode != tt.want || m.searchQuery != tt.query {
+			t.Errorf("start mode %q = mode %v query %q, want %v %q", tt.mode, m.mode, m.searchQuery, tt.want, tt.query)
+		}
+		if tt.want != TemplatesMode && len(m.filteredCmds) != tt.commands {
+			t.Errorf("start mode %q lists %d commands, want %d", tt.mode, len(m.filteredCmds), tt.commands)
+		}
+	}
+}
+
+// TestStartQueryInFirstFrame checks the first frame already shows the
+// start query and its matches
+func TestStartQueryInFirstFrame(t *testing.T) {
+	m := newTestModel(func(cfg *config.Config) {
+		cfg.UI.StartMode = "search"
+		cfg.UI.StartQuery = "make"
+	})
+	view := m.View()
+	if !strings.Contains(view, "make test") || strings.Contains(view, "git push") {
+		t.Errorf("first frame doesn't show only the matches of the start query:\n%s", view)
+	}
+}
+
+// TestMaxItems checks ui.max_items caps the recent, search and frequency
+// lists alike, and 0 lists everything
+func TestMaxItems(t *testing.T) {
+	for _, maxItems := range []int{0, 1, 2, 5} {
+		m := newTestModel(func(cfg *config.Config) { cfg.UI.MaxItems = maxItems })
+		m.minCount = 1
+
+		lists := map[string]func(){
+			"recent": func() { m.searchQuery = ""; m.sortMode = SortRecent },
+			"search": func() { m.searchQuery = "git | make"; m.sortMode = SortRecent; m.config.UI.SearchOrder = "frecency" },
+			"search newest first": func() {
+				m.searchQuery = "git | make"
+				m.sortMode = SortRecent
+				m.config.UI.SearchOrder = "recent"
+			},
+			"frequency": func() { m.searchQuery = ""; m.sortMode = SortFrequency },
+		}
+		for name, show := range lists {
+			show()
+			m.loadCommands()
+			want := 3
+			if maxItems > 0 && maxItems < want {
+				want = maxItems
+			}
+			if len(m.filteredCmds) != want || m.totalMatches != 3 {
+				t.Errorf("max_items %d: %s lists %d of %d, want %d of 3", maxItems, name, len(m.filteredCmds), m.totalMatches, want)
+			}
+		}
+	}
+}
+
+// TestMaxItemsKeepsPins checks pinned commands stay on top of a history
+// that storage cut at ui.max_items, even when they are older than the cut
+func TestMaxItemsKeepsPins(t *testing.T) {
+	m := newTestModel(func(cfg *config.Config) { cfg.UI.MaxItems = 2 })
+	m.storage.Pin("git push")
+	m.loadCommands()
+
+	var got []string
+	for _, cmd := range m.filteredCmds {
+		got = append(got, cmd.Text)
+	}
+	if strings.Join(got, ", ") != "git push, git status" || m.totalMatches != 3 {
+		t.Errorf("history with git push pinned = %q of %d, want git push, git status of 3", got, m.totalMatches)
+	}
+}
+
+// TestTypoTolerance checks a misspelled query falls back to the corrected
+// one, named in the view, only when typo tolerance is on
+func TestTypoTolerance(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		m := newTestModel(func(cfg *config.Config) {
+			cfg.UI.TypoTolerance = enabled
+			cfg.UI.StartMode = "search"
+			cfg.UI.StartQuery = "gti psuh"
+		})
+		want := 0
+		if enabled {
+			want = 1
+		}
+		if len(m.filteredCmds) != want {
+			t.Errorf("typo tolerance %t lists %d commands, want %d", enabled, len(m.filteredCmds), want)
+		}
+		if shown := strings.Contains(m.View(), "showing results for 'git push'"); shown != enabled {
+			t.Errorf("typo tolerance %t names the correction: %t", enabled, shown)
+		}
+	}
+}
+
+// TestSearchOrder checks search results are ranked by frecency by default
+// and by recency with search_order: recent
+func TestSearchOrder(t *testing.T) {
+	now := time.Now()
+	store := storage.NewMemoryStorage()
+	store.Store([]history.Command{
+		{Text: "git log -1", Timestamp: now.Add(-time.Hour), Count: 1},
+		{Text: "git status", Timestamp: now.Add(-7 * 24 * time.Hour), Count: 50},
+	})
+
+	for order, want := range map[string]string{"frecency": "git status", "recent": "git log -1"} {
+		cfg := config.DefaultConfig()
+		cfg.UI.SearchOrder = order
+		cfg.UI.StartMode = "search"
+		cfg.UI.StartQuery = "git"
+		m := NewModel(store, nil, cfg, nil)
+		if len(m.filteredCmds) != 2 || m.filteredCmds[0].Text != want {
+			t.Errorf("search_order %s lists %+v first, want %q", order, m.filteredCmds, want)
+		}
+	}
+}
+
+// TestSearchByFrequency checks a search made in the frequency view lists
+// its matches most used first, ahead of how recent or frecent they are
+func TestSearchByFrequency(t *testing.T) {
+	now := time.Now()
+	store := storage.NewMemoryStorage()
+	store.Store([]history.Command{
+		{Text: "git log", Position: 4, Timestamp: now.Add(-time.Minute), Count: 1},
+		{Text: "make test", Position: 3, Timestamp: now.Add(-time.Hour), Count: 9},
+		{Text: "git commit", Position: 2, Timestamp: now.Add(-2 * time.Hour), Count: 7},
+		{Text: "git push", Position: 1, Timestamp: now.Add(-3 * time.Hour), Count: 3},
+		{Text: "git status", Position: 0, Timestamp: now.Add(-30 * 24 * time.Hour), Count: 12},
+	})
+	cfg := config.DefaultConfig()
+	m := NewModel(store, nil, cfg, nil)
+
+	m, _ = press(m, runes("f/git")...)
+	if m.mode != SearchMode || m.sortMode != SortFrequency {
+		t.Fatalf("mode %v sorted by %v, want a search sorted by frequency", m.mode, m.sortMode)
+	}
+	var got []string
+	for _, cmd := range m.filteredCmds {
+		got = append(got, cmd.Text)
+	}
+	if strings.Join(got, ", ") != "git status, git commit, git push, git log" {
+		t.Errorf("search for git by frequency = %q, want most used first", got)
+	}
+}
+
+// TestExcludeQueryInHeader checks the search header shows the query as
+// typed, negative words included
+func TestExcludeQueryInHeader(t *testing.T) {
+	m := newTestModel(func(cfg *config.Config) {
+		cfg.UI.StartMode = "search"
+		cfg.UI.StartQuery = "git -push"
+	})
+	if len(m.filteredCmds) != 1 || m.filteredCmds[0].Text != "git status" {
+		t.Errorf("git -push lists %+v, want only git status", m.filteredCmds)
+	}
+	if view := m.View(); !strings.Contains(view, "git -push") {
+		t.Errorf("header doesn't show the raw query:\n%s", view)
+	}
+}
+
+// TestFrequencyTruncatedNote checks the frequency view says its counts only
+// cover the lines read when older lines were left out
+func TestFrequencyTruncatedNote(t *testing.T) {
+	for _, truncated := range []bool{false, true} {
+		m := newTestModel(func(*config.Config) {})
+		m.SetTruncated(truncated)
+		m.sortMode = SortFrequency
+		m.loadCommands()
+		if noted := strings.Contains(m.View(), "counts from the last"); noted != truncated {
+			t.Errorf("truncated %t: frequency view notes partial counts %t", truncated, noted)
+		}
+	}
+}
+
+// BenchmarkLoadCommandsSearch lists the 1000 newest of 20000 commands
+// matching a search, as typing a query does
+func BenchmarkLoadCommandsSearch(b *testing.B) {
+	m := newListModel(20000, func(cfg *config.Config) {
+		cfg.UI.MaxItems = 1000
+		cfg.UI.SearchOrder = "recent"
+	})
+	m.searchQuery = "cmd"
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.loadCommands()
+	}
+}
+
+// BenchmarkLoadCommandsRecent lists the 1000 newest of 20000 commands, as
+// opening the history does
+func BenchmarkLoadCommandsRecent(b *testing.B) {
+	m := newListModel(20000, func(cfg *config.Config) { cfg.UI.MaxItems = 1000 })
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.loadCommands()
+	}
+}
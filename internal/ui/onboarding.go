@@ -0,0 +1,155 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// OnboardingFunc saves the history files chosen during onboarding
+type OnboardingFunc func(sources []string) error
+
+// Onboarding steps
+const (
+	stepSources = iota // Choose the detected history files to read
+	stepSummary        // Show where files are written and the shell integration
+)
+
+// onboarding is the state of the first-run setup form
+type onboarding struct {
+	step    int
+	files   []history.DetectedSource
+	checked []bool
+	cursor  int
+	err     string
+	finish  OnboardingFunc
+}
+
+// StartOnboarding opens the first-run setup, listing detected history
+// files to choose from. finish is called with the chosen files before the
+// normal UI is shown.
+func (m *Model) StartOnboarding(files []history.DetectedSource, finish OnboardingFunc) {
+	checked := make([]bool, len(files))
+	for i := range checked {
+		checked[i] = true
+	}
+	m.onboarding = &onboarding{
+		files:   files,
+		checked: checked,
+		finish:  finish,
+	}
+}
+
+// selectedSources returns the paths of the checked files
+func (o *onboarding) selectedSources() []string {
+	var sources []string
+	for i, file := range o.files {
+		if o.checked[i] {
+			sources = append(sources, file.Path)
+		}
+	}
+	return sources
+}
+
+// handleOnboardingKeys moves through the onboarding steps
+func (m Model) handleOnboardingKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	o := m.onboarding
+
+	switch msg.String() {
+	case "ctrl+c", "q":
+		m.quitting = true
+		return m, tea.Quit
+	}
+
+	if o.step == stepSources {
+		switch msg.String() {
+		case "up", "k":
+			if o.cursor > 0 {
+				o.cursor--
+			}
+		case "down", "j":
+			if o.cursor < len(o.files)-1 {
+				o.cursor++
+			}
+		case " ", "x":
+			if len(o.files) > 0 {
+				o.checked[o.cursor] = !o.checked[o.cursor]
+			}
+		case "enter":
+			o.step = stepSummary
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "esc", "backspace", "left":
+		o.step = stepSources
+		o.err = ""
+	case "enter":
+		if err := o.finish(o.selectedSources()); err != nil {
+			o.err = err.Error()
+			return m, nil
+		}
+		m.onboarding = nil
+		m.setStatus("Setup saved to " + m.config.Path())
+
+		// Re-read quietly so the status keeps confirming the setup
+		return m, m.startRefresh(true)
+	}
+	return m, nil
+}
+
+// renderOnboarding renders the current onboarding step
+func (m Model) renderOnboarding() string {
+	o := m.onboarding
+	var lines []string
+	lines = append(lines, m.theme.headerStyle.Render("Welcome to Terminal History Navigator"), "")
+
+	if o.step == stepSources {
+		if len(o.files) == 0 {
+			lines = append(lines,
+				"No zsh or bash history files were found in their default locations.",
+				"You can add files to sources in the config later.")
+		} else {
+			lines = append(lines, "Read history from these files:", "")
+			for i, file := range o.files {
+				box := "[ ]"
+				if o.checked[i] {
+					box = "[x]"
+				}
+				line := fmt.Sprintf("%s %-4s %s", box, file.Shell, file.Path)
+				if i == o.cursor {
+					lines = append(lines, m.selectedStyle().Render(m.theme.glyphs.selected+line))
+				} else {
+					lines = append(lines, m.theme.normalItemStyle.Render("  "+line))
+				}
+			}
+		}
+		lines = append(lines, "", m.theme.footerStyle.Render(m.theme.glyphs.upDown+": move | space: toggle | enter: next | q: quit"))
+		return strings.Join(lines, "\n")
+	}
+
+	sources := o.selectedSources()
+	if len(sources) == 0 {
+		lines = append(lines, "No history files selected.")
+	} else {
+		lines = append(lines, fmt.Sprintf("Reading %d history file(s).", len(sources)))
+	}
+	lines = append(lines,
+		"",
+		"Config:    "+m.config.Path(),
+		"Templates: "+m.config.TemplatesPath,
+		"",
+		"To open the navigator with ctrl+r, add to ~/.zshrc (or ~/.bashrc with init bash):",
+		m.theme.searchStyle.Render(`  eval "$(terminal-history-navigator init zsh)"`),
+		"",
+		"Run terminal-history-navigator --onboarding to see this again.",
+	)
+	if o.err != "" {
+		lines = append(lines, "", m.theme.errorStyle.Render("Error: "+o.err))
+	}
+	lines = append(lines, "", m.theme.footerStyle.Render("enter: save and start | esc: back | q: quit"))
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+)
+
+// PinsFunc saves the texts of the pinned commands so they are pinned again
+// on the next run
+type PinsFunc func(texts []string) error
+
+// SetPinsSaver sets how pins changed with P are saved
+func (m *Model) SetPinsSaver(save PinsFunc) {
+	m.pinsSave = save
+}
+
+// togglePin pins the selected command, or unpins it if pinned, and saves
+// the pins
+func (m *Model) togglePin() {
+	if !m.showsCommands() || m.cursor >= m.getItemCount() {
+		return
+	}
+	text := m.itemCommand(m.cursor)
+	if text == "" {
+		return
+	}
+
+	pinned := !m.storage.IsPinned(text)
+	if pinned {
+		m.storage.Pin(text)
+	} else {
+		m.storage.Unpin(text)
+	}
+	if err := m.savePins(); err != nil {
+		m.setError("Cannot save pins: " + err.Error())
+		return
+	}
+
+	m.loadCommands()
+	m.selectItem(text)
+	if pinned {
+		m.setStatus("Pinned")
+	} else {
+		m.setStatus("Unpinned")
+	}
+}
+
+// savePins saves the texts of the pinned commands, if a saver is set
+func (m *Model) savePins() error {
+	if m.pinsSave == nil {
+		return nil
+	}
+	var texts []string
+	for _, cmd := range m.storage.GetPinned() {
+		texts = append(texts, cmd.Text)
+	}
+	return m.pinsSave(texts)
+}
+
+// togglePinnedOnly switches between all history and only pinned commands
+func (m *Model) togglePinnedOnly() {
+	m.pinnedOnly = !m.pinnedOnly
+	m.cursor = 0
+	m.loadCommands()
+	if m.pinnedOnly && len(m.filteredCmds) == 0 {
+		m.setStatus("No pinned commands; press P to pin the selected one")
+	}
+}
+
+// pinnedFirst returns commands with the pinned ones moved to the front in
+// the order they were pinned, including pins no longer in the history
+func (m *Model) pinnedFirst(commands []history.Command) []history.Command {
+	pinned := m.storage.GetPinned()
+	if len(pinned) == 0 {
+		return commands
+	}
+
+	ordered := make([]history.Command, 0, len(pinned)+len(commands))
+	ordered = append(ordered, pinned...)
+	for _, cmd := range commands {
+		if !m.storage.IsPinned(cmd.Text) {
+			ordered = append(ordered, cmd)
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,198 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+	"github.com/4ndew/terminal-history-navigator/internal/logging"
+	"github.com/4ndew/terminal-history-navigator/internal/storage"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// RefreshFunc re-reads the history
+type RefreshFunc func() (Refreshed, error)
+
+// Refreshed is the history read by a RefreshFunc
+type Refreshed struct {
+	Commands    []history.Command // Deduplicated commands, or only the new ones if Incremental
+	Occurrences []history.Command // Every run of every command, newest first
+	Truncated   bool              // Whether older lines of a source were left out
+	Incremental bool              // Whether only what was appended since the last read was read
+}
+
+// arrivalMarkTime is how long commands new since a refresh stay marked
+const arrivalMarkTime = time.Minute
+
+// refreshTickMsg triggers an automatic refresh
+type refreshTickMsg struct{}
+
+// sourceChangedMsg is sent when a watched history file changed on disk
+type sourceChangedMsg struct{}
+
+// arrivalsExpiredMsg is sent when arrival marks may have run out
+type arrivalsExpiredMsg struct{}
+
+// refreshDoneMsg carries the result of a refresh
+type refreshDoneMsg struct {
+	Refreshed
+	err  error
+	auto bool
+}
+
+// SetRefresh sets how the history is re-read with r, and how often it is
+// re-read automatically (0 disables automatic refresh)
+func (m *Model) SetRefresh(refresh RefreshFunc, interval time.Duration) {
+	m.refresh = refresh
+	m.refreshInterval = interval
+}
+
+// SetTruncated records whether the history was read without the older
+// lines of a source, so the frequency view can say its counts are partial
+func (m *Model) SetTruncated(truncated bool) {
+	m.truncated = truncated
+}
+
+// SetWatcher refreshes the history whenever changes receives a value
+func (m *Model) SetWatcher(changes <-chan struct{}) {
+	m.changes = changes
+}
+
+// waitForChange waits for the next change to a watched history file
+func waitForChange(changes <-chan struct{}) tea.Cmd {
+	return func() tea.Msg {
+		if _, ok := <-changes; !ok {
+			return nil
+		}
+		return sourceChangedMsg{}
+	}
+}
+
+// handleSourceChanged refreshes after a history file changed and waits for
+// the next change
+func (m Model) handleSourceChanged() (tea.Model, tea.Cmd) {
+	return m, tea.Batch(m.startRefresh(true), waitForChange(m.changes))
+}
+
+// refreshTick schedules the next automatic refresh
+func refreshTick(interval time.Duration) tea.Cmd {
+	return tea.Tick(interval, func(time.Time) tea.Msg {
+		return refreshTickMsg{}
+	})
+}
+
+// startRefresh re-reads the history in the background, unless a refresh
+// is already running
+func (m *Model) startRefresh(auto bool) tea.Cmd {
+	if m.refresh == nil || m.refreshing {
+		return nil
+	}
+	// Standard input was read once at startup and can't be read again
+	if slices.Contains(m.config.Sources, history.StdinSource) {
+		if !auto {
+			m.setError("History piped to stdin can't be refreshed")
+		}
+		return nil
+	}
+	m.refreshing = true
+
+	refresh := m.refresh
+	return func() tea.Msg {
+		refreshed, err := refresh()
+		return refreshDoneMsg{Refreshed: refreshed, err: err, auto: auto}
+	}
+}
+
+// handleRefreshTick starts an automatic refresh and schedules the next one
+func (m Model) handleRefreshTick() (tea.Model, tea.Cmd) {
+	return m, tea.Batch(m.startRefresh(true), refreshTick(m.refreshInterval))
+}
+
+// handleRefreshDone stores refreshed history, keeping the cursor on the
+// same command
+func (m Model) handleRefreshDone(msg refreshDoneMsg) (tea.Model, tea.Cmd) {
+	m.refreshing = false
+	if msg.err != nil {
+		if msg.auto {
+			logging.Warnf("automatic refresh failed: %v", msg.err)
+		} else {
+			m.setError(fmt.Sprintf("Failed to refresh: %v", msg.err))
+		}
+		return m, nil
+	}
+
+	selected := m.getCurrentItem()
+
+	var merged storage.MergeResult
+	if msg.Incremental {
+		merged = m.storage.Append(msg.Commands)
+	} else {
+		merged = m.storage.Store(msg.Commands)
+	}
+	m.storage.StoreOccurrences(msg.Occurrences)
+	m.truncated = msg.Truncated
+	merged.Added = m.removeDeleted(merged.Added)
+	m.loadCommands()
+	m.selectItem(selected)
+
+	switch {
+	case len(merged.Added) > 0 && merged.Updated > 0:
+		m.setStatus(fmt.Sprintf("+%d new, %d updated", len(merged.Added), merged.Updated))
+	case len(merged.Added) > 0:
+		m.setStatus(fmt.Sprintf("+%d new", len(merged.Added)))
+	case merged.Updated > 0:
+		m.setStatus(fmt.Sprintf("%d updated", merged.Updated))
+	case !msg.auto:
+		m.setStatus("No new commands")
+	}
+	return m, m.markArrivals(merged.Added)
+}
+
+// markArrivals marks commands that are new since the last refresh for
+// arrivalMarkTime, and schedules a redraw without the marks
+func (m *Model) markArrivals(texts []string) tea.Cmd {
+	if len(texts) == 0 {
+		return nil
+	}
+	if m.arrivals == nil {
+		m.arrivals = make(map[string]time.Time)
+	}
+	until := time.Now().Add(arrivalMarkTime)
+	for _, text := range texts {
+		m.arrivals[text] = until
+	}
+	return tea.Tick(arrivalMarkTime, func(time.Time) tea.Msg {
+		return arrivalsExpiredMsg{}
+	})
+}
+
+// isArrival reports whether a command is still marked as new
+func (m Model) isArrival(text string) bool {
+	until, ok := m.arrivals[text]
+	return ok && time.Now().Before(until)
+}
+
+// handleArrivalsExpired drops the marks that have run out
+func (m Model) handleArrivalsExpired() (tea.Model, tea.Cmd) {
+	now := time.Now()
+	for text, until := range m.arrivals {
+		if !now.Before(until) {
+			delete(m.arrivals, text)
+		}
+	}
+	return m, nil
+}
+
+// selectItem moves the cursor to the item with the given text, if listed
+func (m *Model) selectItem(text string) {
+	if text == "" {
+		return
+	}
+	for i := 0; i < m.getItemCount(); i++ {
+		if m.itemCommand(i) == text {
+			m.cursor = i
+			return
+		}
+	}
+}
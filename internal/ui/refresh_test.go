@@ -0,0 +1,34 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/4ndew/terminal-history-navigator/internal/config"
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+)
+
+// TestRefreshStatus checks a refresh reports the storage's merge result and
+// marks only the new commands
+func TestRefreshStatus(t *testing.T) {
+	m := newTestModel(func(*config.Config) {})
+
+	model, cmd := m.Update(refreshDoneMsg{Refreshed: Refreshed{
+		Commands: []history.Command{
+			{Text: "go test ./...", Position: 3, Count: 1},
+			{Text: "make test", Position: 4, Count: 1},
+		},
+		Incremental: true,
+	}})
+	m = model.(Model)
+	if m.statusMsg != "+1 new, 1 updated" {
+		t.Errorf("status = %q, want +1 new, 1 updated", m.statusMsg)
+	}
+	if !m.isArrival("go test ./...") || m.isArrival("make test") || cmd == nil {
+		t.Errorf("arrivals = %v with expiry scheduled %t, want only go test ./...", m.arrivals, cmd != nil)
+	}
+
+	model, _ = m.Update(refreshDoneMsg{Refreshed: Refreshed{Incremental: true}})
+	if m = model.(Model); m.statusMsg != "No new commands" {
+		t.Errorf("status after an empty refresh = %q, want No new commands", m.statusMsg)
+	}
+}
@@ -0,0 +1,52 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// escapeSequence matches ANSI CSI sequences (colors, cursor movement,
+// clearing), OSC sequences (titles, hyperlinks) and other two-byte escapes
+var escapeSequence = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)?|\x1b[@-_]?`)
+
+// sanitizeText makes command text safe to render: escape sequences are
+// removed and other control characters are shown in caret notation (^M),
+// so history entries can't recolor or clear the screen. Newlines are kept.
+func sanitizeText(text string) string {
+	clean := true
+	for _, r := range text {
+		if isControl(r) {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return text
+	}
+
+	text = escapeSequence.ReplaceAllString(text, "")
+
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\t':
+			b.WriteByte(' ')
+		case r == 0x7f || (r < 0x20 && r != '\n'):
+			b.WriteByte('^')
+			b.WriteRune(r ^ 0x40)
+		case r >= 0x80 && r <= 0x9f:
+			// C1 controls have no caret form
+			fmt.Fprintf(&b, "\\u%04X", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isControl reports whether r is a C0 or C1 control character other than
+// a newline
+func isControl(r rune) bool {
+	return (r < 0x20 && r != '\n') || (r >= 0x7f && r <= 0x9f)
+}
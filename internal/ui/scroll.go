@@ -0,0 +1,78 @@
+package ui
+
+// edgeScrollWindow returns the range of items to show when scrolling only
+// as the cursor nears the window edges. The window starts at scrollStart,
+// which scrollToCursor keeps up to date, and is filled downwards from there.
+func (m Model) edgeScrollWindow(itemCount, maxVisibleLines int) (int, int) {
+	start := m.scrollStart
+	if start < 0 || start > m.cursor || start >= itemCount {
+		// The window hasn't caught up with the cursor yet (e.g. before the
+		// first update), so fall back to placing it around the cursor
+		return m.calculateScrollWindow(itemCount, maxVisibleLines)
+	}
+
+	end, _ := m.fillWindow(start, itemCount, maxVisibleLines)
+	if m.cursor >= end {
+		return m.calculateScrollWindow(itemCount, maxVisibleLines)
+	}
+	return start, end
+}
+
+// fillWindow returns the end of the window starting at start and the
+// number of lines it uses. The first item is always included.
+func (m Model) fillWindow(start, itemCount, maxVisibleLines int) (int, int) {
+	end, lines := start, 0
+	for end < itemCount {
+		h := m.calculateItemHeight(m.itemText(end), end == m.cursor)
+		if end > start && lines+h > maxVisibleLines {
+			break
+		}
+		end++
+		lines += h
+	}
+	return end, lines
+}
+
+// scrollToCursor moves scrollStart so that, in edge scrolling, the cursor
+// stays at least ui.scrolloff items away from the window edges
+func (m *Model) scrollToCursor() {
+	if m.config.UI.Scroll != "edge" {
+		return
+	}
+
+	itemCount := m.getItemCount()
+	if itemCount == 0 || m.cursor >= itemCount {
+		m.scrollStart = 0
+		return
+	}
+
+	maxVisibleLines := m.maxVisibleLines()
+	scrolloff := min(m.config.UI.Scrolloff, (maxVisibleLines-1)/2)
+	start := min(max(m.scrollStart, 0), itemCount-1)
+
+	// Keep scrolloff items above the cursor
+	if m.cursor-scrolloff < start {
+		start = max(m.cursor-scrolloff, 0)
+	}
+
+	// Keep scrolloff items below the cursor, unless the list ends first
+	last := min(m.cursor+scrolloff, itemCount-1)
+	for start < m.cursor {
+		end, _ := m.fillWindow(start, itemCount, maxVisibleLines)
+		if end > last {
+			break
+		}
+		start++
+	}
+
+	// Don't leave empty space at the bottom when there are items above
+	for start > 0 {
+		end, lines := m.fillWindow(start-1, itemCount, maxVisibleLines)
+		if end < itemCount || lines > maxVisibleLines {
+			break
+		}
+		start--
+	}
+
+	m.scrollStart = start
+}
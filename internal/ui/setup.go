@@ -0,0 +1,163 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// sourcePrompt is the path being typed to add a history source
+type sourcePrompt struct {
+	value string
+	err   string
+}
+
+// SetSourcesHandler sets how sources chosen from the empty history list
+// are saved
+func (m *Model) SetSourcesHandler(save OnboardingFunc) {
+	m.sourcesSave = save
+}
+
+// needsSources reports whether no history was read at all, so the list
+// shows how to add sources instead of being empty
+func (m *Model) needsSources() bool {
+	return m.mode == HistoryMode && len(m.commands) == 0 && len(m.hiddenSources) == 0 && m.sourcesSave != nil
+}
+
+// sourceStatus describes why a configured source gave no commands
+func sourceStatus(path string) string {
+	// Remote sources are only checked by reading them
+	if history.IsRemote(path) {
+		return "no commands"
+	}
+	if history.IsGlob(path) {
+		if len(history.ExpandSources([]string{path})) == 0 {
+			return "no matching files"
+		}
+		return "no commands"
+	}
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		return "not found"
+	case err != nil:
+		return err.Error()
+	case info.IsDir():
+		return "is a directory"
+	case info.Size() == 0:
+		return "empty"
+	}
+	return "no commands"
+}
+
+// detectSourcesAgain opens the setup form with the history files found now
+func (m *Model) detectSourcesAgain() {
+	m.StartOnboarding(history.DetectSources(), m.sourcesSave)
+}
+
+// handleSourcePromptKeys edits the path of the source being added
+func (m Model) handleSourcePromptKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+	case "esc":
+		m.sourcePrompt = nil
+		return m, nil
+	case "enter":
+		return m, m.addSource()
+	}
+
+	if value, ok := editText(m.sourcePrompt.value, msg); ok {
+		m.sourcePrompt.value = value
+		m.sourcePrompt.err = ""
+	}
+	return m, nil
+}
+
+// addSource checks that the typed file can be read as history, saves it to
+// the config and re-reads history
+func (m *Model) addSource() tea.Cmd {
+	prompt := m.sourcePrompt
+	path := strings.TrimSpace(prompt.value)
+	if strings.HasPrefix(path, "~/") {
+		homeDir, _ := os.UserHomeDir()
+		path = filepath.Join(homeDir, path[2:])
+	}
+	if path == "" {
+		prompt.err = "enter the path of a history file"
+		return nil
+	}
+	if abs, err := filepath.Abs(path); err == nil && !history.IsRemote(path) {
+		path = abs
+	}
+
+	if status := sourceStatus(path); status != "no commands" {
+		prompt.err = fmt.Sprintf("%s: %s", path, status)
+		return nil
+	}
+	reader := history.NewReader([]string{path})
+	commands, err := reader.ReadHistory()
+	if skipped := reader.Skipped(); err == nil && len(skipped) > 0 {
+		err = skipped[0]
+	}
+	if err != nil {
+		prompt.err = err.Error()
+		return nil
+	}
+	if len(commands) == 0 {
+		prompt.err = fmt.Sprintf("no commands found in %s", path)
+		return nil
+	}
+
+	sources := m.config.Sources
+	if !slices.Contains(sources, path) {
+		sources = append(slices.Clone(sources), path)
+	}
+	if err := m.sourcesSave(sources); err != nil {
+		prompt.err = fmt.Sprintf("failed to save: %v", err)
+		return nil
+	}
+	m.sourcePrompt = nil
+	m.setStatus(fmt.Sprintf("Added %s to sources in %s", path, m.config.Path()))
+
+	// Re-read quietly so the status keeps confirming the new source
+	return m.startRefresh(true)
+}
+
+// renderSourcesGuide renders the empty history list with the sources that
+// were checked and how to add one
+func (m Model) renderSourcesGuide() string {
+	muted := m.theme.normalItemStyle.Foreground(m.theme.colors.muted)
+	lines := []string{"No command history found. Checked:", ""}
+	if len(m.config.Sources) == 0 {
+		lines = append(lines, muted.Render("  (no sources configured)"))
+	}
+	for _, source := range m.config.Sources {
+		lines = append(lines, fmt.Sprintf("  %s%s %s", m.theme.glyphs.fail, source, muted.Render("("+sourceStatus(source)+")")))
+	}
+	lines = append(lines, "",
+		"d  Detect history files again",
+		"a  Add a history file by path",
+		"?  Help",
+	)
+	return strings.Join(lines, "\n")
+}
+
+// renderSourcePrompt renders the path prompt for adding a source
+func (m Model) renderSourcePrompt() string {
+	prompt := m.sourcePrompt
+	lines := []string{
+		m.theme.searchStyle.Render("History file: ") + prompt.value + m.theme.glyphs.cursor,
+		m.theme.footerStyle.Render("enter: add to sources | esc: cancel"),
+	}
+	if prompt.err != "" {
+		lines = append(lines, m.theme.errorStyle.Render("Error: "+prompt.err))
+	}
+	return strings.Join(lines, "\n")
+}
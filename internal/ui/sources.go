@@ -0,0 +1,123 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// sourcePicker is the checklist of history files opened with S
+type sourcePicker struct {
+	cursor int
+}
+
+// sourceFiles returns the configured history files, with glob patterns
+// expanded to the files they match
+func (m Model) sourceFiles() []string {
+	return history.ExpandSources(m.config.Sources)
+}
+
+// openSourcePicker shows the checklist of configured history files
+func (m *Model) openSourcePicker() {
+	if len(m.sourceFiles()) == 0 {
+		m.setError("No history sources configured")
+		return
+	}
+	m.sourcePicker = &sourcePicker{}
+}
+
+// handleSourcePickerKeys toggles sources in the checklist. Changes apply
+// immediately and last until quit.
+func (m Model) handleSourcePickerKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	picker := m.sourcePicker
+
+	switch msg.String() {
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+
+	case "esc", "S", "q":
+		m.sourcePicker = nil
+
+	case "up", "k":
+		if picker.cursor > 0 {
+			picker.cursor--
+		}
+
+	case "down", "j":
+		if picker.cursor < len(m.sourceFiles())-1 {
+			picker.cursor++
+		}
+
+	case " ", "enter", "x":
+		files := m.sourceFiles()
+		if picker.cursor >= len(files) {
+			return m, nil
+		}
+		source := files[picker.cursor]
+		if m.hiddenSources == nil {
+			m.hiddenSources = make(map[string]bool)
+		}
+		if m.hiddenSources[source] {
+			delete(m.hiddenSources, source)
+		} else {
+			m.hiddenSources[source] = true
+		}
+		selected := m.getCurrentItem()
+		m.loadCommands()
+		m.selectItem(selected)
+	}
+	return m, nil
+}
+
+// filterHiddenSources drops commands read from hidden sources
+func (m *Model) filterHiddenSources(commands []history.Command) []history.Command {
+	if len(m.hiddenSources) == 0 {
+		return commands
+	}
+
+	var filtered []history.Command
+	for _, cmd := range commands {
+		if !m.hiddenSources[cmd.Source] {
+			filtered = append(filtered, cmd)
+		}
+	}
+	return filtered
+}
+
+// shownSources returns how many configured sources are not hidden
+func (m Model) shownSources() int {
+	shown := 0
+	for _, source := range m.sourceFiles() {
+		if !m.hiddenSources[source] {
+			shown++
+		}
+	}
+	return shown
+}
+
+// renderSourcePicker renders the source checklist with command counts
+func (m Model) renderSourcePicker() string {
+	counts := make(map[string]int)
+	for _, cmd := range m.commands {
+		counts[cmd.Source]++
+	}
+
+	lines := []string{"Show commands from:", ""}
+	for i, source := range m.sourceFiles() {
+		box := "[x]"
+		if m.hiddenSources[source] {
+			box = "[ ]"
+		}
+		line := fmt.Sprintf("%s %s (%s commands)", box, source, formatCount(counts[source]))
+		if i == m.sourcePicker.cursor {
+			lines = append(lines, m.selectedStyle().Render(m.theme.glyphs.selected+line))
+		} else {
+			lines = append(lines, m.theme.normalItemStyle.Render("  "+line))
+		}
+	}
+	lines = append(lines, "", m.theme.footerStyle.Render("space: toggle | esc: close (hidden sources come back on quit)"))
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,136 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/4ndew/terminal-history-navigator/internal/suggest"
+	"github.com/4ndew/terminal-history-navigator/internal/templates"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TemplateSaveFunc saves a template accepted from the suggestions
+type TemplateSaveFunc func(template templates.Template) error
+
+// maxTemplateSuggestions is how many suggestions the list shows
+const maxTemplateSuggestions = 10
+
+// templateSuggestions is the list of templates mined from history, opened
+// with i in templates mode
+type templateSuggestions struct {
+	items  []suggest.Suggestion
+	cursor int
+}
+
+// SetTemplateSaver sets how templates accepted from the suggestions are saved
+func (m *Model) SetTemplateSaver(save TemplateSaveFunc) {
+	m.templateSave = save
+}
+
+// openTemplateSuggestions lists templates for repeated command patterns
+// that aren't templates already
+func (m *Model) openTemplateSuggestions() {
+	if m.templateSave == nil {
+		m.setError("Saving templates is not available")
+		return
+	}
+
+	existing := make(map[string]bool, len(m.templates))
+	for _, template := range m.templates {
+		existing[template.Command] = true
+	}
+	var items []suggest.Suggestion
+	for _, suggestion := range suggest.Templates(m.storage.GetAll(), 0) {
+		if !existing[suggestion.Command] {
+			items = append(items, suggestion)
+		}
+		if len(items) == maxTemplateSuggestions {
+			break
+		}
+	}
+
+	if len(items) == 0 {
+		m.setStatus(fmt.Sprintf("No template suggestions: no command pattern was used %d+ times with %d+ variations", suggest.MinUses, suggest.MinVariants))
+		return
+	}
+	m.templateSuggest = &templateSuggestions{items: items}
+}
+
+// handleTemplateSuggestionKeys moves through the suggestions and saves the
+// accepted one
+func (m Model) handleTemplateSuggestionKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	list := m.templateSuggest
+
+	switch msg.String() {
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+
+	case "esc", "i", "q":
+		m.templateSuggest = nil
+
+	case "up", "k":
+		if list.cursor > 0 {
+			list.cursor--
+		}
+
+	case "down", "j":
+		if list.cursor < len(list.items)-1 {
+			list.cursor++
+		}
+
+	case "enter":
+		m.acceptTemplateSuggestion()
+	}
+	return m, nil
+}
+
+// acceptTemplateSuggestion saves the selected suggestion as a template and
+// removes it from the list
+func (m *Model) acceptTemplateSuggestion() {
+	list := m.templateSuggest
+	suggestion := list.items[list.cursor]
+	template := templates.Template{
+		Name:        suggestion.Name,
+		Command:     suggestion.Command,
+		Description: suggestion.Describe(),
+		Category:    suggestion.Program,
+	}
+	if err := m.templateSave(template); err != nil {
+		m.setError(fmt.Sprintf("Failed to save template: %v", err))
+		return
+	}
+
+	m.templates = append(m.templates, template)
+	templates.Sort(m.templates)
+	m.setStatus(fmt.Sprintf("Added template: %s", suggestion.Command))
+
+	list.items = append(list.items[:list.cursor], list.items[list.cursor+1:]...)
+	if len(list.items) == 0 {
+		m.templateSuggest = nil
+		return
+	}
+	list.cursor = min(list.cursor, len(list.items)-1)
+}
+
+// renderTemplateSuggestions renders the suggestions with examples of the
+// selected one
+func (m Model) renderTemplateSuggestions() string {
+	list := m.templateSuggest
+	muted := m.theme.normalItemStyle.Foreground(m.theme.colors.muted)
+
+	lines := []string{"Templates suggested from your history:", ""}
+	for i, item := range list.items {
+		line := fmt.Sprintf("%s  %s", m.displayText(item.Command), muted.Render(fmt.Sprintf("%d uses, %d variants", item.Uses, item.Variants)))
+		if i != list.cursor {
+			lines = append(lines, m.theme.normalItemStyle.Render("  "+line))
+			continue
+		}
+		lines = append(lines, m.selectedStyle().Render(m.theme.glyphs.selected+line))
+		for _, example := range item.Examples {
+			lines = append(lines, muted.Render("      e.g. "+m.displayText(example)))
+		}
+	}
+	lines = append(lines, "", m.theme.footerStyle.Render("enter: add to templates | esc: close"))
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,226 @@
+package ui
+
+import (
+	"github.com/4ndew/terminal-history-navigator/internal/config"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// theme holds the colors, styles and glyphs that vary with configuration
+// and the terminal background
+type theme struct {
+	colors      palette
+	accent      lipgloss.Color
+	modeAccents map[ViewMode]lipgloss.Color
+	glyphs      glyphs
+	ascii       bool // Whether selection is marked by text alone, without borders
+
+	headerStyle       lipgloss.Style
+	selectedItemStyle lipgloss.Style
+	normalItemStyle   lipgloss.Style
+	footerStyle       lipgloss.Style
+	statusStyle       lipgloss.Style
+	errorStyle        lipgloss.Style
+	searchStyle       lipgloss.Style
+	helpStyle         lipgloss.Style
+}
+
+// palette is the set of colors for one terminal background
+type palette struct {
+	primary    lipgloss.Color
+	accent     lipgloss.Color
+	muted      lipgloss.Color
+	failure    lipgloss.Color
+	success    lipgloss.Color
+	text       lipgloss.Color
+	selectedFg lipgloss.Color
+	selectedBg lipgloss.Color
+}
+
+// darkPalette is readable on dark terminal backgrounds
+var darkPalette = palette{
+	primary:    lipgloss.Color("#00D4AA"),
+	accent:     lipgloss.Color("#F59E0B"),
+	muted:      lipgloss.Color("#6B7280"),
+	failure:    lipgloss.Color("#EF4444"),
+	success:    lipgloss.Color("#10B981"),
+	text:       lipgloss.Color("#E5E7EB"),
+	selectedFg: lipgloss.Color("#FFFFFF"),
+	selectedBg: lipgloss.Color("#4A5568"), // Менее яркий серо-синий
+}
+
+// lightPalette is readable on light terminal backgrounds
+var lightPalette = palette{
+	primary:    lipgloss.Color("#0F766E"),
+	accent:     lipgloss.Color("#B45309"),
+	muted:      lipgloss.Color("#6B7280"),
+	failure:    lipgloss.Color("#DC2626"),
+	success:    lipgloss.Color("#047857"),
+	text:       lipgloss.Color("#1F2937"),
+	selectedFg: lipgloss.Color("#111827"),
+	selectedBg: lipgloss.Color("#CBD5E1"),
+}
+
+// glyphs are the symbols drawn around items and in status lines
+type glyphs struct {
+	selected string // Prefix of the selected item
+	arrival  string // Prefix of an item new since the last refresh
+	ok       string // Command succeeded
+	fail     string // Command failed, or a source gave no commands
+	pin      string // Command is pinned
+	ellipsis string // Marks cut text
+	cursor   string // Text cursor in prompts
+	sep      string // Separates details, e.g. in the header badge
+	dash     string // Time ranges
+	atLeast  string
+	upDown   string // Navigation keys in hints
+	up       string
+	down     string
+}
+
+// unicodeGlyphs are the default glyphs
+var unicodeGlyphs = glyphs{
+	selected: "► ",
+	arrival:  "• ",
+	ok:       "✓ ",
+	fail:     "✗ ",
+	pin:      "★ ",
+	ellipsis: "…",
+	cursor:   "█",
+	sep:      " · ",
+	dash:     "–",
+	atLeast:  "≥",
+	upDown:   "↑↓",
+	up:       "↑",
+	down:     "↓",
+}
+
+// asciiGlyphs replace every glyph with ASCII text for screen readers and
+// terminals without Unicode fonts
+var asciiGlyphs = glyphs{
+	selected: "> ",
+	arrival:  "* ",
+	ok:       "[ok] ",
+	fail:     "[fail] ",
+	pin:      "[pin] ",
+	ellipsis: "...",
+	cursor:   "_",
+	sep:      " - ",
+	dash:     "-",
+	atLeast:  ">=",
+	upDown:   "up/down",
+	up:       "up",
+	down:     "down",
+}
+
+// asciiBorder draws boxes with ASCII characters
+var asciiBorder = lipgloss.Border{
+	Top:         "-",
+	Bottom:      "-",
+	Left:        "|",
+	Right:       "|",
+	TopLeft:     "+",
+	TopRight:    "+",
+	BottomLeft:  "+",
+	BottomRight: "+",
+}
+
+// newTheme builds the theme from ui.theme and ui.mode_colors. Unknown modes
+// and invalid colors were already dropped by config validation.
+func newTheme(cfg *config.Config) theme {
+	colors := darkPalette
+	if !darkTheme(cfg.UI.Theme) {
+		colors = lightPalette
+	}
+
+	t := theme{
+		colors:      colors,
+		accent:      colors.primary,
+		modeAccents: make(map[ViewMode]lipgloss.Color),
+		glyphs:      unicodeGlyphs,
+
+		headerStyle: lipgloss.NewStyle().
+			Foreground(colors.primary).
+			Bold(true),
+		selectedItemStyle: lipgloss.NewStyle().
+			Foreground(colors.selectedFg).
+			Background(colors.selectedBg).
+			Padding(0, 1),
+		normalItemStyle: lipgloss.NewStyle().
+			Foreground(colors.text),
+		footerStyle: lipgloss.NewStyle().
+			Foreground(colors.muted).
+			Italic(true),
+		statusStyle: lipgloss.NewStyle().
+			Foreground(colors.primary).
+			Bold(true),
+		errorStyle: lipgloss.NewStyle().
+			Foreground(colors.failure).
+			Bold(true),
+		searchStyle: lipgloss.NewStyle().
+			Foreground(colors.accent).
+			Bold(true),
+		helpStyle: lipgloss.NewStyle().
+			Foreground(colors.muted).
+			Border(lipgloss.RoundedBorder()).
+			Padding(1).
+			Margin(1),
+	}
+	if cfg.UI.ASCIIOnly {
+		t.glyphs = asciiGlyphs
+		t.ascii = true
+	}
+	for mode := HistoryMode; mode <= SuggestionsMode; mode++ {
+		if color, ok := cfg.UI.ModeColors[mode.String()]; ok {
+			t.modeAccents[mode] = lipgloss.Color(color)
+		}
+	}
+	return t
+}
+
+// darkTheme reports whether the dark palette should be used. With "auto"
+// the terminal is asked for its background color; terminals that don't
+// answer, and output that isn't a terminal, count as dark.
+func darkTheme(name string) bool {
+	switch name {
+	case "light":
+		return false
+	case "auto":
+		return lipgloss.HasDarkBackground()
+	default:
+		return true
+	}
+}
+
+// accentFor returns the accent color of a mode, or the global accent if
+// the mode has none
+func (t theme) accentFor(mode ViewMode) lipgloss.Color {
+	if color, ok := t.modeAccents[mode]; ok {
+		return color
+	}
+	return t.accent
+}
+
+// badgeStyle returns the style of the header's mode badge
+func (m Model) badgeStyle() lipgloss.Style {
+	return m.theme.searchStyle.Copy().Foreground(m.theme.accentFor(m.mode))
+}
+
+// selectedStyle returns the selection highlight, with a left border in the
+// mode's accent color. In ASCII mode the selected item's prefix marks it
+// instead of a border.
+func (m Model) selectedStyle() lipgloss.Style {
+	if m.theme.ascii {
+		return m.theme.selectedItemStyle.Copy()
+	}
+	return m.theme.selectedItemStyle.Copy().
+		Border(lipgloss.ThickBorder(), false, false, false, true).
+		BorderForeground(m.theme.accentFor(m.mode))
+}
+
+// helpBoxStyle returns the style of the help screen's box
+func (m Model) helpBoxStyle() lipgloss.Style {
+	if m.theme.ascii {
+		return m.theme.helpStyle.Copy().Border(asciiBorder)
+	}
+	return m.theme.helpStyle
+}
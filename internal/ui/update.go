@@ -2,13 +2,26 @@ package ui
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/4ndew/terminal-history-navigator/internal/shell"
 	"github.com/4ndew/terminal-history-navigator/pkg/clipboard"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 // Update handles messages and updates the model state
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	updated, cmd := m.update(msg)
+	if model, ok := updated.(Model); ok {
+		model.scrollToCursor()
+		return model, cmd
+	}
+	return updated, cmd
+}
+
+// update dispatches a message to its handler
+func (m Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.resize(msg.Width, msg.Height)
@@ -16,17 +29,107 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case tea.KeyMsg:
 		return m.handleKeyPress(msg)
+
+	case clipboardClearMsg:
+		return m.handleClipboardClear(msg)
+
+	case clipboardClearedMsg:
+		return m.handleClipboardCleared(msg)
+
+	case refreshTickMsg:
+		return m.handleRefreshTick()
+
+	case sourceChangedMsg:
+		return m.handleSourceChanged()
+
+	case refreshDoneMsg:
+		return m.handleRefreshDone(msg)
+
+	case arrivalsExpiredMsg:
+		return m.handleArrivalsExpired()
+
+	case editorDoneMsg:
+		return m.handleEditorDone(msg)
+
+	case manDoneMsg:
+		return m.handleManDone(msg)
 	}
 
 	return m, nil
 }
 
+// clipboardClearMsg is sent every second while a clipboard clear is pending
+type clipboardClearMsg struct {
+	id int
+}
+
+// clipboardClearTick schedules the next clipboard clear countdown tick
+func clipboardClearTick(id int) tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return clipboardClearMsg{id: id}
+	})
+}
+
+// handleClipboardClear updates the countdown and clears the clipboard once
+// the delay has passed
+func (m Model) handleClipboardClear(msg clipboardClearMsg) (tea.Model, tea.Cmd) {
+	// Ignore ticks from a clear that was replaced by a later copy
+	if msg.id != m.clearID || m.clearText == "" {
+		return m, nil
+	}
+
+	remaining := time.Until(m.clearDeadline).Round(time.Second)
+	if remaining > 0 {
+		// Only update the countdown while the copy message is still shown
+		if strings.HasPrefix(m.statusMsg, m.clearStatus) {
+			m.setStatus(fmt.Sprintf("%s (clipboard clears in %s)", m.clearStatus, remaining))
+		}
+		return m, clipboardClearTick(m.clearID)
+	}
+
+	text := m.clearText
+	m.clearText = ""
+	return m, clearClipboard(m.clearBackend, text)
+}
+
+// clipboardClearedMsg carries the result of clearing the clipboard
+type clipboardClearedMsg struct {
+	cleared bool // False if the clipboard no longer held the copied text
+	err     error
+}
+
+// clearClipboard clears the clipboard through the backend that copied text,
+// if it still holds it. Clipboard utilities can take up to the clipboard
+// timeout, so this runs outside the update loop.
+func clearClipboard(backend, text string) tea.Cmd {
+	return func() tea.Msg {
+		cleared, err := clipboard.Clear(backend, text)
+		return clipboardClearedMsg{cleared: cleared, err: err}
+	}
+}
+
+// handleClipboardCleared reports the result of clearing the clipboard
+func (m Model) handleClipboardCleared(msg clipboardClearedMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case msg.err != nil:
+		m.setError(fmt.Sprintf("Failed to clear clipboard: %v", msg.err))
+	case msg.cleared:
+		m.setStatus("Clipboard cleared")
+	}
+	return m, nil
+}
+
 // handleKeyPress processes keyboard input
 func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.onboarding != nil {
+		return m.handleOnboardingKeys(msg)
+	}
+
 	// Handle help mode separately - any key closes help
 	if m.showHelp {
 		switch msg.String() {
 		case "ctrl+c", "q":
+			m.quitting = true
 			return m, tea.Quit
 		default:
 			// Any other key closes help
@@ -35,6 +138,28 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	}
 
+	if m.excludePrompt != nil {
+		return m.handleExcludeKeys(msg)
+	}
+	if m.sourcePicker != nil {
+		return m.handleSourcePickerKeys(msg)
+	}
+	if m.sourcePrompt != nil {
+		return m.handleSourcePromptKeys(msg)
+	}
+	if m.templateSuggest != nil {
+		return m.handleTemplateSuggestionKeys(msg)
+	}
+
+	// Copying a secret unmasked needs two enters in a row
+	if msg.String() != "enter" {
+		m.confirmCopy = ""
+	}
+	// Likewise deleting needs x pressed repeatedly
+	if msg.String() != "x" {
+		m.confirmDelete = nil
+	}
+
 	switch m.mode {
 	case SearchMode:
 		return m.handleSearchKeys(msg)
@@ -47,6 +172,7 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 func (m Model) handleNormalKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "ctrl+c", "q":
+		m.quitting = true
 		return m, tea.Quit
 
 	case "up", "k":
@@ -58,8 +184,32 @@ func (m Model) handleNormalKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case "enter":
+		if m.mode == SessionsMode && m.openSession < 0 {
+			m.openCurrentSession()
+			return m, nil
+		}
 		return m.handleSelectItem()
 
+	case "backspace", "left":
+		if m.mode == SessionsMode && m.openSession >= 0 {
+			m.closeSession()
+		}
+		return m, nil
+
+	case ">":
+		if m.mode != SuggestionsMode && !m.showSuggestions() {
+			m.setStatus("No commands usually follow this one")
+		}
+		return m, nil
+
+	case "s":
+		if m.mode == SessionsMode {
+			m.switchToHistoryMode()
+		} else {
+			m.switchToSessionsMode()
+		}
+		return m, nil
+
 	case "/":
 		m.switchToSearchMode()
 		return m, nil
@@ -79,24 +229,130 @@ func (m Model) handleNormalKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "f":
 		// Toggle between frequency and chronological sort
 		if m.mode == HistoryMode {
-			if m.statusMsg == "Sorted by frequency" {
-				// Switch back to chronological
-				m.filteredCmds = m.storage.GetRecent(m.config.UI.MaxItems)
-				m.cursor = 0
-				m.setStatus("Sorted chronologically (newest first)")
+			m.toggleSortMode()
+		}
+		return m, nil
+
+	case "r":
+		cmd := m.startRefresh(false)
+		if cmd != nil {
+			m.setStatus("Refreshing...")
+		}
+		return m, cmd
+
+	case "X":
+		m.startExcludePrompt()
+		return m, nil
+
+	case "P":
+		m.togglePin()
+		return m, nil
+
+	case "x":
+		m.handleDeleteKey()
+		return m, nil
+
+	case "*":
+		if m.mode == HistoryMode {
+			m.togglePinnedOnly()
+		}
+		return m, nil
+
+	case "S":
+		m.openSourcePicker()
+		return m, nil
+
+	case "p":
+		return m.handleCopyPaths()
+
+	case "i":
+		if m.mode == TemplatesMode {
+			m.openTemplateSuggestions()
+		}
+		return m, nil
+
+	case "d":
+		if m.needsSources() {
+			m.detectSourcesAgain()
+		}
+		return m, nil
+
+	case "a":
+		if m.needsSources() {
+			m.sourcePrompt = &sourcePrompt{}
+		}
+		return m, nil
+
+	case "m":
+		if m.showsCommands() {
+			return m, m.openManPage()
+		}
+		return m, nil
+
+	case "E":
+		if m.showsCommands() {
+			return m, m.openSourceLine()
+		}
+		return m, nil
+
+	case "+", "=", "-":
+		// Adjust the minimum count of the frequency list
+		if m.mode == HistoryMode && m.sortMode == SortFrequency {
+			if msg.String() == "-" {
+				m.changeMinCount(-1)
+			} else {
+				m.changeMinCount(1)
+			}
+		}
+		return m, nil
+
+	case "w":
+		// Toggle between wrapping and truncating long commands
+		m.truncateLines = !m.truncateLines
+		if m.truncateLines {
+			m.setStatus("Long commands truncated to one line")
+		} else {
+			m.setStatus("Long commands wrapped")
+		}
+		return m, nil
+
+	case "D":
+		// Toggle listing only commands run in the working directory
+		if m.dirAware && (m.mode == HistoryMode || m.mode == SearchMode) {
+			m.dirOnly = !m.dirOnly
+			m.cursor = 0
+			m.loadCommands()
+			if m.dirOnly {
+				m.setStatus("Only commands run in " + m.workingDir)
 			} else {
-				// Switch to frequency
-				freqCmds := m.storage.GetByFrequency()
-				if len(freqCmds) > m.config.UI.MaxItems {
-					freqCmds = freqCmds[:m.config.UI.MaxItems]
-				}
-				m.filteredCmds = freqCmds
-				m.cursor = 0
-				m.setStatus("Sorted by frequency")
+				m.setStatus("Commands from all directories")
 			}
 		}
 		return m, nil
 
+	case "A":
+		// Toggle listing every run instead of one entry per command
+		if m.mode == HistoryMode || m.mode == SearchMode {
+			m.everyRun = !m.everyRun
+			selected := m.getCurrentItem()
+			m.loadCommands()
+			m.selectItem(selected)
+			if m.everyRun {
+				m.setStatus("Listing every run")
+			} else {
+				m.setStatus("Listing each command once")
+			}
+		}
+		return m, nil
+
+	case "T":
+		// Cycle all time → today → last 7 days → last 30 days
+		if m.mode != TemplatesMode {
+			m.setTimeScope(m.timeScope.Next())
+			m.setStatus("Showing " + m.timeScope.String())
+		}
+		return m, nil
+
 	case "?":
 		m.showHelp = !m.showHelp
 		return m, nil
@@ -104,6 +360,17 @@ func (m Model) handleNormalKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "esc":
 		m.clearMessages()
 		m.showHelp = false
+		if m.mode == SuggestionsMode {
+			m.closeSuggestions()
+			return m, nil
+		}
+		if m.mode == SessionsMode && m.openSession >= 0 {
+			m.closeSession()
+			return m, nil
+		}
+		if m.timeScope != ScopeAll {
+			m.setTimeScope(ScopeAll)
+		}
 		return m, nil
 	}
 
@@ -114,15 +381,46 @@ func (m Model) handleNormalKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 func (m Model) handleSearchKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "ctrl+c":
+		m.quitting = true
 		return m, tea.Quit
 
 	case "esc":
-		m.exitSearchMode()
+		// The first esc clears the query, the second leaves search mode
+		if m.searchQuery != "" {
+			m.cursor = 0
+			m.setSearchQuery("")
+		} else {
+			m.exitSearchMode()
+		}
 		return m, nil
 
 	case "enter":
 		return m.handleSelectItem()
 
+	case "ctrl+f":
+		// f would be typed into the query, so use ctrl+f to re-rank matches
+		m.toggleSortMode()
+		return m, nil
+
+	case "ctrl+t":
+		// T would be typed into the query, so use ctrl+t to cycle the scope
+		m.setTimeScope(m.timeScope.Next())
+		return m, nil
+
+	case "ctrl+o":
+		// Switch between matching all query words and any of them
+		m.matchAny = !m.matchAny
+		m.cursor = 0
+		m.loadCommands()
+		return m, nil
+
+	case "ctrl+x":
+		// Switch between word prefixes and fuzzy subsequences
+		m.fuzzy = !m.fuzzy
+		m.cursor = 0
+		m.loadCommands()
+		return m, nil
+
 	case "up", "ctrl+p":
 		m.moveUp()
 		return m, nil
@@ -156,17 +454,108 @@ func (m Model) handleSelectItem() (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
-	// Copy to clipboard
-	err := clipboard.Copy(selectedText)
+	// Only part of a truncated command was kept, so confirm before using it
+	if m.mode != TemplatesMode {
+		if cmd := m.filteredCmds[m.cursor]; cmd.Truncated && m.confirmCopy != selectedText {
+			m.confirmCopy = selectedText
+			m.setError(fmt.Sprintf("Only the first part of this %s command was kept, press enter again to use it", formatSize(cmd.FullSize)))
+			return m, nil
+		}
+	}
+
+	// In picker mode the caller prints the selection after the TUI exits
+	if m.pickerMode {
+		m.selection = selectedText
+		if m.mode == TemplatesMode {
+			template := m.templates[m.cursor]
+			m.pickedTemplate = &template
+		} else {
+			cmd := m.filteredCmds[m.cursor]
+			m.pickedCommand = &cmd
+		}
+		m.outcome = OutcomeSelected
+		m.quitting = true
+		return m, tea.Quit
+	}
+
+	// With redaction on, copy the masked command or ask before copying the secret
+	shownText := m.displayText(selectedText)
+	if m.redactor.Contains(selectedText) {
+		if m.config.Security.CopyRedacted {
+			selectedText = shownText
+		} else if m.confirmCopy != selectedText {
+			m.confirmCopy = selectedText
+			m.setError("Command contains a secret, press enter again to copy it unmasked")
+			return m, nil
+		}
+	}
+	m.confirmCopy = ""
+
+	return m, m.copyText(selectedText, shownText, "Copied")
+}
+
+// copyText copies text to the clipboard and reports it in the footer as
+// "label: shown", scheduling a clear if the text looks sensitive
+func (m *Model) copyText(text, shown, label string) tea.Cmd {
+	err := clipboard.Copy(text)
 	if err != nil {
 		m.setError(fmt.Sprintf("Failed to copy: %v", err))
+		return nil
+	}
+	m.outcome = OutcomeSelected
+
+	// Show success message, naming the destination when it isn't the system clipboard
+	backend := clipboard.LastBackend()
+	switch backend {
+	case "tmux":
+		m.setStatus(fmt.Sprintf("%s to tmux buffer: %s", label, truncateString(shown, 50)))
+	case "file":
+		m.setStatus(fmt.Sprintf("Written to file %s (not the system clipboard)", clipboard.FallbackFile()))
+	default:
+		m.setStatus(fmt.Sprintf("%s: %s", label, truncateString(shown, 50)))
+	}
+
+	// Schedule clearing the clipboard if the command looks sensitive
+	clearAfter := m.config.Clipboard.ClearAfterSeconds
+	if clearAfter > 0 && m.isSensitive(text) {
+		m.clearID++
+		m.clearText = text
+		m.clearBackend = backend
+		m.clearStatus = m.statusMsg
+		m.clearDeadline = time.Now().Add(time.Duration(clearAfter) * time.Second)
+		m.setStatus(fmt.Sprintf("%s (clipboard clears in %ds)", m.clearStatus, clearAfter))
+		return clipboardClearTick(m.clearID)
+	}
+
+	return nil
+}
+
+// handleCopyPaths copies the file paths among the selected command's
+// arguments, separated by spaces
+func (m Model) handleCopyPaths() (tea.Model, tea.Cmd) {
+	if !m.showsCommands() || m.cursor >= m.getItemCount() {
 		return m, nil
 	}
+	cmd := m.filteredCmds[m.cursor]
 
-	// Show success message
-	m.setStatus(fmt.Sprintf("Copied: %s", truncateString(selectedText, 50)))
+	paths := shell.Paths(cmd.Text, cmd.Directory)
+	if len(paths) == 0 {
+		m.setError("No file paths found in this command")
+		return m, nil
+	}
 
-	return m, nil
+	// Quote paths with spaces so the copy can be pasted as arguments
+	quoted := make([]string, len(paths))
+	for i, path := range paths {
+		quoted[i] = shell.Quote(path)
+	}
+	text := strings.Join(quoted, " ")
+
+	label := "Copied path"
+	if len(paths) > 1 {
+		label = fmt.Sprintf("Copied %d paths", len(paths))
+	}
+	return m, m.copyText(text, m.displayText(text), label)
 }
 
 // truncateString truncates a string to maxLen characters with ellipsis
@@ -176,3 +565,19 @@ func truncateString(s string, maxLen int) string {
 	}
 	return s[:maxLen-3] + "..."
 }
+
+// editText applies a typing or backspace key to a prompt's value. It
+// returns false for keys that don't edit text.
+func editText(value string, msg tea.KeyMsg) (string, bool) {
+	switch {
+	case msg.Type == tea.KeyBackspace:
+		runes := []rune(value)
+		if len(runes) > 0 {
+			runes = runes[:len(runes)-1]
+		}
+		return string(runes), true
+	case msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace:
+		return value + string(msg.Runes), true
+	}
+	return value, false
+}
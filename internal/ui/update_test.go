@@ -0,0 +1,222 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/4ndew/terminal-history-navigator/internal/config"
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+	"github.com/4ndew/terminal-history-navigator/internal/redact"
+	"github.com/4ndew/terminal-history-navigator/internal/storage"
+	"github.com/4ndew/terminal-history-navigator/pkg/clipboard"
+)
+
+// useFileClipboard makes the clipboard a file for the rest of the test
+func useFileClipboard(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "clipboard.txt")
+	clipboard.SetFallbackFile(path)
+	if err := clipboard.SetBackend("file"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		clipboard.SetFallbackFile("")
+		clipboard.SetBackend("auto")
+	})
+	return path
+}
+
+func TestClearClipboard(t *testing.T) {
+	path := useFileClipboard(t)
+	if err := clipboard.Copy("export TOKEN=abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := clearClipboard("file", "export TOKEN=abc")()
+	if cleared, ok := msg.(clipboardClearedMsg); !ok || !cleared.cleared || cleared.err != nil {
+		t.Fatalf("clearing the copied text = %+v, want cleared", msg)
+	}
+	if data, err := os.ReadFile(path); err != nil || len(data) != 0 {
+		t.Errorf("clipboard after clearing = %q, %v; want empty", data, err)
+	}
+}
+
+// TestClearClipboardKeepsNewerCopy checks text copied after the sensitive
+// command isn't cleared
+func TestClearClipboardKeepsNewerCopy(t *testing.T) {
+	path := useFileClipboard(t)
+	if err := clipboard.Copy("git status"); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := clearClipboard("file", "export TOKEN=abc")()
+	if cleared, ok := msg.(clipboardClearedMsg); !ok || cleared.cleared || cleared.err != nil {
+		t.Fatalf("clearing after another copy = %+v, want nothing done", msg)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "git status" {
+		t.Errorf("clipboard = %q, %v; want the newer copy", data, err)
+	}
+}
+
+// press sends keys to the model in order, returning it and whether the
+// last key quit the program
+func press(m Model, keys ...tea.KeyMsg) (Model, bool) {
+	var cmd tea.Cmd
+	for _, key := range keys {
+		var model tea.Model
+		model, cmd = m.Update(key)
+		m = model.(Model)
+	}
+	if cmd == nil {
+		return m, false
+	}
+	_, quit := cmd().(tea.QuitMsg)
+	return m, quit
+}
+
+// runes returns a key press for each character of text, as if typed
+func runes(text string) []tea.KeyMsg {
+	var keys []tea.KeyMsg
+	for _, r := range text {
+		keys = append(keys, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	return keys
+}
+
+// TestOutcome checks the model records whether a command was selected
+// before quitting, for main to pick the exit code
+func TestOutcome(t *testing.T) {
+	var (
+		enter = tea.KeyMsg{Type: tea.KeyEnter}
+		down  = tea.KeyMsg{Type: tea.KeyDown}
+		esc   = tea.KeyMsg{Type: tea.KeyEsc}
+		ctrlC = tea.KeyMsg{Type: tea.KeyCtrlC}
+		q     = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")}
+		slash = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")}
+	)
+	tests := []struct {
+		name string
+		keys []tea.KeyMsg
+		want Outcome
+	}{
+		{"enter", []tea.KeyMsg{down, enter}, OutcomeSelected},
+		{"q", []tea.KeyMsg{down, q}, OutcomeCancelled},
+		{"ctrl+c", []tea.KeyMsg{ctrlC}, OutcomeCancelled},
+		{"ctrl+c in search", []tea.KeyMsg{slash, ctrlC}, OutcomeCancelled},
+		{"esc then q", []tea.KeyMsg{slash, esc, esc, q}, OutcomeCancelled},
+	}
+	for _, tt := range tests {
+		m := newTestModel(func(*config.Config) {})
+		m.SetPickerMode(true)
+		m, quit := press(m, tt.keys...)
+		if !quit || m.Outcome() != tt.want {
+			t.Errorf("%s: quit %v with outcome %v, want quit with %v", tt.name, quit, m.Outcome(), tt.want)
+		}
+		if tt.want == OutcomeSelected && m.Selection() != "make test" {
+			t.Errorf("%s: selection = %q, want make test", tt.name, m.Selection())
+		}
+		if tt.want == OutcomeCancelled && m.Selection() != "" {
+			t.Errorf("%s: selection = %q, want none", tt.name, m.Selection())
+		}
+	}
+}
+
+// TestOutcomeAfterCopy checks copying outside picker mode counts as a
+// selection once the user quits
+func TestOutcomeAfterCopy(t *testing.T) {
+	useFileClipboard(t)
+	m := newTestModel(func(*config.Config) {})
+
+	m, quit := press(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if quit || m.Outcome() != OutcomeSelected {
+		t.Fatalf("after copying quit %v with outcome %v, want no quit and selected", quit, m.Outcome())
+	}
+	m, quit = press(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	if !quit || m.Outcome() != OutcomeSelected {
+		t.Errorf("quit %v with outcome %v after copying, want selected", quit, m.Outcome())
+	}
+}
+
+// TestRedactedCopy checks a command with a secret is shown masked, and
+// copied masked or only after a second enter depending on the config
+func TestRedactedCopy(t *testing.T) {
+	const secret = "export API_KEY=abc123"
+	masked := "export API_KEY=" + redact.Mask
+
+	for _, copyRedacted := range []bool{true, false} {
+		path := useFileClipboard(t)
+		store := storage.NewMemoryStorage()
+		store.Store([]history.Command{{Text: secret, Count: 1}})
+		cfg := config.DefaultConfig()
+		cfg.Security.Redact = true
+		cfg.Security.CopyRedacted = copyRedacted
+		m := NewModel(store, nil, cfg, nil)
+
+		if view := m.View(); strings.Contains(view, "abc123") || !strings.Contains(view, masked) {
+			t.Errorf("list shows the secret:\n%s", view)
+		}
+
+		m, _ = press(m, tea.KeyMsg{Type: tea.KeyEnter})
+		want := masked
+		if !copyRedacted {
+			if data, _ := os.ReadFile(path); len(data) != 0 {
+				t.Errorf("copied %q on the first enter, want a warning first", data)
+			}
+			m, _ = press(m, tea.KeyMsg{Type: tea.KeyEnter})
+			want = secret
+		}
+		if data, err := os.ReadFile(path); err != nil || string(data) != want {
+			t.Errorf("copy_redacted %t: copied %q, %v; want %q", copyRedacted, data, err, want)
+		}
+		if strings.Contains(m.View(), "abc123") {
+			t.Errorf("copy_redacted %t: copy confirmation shows the secret:\n%s", copyRedacted, m.View())
+		}
+	}
+}
+
+// TestTruncatedCopy checks a truncated command shows its full size and
+// needs a second enter to copy
+func TestTruncatedCopy(t *testing.T) {
+	path := useFileClipboard(t)
+	store := storage.NewMemoryStorage()
+	store.Store([]history.Command{{Text: "psql -c 'INSERT", Count: 1, Truncated: true, FullSize: 204800}})
+	m := NewModel(store, nil, config.DefaultConfig(), nil)
+
+	if view := m.View(); !strings.Contains(view, "(truncated, 200KB)") {
+		t.Errorf("list doesn't mark the truncated command:\n%s", view)
+	}
+	m, _ = press(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if data, _ := os.ReadFile(path); len(data) != 0 {
+		t.Errorf("copied %q on the first enter, want a warning first", data)
+	}
+	press(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if data, err := os.ReadFile(path); err != nil || string(data) != "psql -c 'INSERT" {
+		t.Errorf("copied %q, %v after confirming, want the truncated text", data, err)
+	}
+}
+
+// TestMatchAnyToggle checks ctrl+o in search mode switches to matching any
+// query word, and the header says so
+func TestMatchAnyToggle(t *testing.T) {
+	m := newTestModel(func(cfg *config.Config) {
+		cfg.UI.StartMode = "search"
+		cfg.UI.StartQuery = "make push"
+	})
+	if len(m.filteredCmds) != 0 || strings.Contains(m.View(), "any word") {
+		t.Fatalf("matching all words lists %d commands", len(m.filteredCmds))
+	}
+
+	m, _ = press(m, tea.KeyMsg{Type: tea.KeyCtrlO})
+	if got := len(m.filteredCmds); got != 2 || !strings.Contains(m.View(), "any word") {
+		t.Errorf("matching any word lists %d commands, want 2 with the header saying so:\n%s", got, m.View())
+	}
+
+	m, _ = press(m, tea.KeyMsg{Type: tea.KeyCtrlO})
+	if len(m.filteredCmds) != 0 {
+		t.Errorf("toggling back lists %d commands, want 0", len(m.filteredCmds))
+	}
+}
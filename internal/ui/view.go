@@ -2,66 +2,31 @@ package ui
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+	"github.com/4ndew/terminal-history-navigator/internal/logging"
+	"github.com/4ndew/terminal-history-navigator/internal/storage"
+	"github.com/4ndew/terminal-history-navigator/internal/version"
 	"github.com/charmbracelet/lipgloss"
-)
-
-// Styles
-var (
-	// Colors
-	primaryColor = lipgloss.Color("#00D4AA")
-	accentColor  = lipgloss.Color("#F59E0B")
-	mutedColor   = lipgloss.Color("#6B7280")
-	errorColor   = lipgloss.Color("#EF4444")
-	successColor = lipgloss.Color("#10B981")
-
-	// Base styles
-	baseStyle = lipgloss.NewStyle().
-			Padding(1, 2)
-
-	// Header styles
-	headerStyle = lipgloss.NewStyle().
-			Foreground(primaryColor).
-			Bold(true)
-
-	// Item styles - менее яркий цвет выделения
-	selectedItemStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("#FFFFFF")).
-				Background(lipgloss.Color("#4A5568")). // Менее яркий серо-синий
-				Padding(0, 1)
-
-	normalItemStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#E5E7EB"))
-
-	// Footer styles
-	footerStyle = lipgloss.NewStyle().
-			Foreground(mutedColor).
-			Italic(true)
-
-	statusStyle = lipgloss.NewStyle().
-			Foreground(primaryColor).
-			Bold(true)
-
-	errorStyle = lipgloss.NewStyle().
-			Foreground(errorColor).
-			Bold(true)
-
-	// Search styles
-	searchStyle = lipgloss.NewStyle().
-			Foreground(accentColor).
-			Bold(true)
-
-	// Help styles
-	helpStyle = lipgloss.NewStyle().
-			Foreground(mutedColor).
-			Border(lipgloss.RoundedBorder()).
-			Padding(1).
-			Margin(1)
+	"github.com/mattn/go-runewidth"
 )
 
 // View renders the TUI interface
 func (m Model) View() string {
+	// A blank last frame erases the inline view from the terminal
+	if m.quitting {
+		return ""
+	}
+	if m.inline {
+		return m.inlineView()
+	}
+	if m.onboarding != nil {
+		return m.renderOnboarding()
+	}
 	if m.showHelp {
 		return m.renderHelp()
 	}
@@ -72,19 +37,32 @@ func (m Model) View() string {
 	sections = append(sections, m.renderHeader())
 	sections = append(sections, "") // Empty line for separation
 
-	// Main content
-	sections = append(sections, m.renderMainContent())
+	// Main content, replaced by the source checklist or template
+	// suggestions while they are open
+	if m.sourcePicker != nil {
+		sections = append(sections, m.renderSourcePicker())
+	} else if m.templateSuggest != nil {
+		sections = append(sections, m.renderTemplateSuggestions())
+	} else {
+		sections = append(sections, m.renderMainContent())
+	}
 
-	// Footer
+	// Footer, replaced by the prompt while adding an exclude pattern or source
 	sections = append(sections, "") // Empty line before footer
-	sections = append(sections, m.renderFooter())
+	if m.excludePrompt != nil {
+		sections = append(sections, m.renderExcludePrompt())
+	} else if m.sourcePrompt != nil {
+		sections = append(sections, m.renderSourcePrompt())
+	} else {
+		sections = append(sections, m.renderFooter())
+	}
 
 	return strings.Join(sections, "\n")
 }
 
 // renderHeader renders the application header - always visible in all modes
 func (m Model) renderHeader() string {
-	title := headerStyle.Render("Terminal History Navigator")
+	title := m.theme.headerStyle.Render("Terminal History Navigator")
 
 	var modeStr string
 	switch m.mode {
@@ -98,44 +76,78 @@ func (m Model) renderHeader() string {
 		} else {
 			modeStr = fmt.Sprintf("Search: %s", m.searchQuery)
 		}
+	case SessionsMode:
+		if m.openSession >= 0 {
+			modeStr = "Session: " + formatSession(m.sessions[m.openSession], m.theme.glyphs)
+		} else {
+			modeStr = "Sessions"
+		}
+	case SuggestionsMode:
+		modeStr = "Usually next after: " + truncateString(m.displayText(m.suggestFor), 40)
+	}
+	if m.mode == SearchMode {
+		if query := storage.ParseQuery(m.effectiveQuery()); len(query.Groups) > 1 {
+			modeStr += m.theme.glyphs.sep + "any word"
+		} else if len(query.Groups) == 1 && len(query.Groups[0]) > 1 {
+			modeStr += m.theme.glyphs.sep + "all words"
+		}
+		if m.fuzzy {
+			modeStr += m.theme.glyphs.sep + "fuzzy"
+		}
+	}
+	if m.mode == HistoryMode && m.pinnedOnly && m.searchQuery == "" {
+		modeStr += m.theme.glyphs.sep + "pinned"
+	}
+	if (m.mode == HistoryMode || m.mode == SearchMode) && m.sortMode == SortFrequency {
+		modeStr += m.theme.glyphs.sep + "frequency"
+	}
+	if (m.mode == HistoryMode || m.mode == SearchMode) && m.dirAware {
+		if m.dirOnly {
+			modeStr += m.theme.glyphs.sep + "this dir only"
+		} else {
+			modeStr += m.theme.glyphs.sep + "dir-aware"
+		}
+	}
+	if m.mode != TemplatesMode && m.timeScope != ScopeAll {
+		modeStr += m.theme.glyphs.sep + m.timeScope.String()
 	}
 
-	modeDisplay := searchStyle.Render(fmt.Sprintf("[%s]", modeStr))
+	modeDisplay := m.badgeStyle().Render(fmt.Sprintf("[%s]", modeStr))
 	return title + " " + modeDisplay
 }
 
 // renderMainContent renders the main content area with improved scrolling for multiline items
 func (m Model) renderMainContent() string {
-	items, selectedIndex := m.getVisibleItems()
-
-	if len(items) == 0 {
+	itemCount := m.getItemCount()
+	if itemCount == 0 {
 		return m.renderEmptyState()
 	}
 
-	// Calculate available space for items (subtract header, separators, footer)
-	maxVisibleLines := m.height - 6 // Header(1) + separator(1) + separator(1) + footer(3)
-	if maxVisibleLines < 3 {
-		maxVisibleLines = 3
-	}
-
-	// Pre-calculate how many lines each item will take
-	itemHeights := make([]int, len(items))
-	totalLines := 0
+	start, end := m.scrollWindow(itemCount)
+	return m.renderItemsRange(start, end)
+}
 
-	for i, item := range items {
-		height := m.calculateItemHeight(item, i == selectedIndex)
-		itemHeights[i] = height
-		totalLines += height
+// scrollWindow returns the range of items shown, placed as ui.scroll says
+func (m Model) scrollWindow(itemCount int) (int, int) {
+	maxVisibleLines := m.maxVisibleLines()
+	if m.config.UI.Scroll == "edge" {
+		return m.edgeScrollWindow(itemCount, maxVisibleLines)
 	}
+	return m.calculateScrollWindow(itemCount, maxVisibleLines)
+}
 
-	// If all items fit, show them all
-	if totalLines <= maxVisibleLines {
-		return m.renderItemsRange(items, 0, len(items), selectedIndex, itemHeights)
+// maxVisibleLines returns the number of lines available for items
+func (m Model) maxVisibleLines() int {
+	if m.inline {
+		return max(m.height-2, 1) // Header(1) + footer(1)
 	}
 
-	// Calculate scroll window considering item heights
-	start, end := m.calculateScrollWindowForMultiline(items, itemHeights, selectedIndex, maxVisibleLines)
-	return m.renderItemsRange(items, start, end, selectedIndex, itemHeights)
+	// Subtract header, separators and footer
+	lines := m.height - 6 // Header(1) + separator(1) + separator(1) + footer(3)
+	if lines < 3 {
+		lines = 3
+	}
+	return lines
 }
 
 // calculateItemHeight calculates how many lines an item will occupy
@@ -147,15 +159,19 @@ func (m Model) calculateItemHeight(item string, isSelected bool) int {
 
 	var prefix string
 	if isSelected {
-		prefix = "► "
+		prefix = m.theme.glyphs.selected
 	} else {
 		prefix = "  "
 	}
 
+	if m.truncates(isSelected) {
+		return 1
+	}
+
 	// Add status indicator space (approximate)
-	statusIndicatorSpace := 2 // "✓ " or "✗ " or empty
+	statusIndicatorSpace := runewidth.StringWidth(m.theme.glyphs.fail) + m.timestampColumnWidth() // Exit status glyph or empty, after the timestamp
 
-	availableForText := maxWidth - len(prefix) - statusIndicatorSpace
+	availableForText := maxWidth - runewidth.StringWidth(prefix) - statusIndicatorSpace
 	if availableForText < 10 {
 		availableForText = 10
 	}
@@ -170,85 +186,106 @@ func (m Model) calculateItemHeight(item string, isSelected bool) int {
 	return len(lines)
 }
 
-// calculateScrollWindowForMultiline calculates scroll window considering multiline items
-func (m Model) calculateScrollWindowForMultiline(items []string, itemHeights []int, selectedIndex, maxVisibleLines int) (int, int) {
-	if selectedIndex < 0 || selectedIndex >= len(items) {
-		return 0, len(items)
+// calculateScrollWindow returns the range of items to show so the selected
+// item is visible, keeping up to a third of the window above it. Only items
+// near the selection are measured, so long lists stay fast.
+func (m Model) calculateScrollWindow(itemCount, maxVisibleLines int) (int, int) {
+	selected := m.cursor
+	if selected < 0 || selected >= itemCount {
+		selected = 0
 	}
 
-	// Try different start positions to find one that fits selected item in view
-	bestStart := 0
-	bestEnd := len(items)
+	height := func(i int) int {
+		return m.calculateItemHeight(m.itemText(i), i == selected)
+	}
 
-	// Start from selected item and work backwards
-	for start := selectedIndex; start >= 0; start-- {
-		currentLines := 0
-		end := start
+	start, end := selected, selected+1
+	lines := height(selected)
 
-		// Count forward from start position
-		for i := start; i < len(items) && currentLines < maxVisibleLines; i++ {
-			if currentLines+itemHeights[i] <= maxVisibleLines {
-				currentLines += itemHeights[i]
-				end = i + 1
-			} else {
-				break
-			}
+	// Add context above the selected item
+	above := 0
+	for start > 0 {
+		h := height(start - 1)
+		if lines+h > maxVisibleLines || above+h > maxVisibleLines/3 {
+			break
 		}
+		start--
+		lines += h
+		above += h
+	}
 
-		// If selected item is visible in this window
-		if selectedIndex >= start && selectedIndex < end {
-			bestStart = start
-			bestEnd = end
-
-			// If we have room and selected item is not centered, continue looking
-			selectedPosition := 0
-			for i := start; i < selectedIndex; i++ {
-				selectedPosition += itemHeights[i]
-			}
+	// Fill the rest of the window below it
+	for end < itemCount {
+		h := height(end)
+		if lines+h > maxVisibleLines {
+			break
+		}
+		end++
+		lines += h
+	}
 
-			// If selected item is reasonably centered, use this window
-			if selectedPosition >= currentLines/3 {
-				break
-			}
+	// Near the end of the list, use any space left for more items above
+	for start > 0 {
+		h := height(start - 1)
+		if lines+h > maxVisibleLines {
+			break
 		}
+		start--
+		lines += h
 	}
 
-	return bestStart, bestEnd
+	return start, end
 }
 
-// renderItemsRange renders items in the specified range with proper index mapping
-func (m Model) renderItemsRange(items []string, start, end, selectedIndex int, itemHeights []int) string {
+// renderItemsRange renders items in the specified range
+func (m Model) renderItemsRange(start, end int) string {
 	var renderedItems []string
+	now := time.Now()
 
-	for i := start; i < end && i < len(items); i++ {
-		item := items[i]
-		isSelected := (i == selectedIndex) // Используем глобальный индекс правильно
+	for i := start; i < end; i++ {
+		isSelected := i == m.cursor
 
 		// Add status indicator for commands with exit codes
 		statusIndicator := ""
-		if m.mode == HistoryMode || m.mode == SearchMode {
-			if i < len(m.filteredCmds) {
-				cmd := m.filteredCmds[i]
-				if cmd.HasExit {
-					if cmd.ExitCode == 0 {
-						statusIndicator = lipgloss.NewStyle().Foreground(successColor).Render("✓ ")
-					} else {
-						statusIndicator = lipgloss.NewStyle().Foreground(errorColor).Render("✗ ")
-					}
+		if m.showsCommands() {
+			cmd := m.filteredCmds[i]
+			if cmd.HasExit {
+				if cmd.ExitCode == 0 {
+					statusIndicator = lipgloss.NewStyle().Foreground(m.theme.colors.success).Render(m.theme.glyphs.ok)
+				} else {
+					statusIndicator = lipgloss.NewStyle().Foreground(m.theme.colors.failure).Render(m.theme.glyphs.fail)
 				}
 			}
+			if m.storage.IsPinned(cmd.Text) {
+				statusIndicator = lipgloss.NewStyle().Foreground(m.theme.colors.accent).Render(m.theme.glyphs.pin) + statusIndicator
+			}
+		}
+
+		// Timestamp column, blank for commands without one and "?" for
+		// ones whose recorded timestamp is unreadable
+		if m.showsTimestamps() {
+			var column string
+			if timestamp := m.filteredCmds[i].Timestamp; !timestamp.IsZero() {
+				column = m.times.Format(timestamp, now)
+			} else if m.filteredCmds[i].BadTimestamp {
+				column = "?"
+			}
+			column += strings.Repeat(" ", m.timestampColumnWidth()-runewidth.StringWidth(column))
+			statusIndicator = lipgloss.NewStyle().Foreground(m.theme.colors.muted).Render(column) + statusIndicator
 		}
 
 		// Render item
-		renderedItem := m.renderSingleItem(item, statusIndicator, isSelected)
+		arrival := m.showsCommands() && m.isArrival(m.filteredCmds[i].Text)
+		renderedItem := m.renderSingleItem(m.itemText(i), statusIndicator, isSelected, arrival)
 		renderedItems = append(renderedItems, renderedItem)
 	}
 
 	return strings.Join(renderedItems, "\n")
 }
 
-// renderSingleItem renders a single item with proper wrapping
-func (m Model) renderSingleItem(item string, statusIndicator string, isSelected bool) string {
+// renderSingleItem renders a single item with proper wrapping. Items new
+// since the last refresh are marked unless selected.
+func (m Model) renderSingleItem(item string, statusIndicator string, isSelected, arrival bool) string {
 	// Calculate available width
 	maxWidth := m.width - 6 // Account for selection markers and padding
 	if maxWidth < 20 {
@@ -260,24 +297,38 @@ func (m Model) renderSingleItem(item string, statusIndicator string, isSelected
 
 	var prefix string
 	if isSelected {
-		prefix = "► "
+		prefix = m.theme.glyphs.selected
 	} else {
 		prefix = "  "
+		if arrival {
+			prefix = m.theme.glyphs.arrival
+		}
+	}
+	selected := m.selectedStyle()
+
+	// In truncate mode every item is a single line
+	if m.truncates(isSelected) {
+		available := maxWidth - runewidth.StringWidth(prefix) - lipgloss.Width(statusIndicator)
+		line := prefix + statusIndicator + truncateWidth(strings.ReplaceAll(item, "\n", " "), available, m.theme.glyphs.ellipsis)
+		if isSelected {
+			return selected.Render(line)
+		}
+		return m.theme.normalItemStyle.Render(line)
 	}
 
 	// If it fits in one line
-	if len(prefix+fullText) <= maxWidth {
+	if runewidth.StringWidth(prefix)+lipgloss.Width(fullText) <= maxWidth {
 		var styledItem string
 		if isSelected {
-			styledItem = selectedItemStyle.Render(prefix + fullText)
+			styledItem = selected.Render(prefix + fullText)
 		} else {
-			styledItem = normalItemStyle.Render(prefix + fullText)
+			styledItem = m.theme.normalItemStyle.Render(prefix + fullText)
 		}
 		return styledItem
 	}
 
 	// Need to wrap
-	availableForText := maxWidth - len(prefix) - len(statusIndicator)
+	availableForText := maxWidth - runewidth.StringWidth(prefix) - lipgloss.Width(statusIndicator)
 	if availableForText < 10 {
 		availableForText = 10
 	}
@@ -296,14 +347,14 @@ func (m Model) renderSingleItem(item string, statusIndicator string, isSelected
 		} else {
 			// Continuation lines get padding
 			linePrefix = "  "
-			indicator = strings.Repeat(" ", len(statusIndicator))
+			indicator = strings.Repeat(" ", lipgloss.Width(statusIndicator))
 		}
 
 		var styledLine string
 		if isSelected {
-			styledLine = selectedItemStyle.Render(linePrefix + indicator + line)
+			styledLine = selected.Render(linePrefix + indicator + line)
 		} else {
-			styledLine = normalItemStyle.Render(linePrefix + indicator + line)
+			styledLine = m.theme.normalItemStyle.Render(linePrefix + indicator + line)
 		}
 		wrappedLines = append(wrappedLines, styledLine)
 	}
@@ -311,6 +362,37 @@ func (m Model) renderSingleItem(item string, statusIndicator string, isSelected
 	return strings.Join(wrappedLines, "\n")
 }
 
+// showsTimestamps reports whether commands are listed with a timestamp column
+func (m Model) showsTimestamps() bool {
+	return m.config.UI.ShowTimestamps && m.hasTimestamps && m.showsCommands()
+}
+
+// timestampColumnWidth returns the width of the timestamp column including
+// the space after it, or 0 when it isn't shown
+func (m Model) timestampColumnWidth() int {
+	if !m.showsTimestamps() {
+		return 0
+	}
+	return m.times.Width() + 1
+}
+
+// truncates reports whether an item is cut to one line instead of wrapped
+func (m Model) truncates(isSelected bool) bool {
+	return m.truncateLines && !(isSelected && m.config.UI.WrapSelected)
+}
+
+// truncateWidth cuts text to at most width terminal columns, ending it
+// with tail if anything was cut. Wide characters are never split.
+func truncateWidth(text string, width int, tail string) string {
+	if runewidth.StringWidth(text) <= width {
+		return text
+	}
+	if width < 1 {
+		return ""
+	}
+	return runewidth.Truncate(text, width, tail)
+}
+
 // wrapText wraps text to specified width
 func wrapText(text string, width int) []string {
 	if len(text) <= width {
@@ -348,11 +430,25 @@ func wrapText(text string, width int) []string {
 func (m Model) renderEmptyState() string {
 	var message string
 
+	if m.showsCommands() && len(m.hiddenSources) > 0 && m.shownSources() == 0 {
+		message = "All history sources are hidden. Press S to show them again."
+		return lipgloss.NewStyle().Foreground(m.theme.colors.muted).Render(message)
+	}
+
+	if m.needsSources() {
+		return m.renderSourcesGuide()
+	}
+
 	switch m.mode {
 	case HistoryMode:
 		message = "No command history found"
+		if m.sortMode == SortFrequency && len(m.commands) > 0 {
+			message = fmt.Sprintf("No commands used at least %d times (- lowers the minimum)", m.minCount)
+		}
 	case TemplatesMode:
 		message = "No templates available"
+	case SessionsMode:
+		message = "No sessions found (sessions need timestamped history, e.g. zsh extended_history)"
 	case SearchMode:
 		if m.searchQuery == "" {
 			message = "Start typing to search..."
@@ -361,7 +457,7 @@ func (m Model) renderEmptyState() string {
 		}
 	}
 
-	return lipgloss.NewStyle().Foreground(mutedColor).Render(message)
+	return lipgloss.NewStyle().Foreground(m.theme.colors.muted).Render(message)
 }
 
 // renderFooter renders the footer with status and controls
@@ -370,32 +466,80 @@ func (m Model) renderFooter() string {
 
 	// Status or error message
 	if m.errorMsg != "" {
-		sections = append(sections, errorStyle.Render("Error: "+m.errorMsg))
+		sections = append(sections, m.theme.errorStyle.Render("Error: "+m.errorMsg))
 	} else if m.statusMsg != "" {
-		sections = append(sections, statusStyle.Render(m.statusMsg))
+		sections = append(sections, m.theme.statusStyle.Render(m.statusMsg))
+	}
+
+	if m.correctedQuery != "" && (m.mode == HistoryMode || m.mode == SearchMode) {
+		sections = append(sections, m.theme.searchStyle.Render(fmt.Sprintf("showing results for '%s'", m.correctedQuery)))
 	}
 
 	// Item count and position info
 	itemCount := m.getItemCount()
 	if itemCount > 0 {
 		position := fmt.Sprintf("%d/%d", m.cursor+1, itemCount)
+		if m.showsCommands() && m.totalMatches > itemCount {
+			position += fmt.Sprintf(" (showing first %s of %s)", formatCount(itemCount), formatCount(m.totalMatches))
+		}
 
 		// Add sorting info
 		var sortInfo string
-		if m.mode == HistoryMode {
-			if m.statusMsg == "Sorted by frequency" {
+		if m.mode == HistoryMode || m.mode == SearchMode {
+			if m.sortMode == SortFrequency && m.mode == HistoryMode && m.searchQuery == "" {
+				sortInfo = fmt.Sprintf(" (count %s %d%s%s commands)", m.theme.glyphs.atLeast, m.minCount, m.theme.glyphs.sep, formatCount(m.totalMatches))
+			} else if m.sortMode == SortFrequency {
 				sortInfo = " (by frequency)"
+			}
+			if m.sortMode == SortFrequency && m.truncated {
+				sortInfo += fmt.Sprintf(" (counts from the last %s lines)", formatCount(m.config.Performance.MaxHistoryLines))
+			} else if m.pinnedOnly && m.mode == HistoryMode && m.searchQuery == "" {
+				sortInfo = " (pinned, in pin order)"
+			} else if m.everyRun {
+				sortInfo = " (every run, newest first)"
+			} else if m.fuzzyRanked() {
+				sortInfo = " (best match first)"
+			} else if m.frecencyRanked() {
+				sortInfo = " (frequent and recent first)"
 			} else {
-				sortInfo = " (newest first)"
+				sortInfo = " (newest first, deduplicated)"
+			}
+		}
+
+		// Where the selected command ran and how long it took, when the
+		// history recorded it
+		var details string
+		if m.showsCommands() && m.cursor < len(m.filteredCmds) {
+			selected := m.filteredCmds[m.cursor]
+			if history.IsRemote(selected.Source) {
+				details += m.theme.glyphs.sep + "on " + history.RemoteHost(selected.Source)
+			}
+			if selected.Directory != "" {
+				details += m.theme.glyphs.sep + "in " + shortenHome(selected.Directory)
+			}
+			if selected.HasDuration {
+				details += m.theme.glyphs.sep + "took " + selected.Duration.String()
 			}
 		}
 
-		sections = append(sections, lipgloss.NewStyle().Foreground(mutedColor).Render(position+sortInfo))
+		sections = append(sections, lipgloss.NewStyle().Foreground(m.theme.colors.muted).Render(position+sortInfo+details))
+	}
+
+	if len(m.hiddenSources) > 0 {
+		sections = append(sections, m.theme.searchStyle.Render(fmt.Sprintf("%d/%d sources", m.shownSources(), len(m.sourceFiles()))))
+	}
+
+	if count := logging.Count(); count > 0 {
+		warnings := fmt.Sprintf("%d warnings", count)
+		if count == 1 {
+			warnings = "1 warning"
+		}
+		sections = append(sections, lipgloss.NewStyle().Foreground(m.theme.colors.accent).Render(warnings+", press ? for details"))
 	}
 
 	// Controls help
 	controls := m.getControlsHelp()
-	sections = append(sections, footerStyle.Render(controls))
+	sections = append(sections, m.theme.footerStyle.Render(controls))
 
 	// Join sections and wrap if necessary
 	footer := strings.Join(sections, " | ")
@@ -444,35 +588,70 @@ func (m Model) wrapFooter(footer string) string {
 
 // getControlsHelp returns context-appropriate control hints
 func (m Model) getControlsHelp() string {
+	action := "enter: copy"
+	if m.pickerMode {
+		action = "enter: select"
+	}
+
 	switch m.mode {
 	case SearchMode:
-		return "esc: exit | enter: copy | ↑↓: navigate"
+		if m.searchQuery != "" {
+			return "esc: clear | " + action + " | " + m.theme.glyphs.upDown + ": navigate | ctrl+f: frequency | ctrl+t: time | ctrl+o: and/or | ctrl+x: fuzzy"
+		}
+		return "esc: exit | " + action + " | " + m.theme.glyphs.upDown + ": navigate | ctrl+f: frequency | ctrl+t: time | ctrl+o: and/or | ctrl+x: fuzzy"
 	case TemplatesMode:
-		return "enter: copy | t: history | /: search | ?: help | q: quit"
+		return action + " | i: suggest templates | t: history | /: search | ?: help | q: quit"
+	case SuggestionsMode:
+		return action + " | esc: back | ?: help | q: quit"
+	case SessionsMode:
+		if m.openSession >= 0 {
+			return action + " | esc: back to sessions | s: history | ?: help | q: quit"
+		}
+		return "enter: open session | s: history | T: time | ?: help | q: quit"
 	default:
-		return "enter: copy | t: templates | /: search | f: frequency | ?: help | q: quit"
+		return action + " | t: templates | s: sessions | /: search | f: frequency | r: refresh | ?: help | q: quit"
 	}
 }
 
 // renderHelp renders the help screen
 func (m Model) renderHelp() string {
-	helpText := `Terminal History Navigator - Help
+	helpText := fmt.Sprintf(`Terminal History Navigator - Help
+Version: %s
 
 NAVIGATION:
-  ↑/k         Move up
-  ↓/j         Move down
+  %-11s Move up
+  %-11s Move down
   enter       Copy selected item to clipboard
   
 MODES:
   h           Switch to history mode
   t           Toggle templates mode
   /           Start search
-  f           Sort by frequency (history mode)
+  f           Sort by frequency (ctrl+f in search mode)
+  +/-         Raise or lower the minimum count of the frequency list
+  s           Browse sessions (enter opens, esc goes back)
+  r           Re-read history files
+  X           Add an exclude pattern for the selected command
+  P           Pin or unpin the selected command (pins stay on top)
+  x           Delete the selected command (press again to confirm)
+  *           Only pinned commands
+  w           Toggle wrapping and truncating long commands
+  S           Show or hide history sources until quit
+  m           Open the man page of the selected command's program
+  E           Open the selected command's line in its history file ($EDITOR)
+  p           Copy only the file paths in the selected command
+  i           Suggest templates from repeated commands (templates mode)
+  D           Only commands run in the current directory (when known)
+  A           List every run instead of each command once
+  >           Show commands that usually follow the selected one
+  T           Cycle time scope: today, 7 days, 30 days (ctrl+t in search mode)
   
 SEARCH:
   /           Enter search mode
-  esc         Exit search mode
+  esc         Clear query, then exit search mode
   backspace   Delete search character
+  ctrl+o      Match any word instead of all (or separate words with |)
+  ctrl+x      Fuzzy search: letters in order, e.g. gcm for git commit -m
   
 OTHER:
   ?           Toggle this help
@@ -480,10 +659,58 @@ OTHER:
   q/ctrl+c    Quit application
 
 CONFIGURATION:
-  Config: ~/.config/history-nav/config.yaml
-  Templates: ~/.config/history-nav/templates.yaml
+  Config: %s
+  Templates: %s
+  Log: %s
+%s
+Press any key to close help...`, version.String(), m.theme.glyphs.up+"/k", m.theme.glyphs.down+"/j", m.config.Path(), m.config.TemplatesPath, logging.Path(), m.renderWarnings())
+
+	return m.helpBoxStyle().Render(helpText)
+}
+
+// helpWarnings is how many recent warnings the help screen lists
+const helpWarnings = 5
 
-Press any key to close help...`
+// renderWarnings lists the most recent warnings for the help screen
+func (m Model) renderWarnings() string {
+	entries := logging.Recent()
+	if len(entries) == 0 {
+		return ""
+	}
+	if len(entries) > helpWarnings {
+		entries = entries[len(entries)-helpWarnings:]
+	}
+
+	var b strings.Builder
+	b.WriteString("\nWARNINGS:\n")
+	for _, entry := range entries {
+		line := fmt.Sprintf("  %s  %s", entry.Time.Format("15:04:05"), entry.Message)
+		b.WriteString(truncateWidth(sanitizeText(line), max(m.width-8, 20), m.theme.glyphs.ellipsis) + "\n")
+	}
+	return b.String()
+}
 
-	return helpStyle.Render(helpText)
+// formatCount formats a number with thousands separators, e.g. 23,412
+func formatCount(n int) string {
+	digits := fmt.Sprintf("%d", n)
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	return b.String()
+}
+
+// shortenHome replaces the home directory at the start of path with ~
+func shortenHome(path string) string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil || homeDir == "" {
+		return path
+	}
+	if rest, found := strings.CutPrefix(path, homeDir); found && (rest == "" || strings.HasPrefix(rest, string(filepath.Separator))) {
+		return "~" + rest
+	}
+	return path
 }
@@ -0,0 +1,183 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/4ndew/terminal-history-navigator/internal/config"
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+	"github.com/4ndew/terminal-history-navigator/internal/storage"
+	"github.com/4ndew/terminal-history-navigator/internal/templates"
+)
+
+// newListModel returns a model over n one-line commands, newest "cmd 0",
+// sized to show ten of them
+func newListModel(n int, configure func(cfg *config.Config)) Model {
+	commands := make([]history.Command, n)
+	for i := range commands {
+		commands[i] = history.Command{Text: fmt.Sprintf("cmd %d", i), Position: n - i, Count: 1}
+	}
+	store := storage.NewMemoryStorage()
+	store.Store(commands)
+
+	cfg := config.DefaultConfig()
+	configure(cfg)
+	model, _ := NewModel(store, nil, cfg, nil).Update(tea.WindowSizeMsg{Width: 80, Height: 16})
+	return model.(Model)
+}
+
+// TestScrollWindow moves the cursor through 30 items with room for 10 and
+// checks which items are shown after each step, in both scroll modes
+func TestScrollWindow(t *testing.T) {
+	type step struct {
+		moves      int // Down if positive, up if negative
+		start, end int // Items shown after the moves
+	}
+	tests := []struct {
+		scroll string
+		steps  []step
+	}{
+		// The cursor keeps up to a third of the window above it
+		{"center", []step{
+			{0, 0, 10},
+			{3, 0, 10},
+			{1, 1, 11},
+			{5, 6, 16},
+			{-2, 4, 14},
+			{18, 20, 30}, // Near the end, the window fills upwards
+			{2, 20, 30},
+			{-29, 0, 10},
+		}},
+		// The window only moves when the cursor comes within two items of
+		// its edges
+		{"edge", []step{
+			{0, 0, 10},
+			{7, 0, 10},
+			{1, 1, 11},
+			{4, 5, 15},
+			{-1, 5, 15},
+			{-5, 4, 14},
+			{-4, 0, 10},
+			{29, 20, 30},
+			{-7, 20, 30},
+			{-1, 19, 29},
+		}},
+	}
+	for _, tt := range tests {
+		m := newListModel(30, func(cfg *config.Config) {
+			cfg.UI.Scroll = tt.scroll
+			cfg.UI.Scrolloff = 2
+		})
+		if lines := m.maxVisibleLines(); lines != 10 {
+			t.Fatalf("visible lines = %d, want 10", lines)
+		}
+
+		for i, s := range tt.steps {
+			key := tea.KeyMsg{Type: tea.KeyDown}
+			if s.moves < 0 {
+				key = tea.KeyMsg{Type: tea.KeyUp}
+			}
+			for n := 0; n < s.moves || n < -s.moves; n++ {
+				m, _ = press(m, key)
+			}
+			start, end := m.scrollWindow(m.getItemCount())
+			if start != s.start || end != s.end {
+				t.Errorf("%s step %d (cursor %d): window [%d, %d), want [%d, %d)", tt.scroll, i, m.cursor, start, end, s.start, s.end)
+			}
+		}
+	}
+}
+
+func TestSanitizeText(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"git status", "git status"},
+		{"echo \x1b[31mred\x1b[0m", "echo red"},
+		{"printf '\x1b]0;title\x07'", "printf ''"},
+		{"printf '\x1b]8;;https://example.com\x1b\\link'", "printf 'link'"},
+		{"echo one\rtwo", "echo one^Mtwo"},
+		{"echo \x07beep", "echo ^Gbeep"},
+		{"a\tb", "a b"},
+		{"for f in *; do\n  echo $f\ndone", "for f in *; do\n  echo $f\ndone"},
+		{"echo \u009bC1", "echo \\u009BC1"},
+		{"echo \x7f", "echo ^?"},
+	}
+	for _, tt := range tests {
+		if got := sanitizeText(tt.text); got != tt.want {
+			t.Errorf("sanitizeText(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+// TestViewSanitizesCommands checks escape sequences, carriage returns and
+// bells in history never reach the terminal, whichever item is selected
+func TestViewSanitizesCommands(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	store.Store([]history.Command{
+		{Text: "echo \x1b[31mred\x1b[0m", Position: 2, Count: 1},
+		{Text: "printf 'done\rhidden'", Position: 1, Count: 1},
+		{Text: "echo ring\x07 && tput bel", Position: 0, Count: 1},
+	})
+	cfg := config.DefaultConfig()
+	model, _ := NewModel(store, nil, cfg, nil).Update(tea.WindowSizeMsg{Width: 80, Height: 16})
+	m := model.(Model)
+
+	for i := 0; i < 3; i++ {
+		view := m.View()
+		for _, bad := range []string{"\x1b[31m", "\r", "\x07"} {
+			if strings.Contains(view, bad) {
+				t.Errorf("view with item %d selected contains %q:\n%s", i, bad, view)
+			}
+		}
+		if !strings.Contains(view, "echo red") || !strings.Contains(view, "done^Mhidden") || !strings.Contains(view, "ring^G") {
+			t.Errorf("view with item %d selected doesn't show the commands sanitized:\n%s", i, view)
+		}
+		m, _ = press(m, tea.KeyMsg{Type: tea.KeyDown})
+	}
+}
+
+// TestASCIIOnlyView checks that with ui.ascii_only every screen is plain
+// ASCII: glyphs, borders, ellipses and status marks included
+func TestASCIIOnlyView(t *testing.T) {
+	now := time.Now()
+	store := storage.NewMemoryStorage()
+	store.Store([]history.Command{
+		{Text: "git status", Position: 3, Count: 4, Timestamp: now.Add(-time.Minute), HasExit: true, Directory: "/srv/app"},
+		{Text: "make test", Position: 2, Count: 2, Timestamp: now.Add(-time.Hour), HasExit: true, ExitCode: 2, HasDuration: true, Duration: 3 * time.Second},
+		{Text: "git push", Position: 1, Count: 1, Timestamp: now.Add(-48 * time.Hour)},
+		{Text: "docker run --rm -v /srv/app:/app -e ENVIRONMENT=production ghcr.io/org/app:1.0 ./bin/migrate --all --verbose", Position: 0, Count: 1},
+	})
+	templateList := []templates.Template{{Name: "Build", Command: "go build ./...", Description: "Build everything"}}
+	cfg := config.DefaultConfig()
+	cfg.UI.ASCIIOnly = true
+
+	screens := []string{"", "?", "/git", "/", "t", "s", "f", "w", "x", "X", "T", "A", "*", "jjj"}
+	for _, keys := range screens {
+		model, _ := NewModel(store, templateList, cfg, nil).Update(tea.WindowSizeMsg{Width: 60, Height: 24})
+		m := model.(Model)
+		m, _ = press(m, runes(keys)...)
+
+		view := m.View()
+		for i := 0; i < len(view); i++ {
+			if view[i] >= 0x80 {
+				t.Errorf("view after %q has byte %#x at %d in %q", keys, view[i], i, lineAt(view, i))
+				break
+			}
+		}
+	}
+}
+
+// lineAt returns the line of s containing byte i
+func lineAt(s string, i int) string {
+	start := strings.LastIndexByte(s[:i], '\n') + 1
+	end := strings.IndexByte(s[i:], '\n')
+	if end < 0 {
+		return s[start:]
+	}
+	return s[start : i+end]
+}
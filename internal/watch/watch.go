@@ -0,0 +1,132 @@
+package watch
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+	"github.com/4ndew/terminal-history-navigator/internal/logging"
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDelay is how long changes are collected before they are reported,
+// since shells write their history after every command
+const DefaultDelay = 2 * time.Second
+
+// Watcher reports changes to local history files. It watches their
+// directories, so files created later and files replaced by renaming are
+// noticed too.
+type Watcher struct {
+	fs      *fsnotify.Watcher
+	delay   time.Duration
+	changes chan struct{}
+
+	mu       sync.Mutex
+	patterns []string        // Watched sources, as paths or glob patterns
+	dirs     map[string]bool // Directories added to fs
+}
+
+// New starts watching sources, reporting changes at most once per delay
+func New(sources []string, delay time.Duration) (*Watcher, error) {
+	fs, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		fs:      fs,
+		delay:   delay,
+		changes: make(chan struct{}, 1),
+		dirs:    make(map[string]bool),
+	}
+	w.Watch(sources)
+	go w.run()
+	return w, nil
+}
+
+// Watch replaces the watched sources. Directories of earlier sources stay
+// watched, but their events are ignored.
+func (w *Watcher) Watch(sources []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.patterns = nil
+	for _, source := range sources {
+		if source == history.StdinSource || history.IsRemote(source) {
+			continue
+		}
+		w.patterns = append(w.patterns, filepath.Clean(source))
+
+		dir := filepath.Dir(source)
+		if w.dirs[dir] {
+			continue
+		}
+		if err := w.fs.Add(dir); err != nil {
+			// A missing directory can't get a history file either
+			logging.Warnf("cannot watch %s for history changes: %v", dir, err)
+			continue
+		}
+		w.dirs[dir] = true
+	}
+}
+
+// Changes returns the channel that receives a value after watched files
+// changed
+func (w *Watcher) Changes() <-chan struct{} {
+	return w.changes
+}
+
+// Close stops watching
+func (w *Watcher) Close() error {
+	return w.fs.Close()
+}
+
+// run collects file events and reports them once the delay has passed
+func (w *Watcher) run() {
+	var pending <-chan time.Time
+	for {
+		select {
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			if pending == nil && event.Op != fsnotify.Chmod && w.matches(event.Name) {
+				pending = time.After(w.delay)
+			}
+
+		case <-pending:
+			pending = nil
+			// A change not yet picked up covers this one too
+			select {
+			case w.changes <- struct{}{}:
+			default:
+			}
+
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+			logging.Warnf("watching history files: %v", err)
+		}
+	}
+}
+
+// matches reports whether path is one of the watched sources
+func (w *Watcher) matches(path string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	path = filepath.Clean(path)
+	for _, pattern := range w.patterns {
+		if pattern == path {
+			return true
+		}
+		if history.IsGlob(pattern) {
+			if matched, _ := filepath.Match(pattern, path); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -1,79 +1,534 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/4ndew/terminal-history-navigator/internal/config"
+	"github.com/4ndew/terminal-history-navigator/internal/export"
 	"github.com/4ndew/terminal-history-navigator/internal/history"
+	"github.com/4ndew/terminal-history-navigator/internal/logging"
+	"github.com/4ndew/terminal-history-navigator/internal/session"
 	"github.com/4ndew/terminal-history-navigator/internal/storage"
 	"github.com/4ndew/terminal-history-navigator/internal/templates"
 	"github.com/4ndew/terminal-history-navigator/internal/ui"
+	"github.com/4ndew/terminal-history-navigator/internal/version"
+	"github.com/4ndew/terminal-history-navigator/internal/watch"
+	"github.com/4ndew/terminal-history-navigator/pkg/clipboard"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/term"
 )
 
+// Exit codes of the TUI and subcommands
+const (
+	exitSelected  = 0   // A command was selected (printed or copied)
+	exitError     = 1   // The TUI or a subcommand failed at runtime
+	exitUsage     = 2   // Invalid flags or configuration
+	exitCancelled = 130 // Quit without selecting a command
+)
+
+// subcommands maps subcommand names to their entry points, which return
+// the process exit code
+var subcommands = map[string]func(args []string) int{
+	"backup":  runBackup,
+	"clean":   runClean,
+	"doctor":  runDoctor,
+	"export":  runExport,
+	"import":  runImport,
+	"init":    runInit,
+	"restore": runRestore,
+	"list":    runList,
+	"merge":   runMerge,
+	"search":  runSearch,
+	"stats":   runStats,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(run(os.Args[2:]))
+		}
+	}
+
+	configFlag := flag.String("config", "", "path to an alternate config file")
+	modeFlag := flag.String("mode", "", "start mode: history, templates or search")
+	queryFlag := flag.String("query", "", "initial search query")
+	versionFlag := flag.Bool("version", false, "print version information and exit")
+	jsonFlag := flag.Bool("json", false, "print version information as JSON (with --version)")
+	printFlag := flag.Bool("print", false, "print the selected command to stdout instead of copying it")
+	outputFlag := flag.String("output", "plain", "picker output format: plain or json")
+	onboardingFlag := flag.Bool("onboarding", false, "show the first-run setup again, e.g. after installing a new shell")
+	noOnboardingFlag := flag.Bool("no-onboarding", false, "skip the first-run setup, e.g. for scripted installs")
+	stdinFlag := flag.Bool("stdin", false, "read history piped to stdin instead of the configured sources")
+	themeFlag := flag.String("theme", "", "color theme for this run: auto, dark or light (overrides ui.theme)")
+	heightFlag := flag.String("height", "", "draw below the prompt in N rows or N% of the terminal instead of full screen (default 40% when stdout is not a terminal)")
+	flag.Usage = usage
+	flag.Parse()
+
+	// Print version before touching config or the terminal
+	if *versionFlag {
+		printVersion(*jsonFlag)
+		return
+	}
+
+	switch *outputFlag {
+	case "plain", "json":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid --output %q (use plain or json)\n", *outputFlag)
+		os.Exit(exitUsage)
+	}
+
+	switch *themeFlag {
+	case "", "auto", "dark", "light":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid --theme %q (use auto, dark or light)\n", *themeFlag)
+		os.Exit(exitUsage)
+	}
+
+	if *stdinFlag && term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Fprintln(os.Stderr, "Error: --stdin needs history piped in, e.g. ssh host cat .zsh_history | terminal-history-navigator --stdin")
+		os.Exit(exitUsage)
+	}
+
+	inlineRows, inlinePercent, err := parseHeight(*heightFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --height %q: %v\n", *heightFlag, err)
+		os.Exit(exitUsage)
+	}
+
 	// Initialize configuration
-	cfg, err := config.Load()
+	cfg, err := loadConfig(*configFlag)
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		fmt.Fprintf(os.Stderr, "Error: failed to load config: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	// Flags take precedence over the configured start mode and query
+	startOverridden := *modeFlag != "" || *queryFlag != ""
+	if *modeFlag != "" {
+		if _, ok := ui.ParseViewMode(*modeFlag); !ok {
+			fmt.Fprintf(os.Stderr, "Error: invalid --mode %q (use history, templates or search)\n", *modeFlag)
+			os.Exit(exitUsage)
+		}
+		cfg.UI.StartMode = *modeFlag
+	}
+	if *queryFlag != "" {
+		cfg.UI.StartQuery = *queryFlag
+		// A query on its own opens search mode with the query applied
+		if *modeFlag == "" {
+			cfg.UI.StartMode = "search"
+		}
+	}
+
+	if *themeFlag != "" {
+		cfg.UI.Theme = *themeFlag
+	}
+	if *stdinFlag {
+		cfg.Sources = []string{history.StdinSource}
+		cfg.ImportFile = ""
 	}
 
+	// Warnings go to the log, since the TUI would hide or be garbled by them
+	logWarnings(cfg.Validate())
+
+	if err := clipboard.SetBackend(cfg.Clipboard.Backend); err != nil {
+		logging.Warnf("%v", err)
+	}
+	clipboard.SetTimeout(cfg.Clipboard.Timeout)
+	clipboard.SetFallbackFile(cfg.Clipboard.FallbackFile)
+
 	// Initialize storage
 	store := storage.NewMemoryStorage()
 
 	// Initialize reader
-	reader := history.NewReader(cfg.Sources)
-	reader.SetMaxLines(cfg.Performance.MaxHistoryLines)
-
-	// Set exclude patterns if any configured
-	if len(cfg.ExcludePatterns) > 0 {
-		err = reader.SetExcludePatterns(cfg.ExcludePatterns)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Invalid exclude patterns: %v\n", err)
-		}
-	}
+	reader := newReader(cfg)
 
 	// Load initial history
 	err = loadHistory(reader, store)
 	if err != nil {
-		log.Fatalf("Failed to read history: %v", err)
+		fmt.Fprintf(os.Stderr, "Error: failed to read history: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	// Pinned commands survive restarts
+	pins, err := storage.LoadPins(cfg.PinsPath)
+	if err != nil {
+		logging.Warnf("failed to load pins: %v", err)
+	}
+	for _, text := range pins {
+		store.Pin(text)
 	}
 
 	// Load templates
 	templateLoader := templates.NewLoader(cfg.TemplatesPath)
 	templatesData, err := templateLoader.Load()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: Failed to load templates: %v\n", err)
+		logging.Warnf("failed to load templates: %v", err)
 		// Continue without templates
 	}
 
+	// Load previous session state if enabled
+	var state *session.State
+	if cfg.UI.RestoreSession && !startOverridden {
+		state, err = session.Load()
+		if err != nil {
+			logging.Warnf("failed to load session state: %v", err)
+		}
+	}
+
+	// Picker mode is used when stdout is captured, e.g. vim $(history-nav),
+	// and then draws inline below the prompt like fzf unless told otherwise
+	captured := !term.IsTerminal(int(os.Stdout.Fd()))
+	picker := *printFlag || captured
+	if captured && *heightFlag == "" {
+		inlinePercent = defaultInlinePercent
+	}
+	inline := inlineRows > 0 || inlinePercent > 0
+
+	// In picker mode stdout is reserved for the selection, so draw the
+	// TUI on the controlling terminal instead. The renderer must be set
+	// before the model is created, since the theme asks it for the
+	// terminal background.
+	var tty *os.File
+	if picker {
+		tty, err = os.OpenFile("/dev/tty", os.O_RDWR, 0)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: picker mode needs a terminal: %v\n", err)
+			os.Exit(exitError)
+		}
+		defer tty.Close()
+		lipgloss.SetDefaultRenderer(lipgloss.NewRenderer(tty))
+	}
+
 	// Create UI model
-	model := ui.NewModel(store, templatesData, cfg)
+	model := ui.NewModel(store, templatesData, cfg, state)
+	model.SetPickerMode(picker)
+	for _, err := range reader.Skipped() {
+		logging.Warnf("skipped source %v", err)
+	}
+	model.ShowSkippedSources(reader.Skipped())
+	if inline {
+		model.SetInlineHeight(inlineRows, inlinePercent)
+	}
+	model.SetTemplateSaver(templateLoader.Add)
+	model.SetPinsSaver(func(texts []string) error {
+		return storage.SavePins(cfg.PinsPath, texts)
+	})
+	if cfg.AllowHistoryFileEdits && !*stdinFlag {
+		model.SetFileDeleter(reader.DeleteCommand)
+	}
+	model.SetExcludeHandler(func(pattern string) error {
+		if err := cfg.AddExcludePattern(pattern); err != nil {
+			return err
+		}
+		if err := reader.SetExcludePatterns(cfg.ExcludePatterns); err != nil {
+			return err
+		}
+		// Keep the newly excluded commands out of the cache on disk
+		return reader.ClearCache()
+	})
+	// Pick up commands as shells append them, without pressing r
+	var watcher *watch.Watcher
+	if cfg.Performance.WatchFiles && !*stdinFlag {
+		watcher, err = watch.New(cfg.Sources, watch.DefaultDelay)
+		if err != nil {
+			logging.Warnf("cannot watch history files: %v", err)
+		} else {
+			defer watcher.Close()
+		}
+	}
+	saveSources := func(sources []string) error {
+		if err := cfg.SetSources(sources); err != nil {
+			return err
+		}
+		reader.SetSources(sources)
+		if watcher != nil {
+			watcher.Watch(sources)
+		}
+		return nil
+	}
+	model.SetSourcesHandler(saveSources)
+	if (cfg.Created() || *onboardingFlag) && !*noOnboardingFlag && !*stdinFlag {
+		model.StartOnboarding(history.DetectSources(), saveSources)
+	}
+	if watcher != nil {
+		model.SetWatcher(watcher.Changes())
+	}
+	model.SetTruncated(reader.Truncated())
+	model.SetRefresh(func() (ui.Refreshed, error) {
+		commands, incremental, err := reader.ReadNew()
+		return ui.Refreshed{
+			Commands:    commands,
+			Occurrences: reader.Occurrences(),
+			Truncated:   reader.Truncated(),
+			Incremental: incremental,
+		}, err
+	}, time.Duration(cfg.Performance.AutoRefreshSeconds)*time.Second)
+
+	// Inline mode leaves the screen and mouse to the shell
+	var options []tea.ProgramOption
+	if !inline {
+		options = append(options,
+			tea.WithAltScreen(),       // Use alternate screen
+			tea.WithMouseCellMotion(), // Enable mouse support
+		)
+	}
+
+	// Piped history has used up stdin, so keys come from the terminal
+	if tty != nil {
+		options = append(options, tea.WithInput(tty), tea.WithOutput(tty))
+	} else if slices.Contains(cfg.Sources, history.StdinSource) {
+		options = append(options, tea.WithInputTTY())
+	}
 
 	// Create TUI program
-	program := tea.NewProgram(
-		model,
-		tea.WithAltScreen(),       // Use alternate screen
-		tea.WithMouseCellMotion(), // Enable mouse support
-	)
+	program := tea.NewProgram(model, options...)
 
 	// Run the program
-	if _, err := program.Run(); err != nil {
+	finalModel, err := program.Run()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error running program: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
+	}
+	m, _ := finalModel.(ui.Model)
+
+	// Save session state for the next run
+	if cfg.UI.RestoreSession {
+		if err := m.SessionState().Save(); err != nil {
+			logging.Warnf("failed to save session state: %v", err)
+		}
+	}
+
+	if code := finish(os.Stdout, m, picker, *outputFlag); code != 0 {
+		os.Exit(code)
+	}
+}
+
+// finish prints the command picked in picker mode to stdout, which gets
+// nothing else, and returns the exit code for how the TUI ended
+func finish(stdout io.Writer, m ui.Model, picker bool, format string) int {
+	if m.Outcome() != ui.OutcomeSelected {
+		return exitCancelled
+	}
+
+	if picker {
+		if err := printPick(stdout, m, format); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitError
+		}
+	}
+	return 0
+}
+
+// defaultInlinePercent is the part of the terminal used inline when stdout
+// is captured and --height isn't given
+const defaultInlinePercent = 40
+
+// parseHeight parses --height as a number of rows or a percentage of the
+// terminal. Both are 0 when height is empty.
+func parseHeight(height string) (rows, percent int, err error) {
+	if height == "" {
+		return 0, 0, nil
+	}
+	if number, found := strings.CutSuffix(height, "%"); found {
+		percent, err = strconv.Atoi(number)
+		if err != nil || percent < 1 || percent > 100 {
+			return 0, 0, fmt.Errorf("use a percentage from 1%% to 100%%")
+		}
+		return 0, percent, nil
+	}
+	rows, err = strconv.Atoi(height)
+	if err != nil || rows < 1 {
+		return 0, 0, fmt.Errorf("use a number of rows or a percentage like 40%%")
+	}
+	return rows, 0, nil
+}
+
+// printPick prints the item selected in picker mode to w as plain text or
+// a JSON object
+func printPick(w io.Writer, m ui.Model, format string) error {
+	if format != "json" {
+		_, err := fmt.Fprintln(w, m.Selection())
+		return err
+	}
+
+	var pick export.Pick
+	if template, ok := m.PickedTemplate(); ok {
+		pick = export.NewTemplatePick(template)
+	} else {
+		cmd, _ := m.PickedCommand()
+		pick = export.NewHistoryPick(cmd)
+	}
+
+	data, err := json.Marshal(pick)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// usage prints the command-line help, including subcommands and exit codes
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "Usage: terminal-history-navigator [flags]")
+	fmt.Fprintln(out, "       terminal-history-navigator backup|clean|doctor|export|import|init|list|merge|restore|search|stats [flags]")
+	fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
+	fmt.Fprintln(out, "\nExit codes:")
+	fmt.Fprintln(out, "  0    a command was selected (printed or copied)")
+	fmt.Fprintln(out, "  1    the TUI failed to start or run")
+	fmt.Fprintln(out, "  2    invalid flags or configuration")
+	fmt.Fprintln(out, "  130  quit without selecting a command")
+}
+
+// printVersion prints the build metadata as text or JSON
+func printVersion(asJSON bool) {
+	if asJSON {
+		data, _ := json.Marshal(version.Get())
+		fmt.Println(string(data))
+		return
 	}
+	fmt.Printf("terminal-history-navigator %s\n", version.String())
 }
 
-// loadHistory reads command history and stores it
+// loadConfig loads the configuration from path, or the default location
+// when path is empty, and directs warnings to the configured log file
+func loadConfig(path string) (*config.Config, error) {
+	var cfg *config.Config
+	var err error
+	if path != "" {
+		cfg, err = config.LoadFrom(path)
+	} else {
+		cfg, err = config.Load()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logging.SetFile(cfg.Log.File, int64(cfg.Log.MaxSizeKB)*1024)
+	return cfg, nil
+}
+
+// logWarnings logs config warnings
+func logWarnings(warnings []string) {
+	for _, warning := range warnings {
+		logging.Warnf("%s", warning)
+	}
+}
+
+// newReader creates a history reader from the configuration, warning about
+// invalid patterns
+func newReader(cfg *config.Config) *history.Reader {
+	// Imported commands are read like those of any other source
+	sources := cfg.Sources
+	if cfg.ImportFile != "" && !slices.Contains(sources, cfg.ImportFile) {
+		sources = append(slices.Clip(sources), cfg.ImportFile)
+	}
+	reader := history.NewReader(sources)
+	reader.SetMaxLines(cfg.Performance.MaxHistoryLines)
+	reader.SetRemoteTimeout(cfg.Performance.RemoteTimeout)
+	if cfg.Performance.CacheEnabled {
+		reader.SetCachePath(cfg.Performance.CachePath)
+	}
+	reader.SetSecretsMode(cfg.Secrets.Mode)
+	reader.SetNormalization(history.Normalization{
+		CollapseSpaces:   cfg.Normalize.CollapseWhitespace,
+		StripEnv:         cfg.Normalize.StripEnv,
+		LowercaseCommand: cfg.Normalize.LowercaseCommand,
+	})
+	reader.SetMaxCommandLength(cfg.Performance.MaxCommandLength, cfg.Performance.LongCommands == "skip")
+	reader.SetFilters(history.Filters{
+		MinLength:            cfg.Filters.MinLength,
+		DropNumeric:          cfg.Filters.DropNumeric,
+		DropBinary:           cfg.Filters.DropBinary,
+		DropSpaced:           cfg.Filters.DropSpaced,
+		DropFutureTimestamps: cfg.Filters.DropFutureTimestamps,
+		FutureSkew:           cfg.Filters.FutureSkew,
+		MaxAge:               time.Duration(cfg.Performance.MaxAgeDays) * 24 * time.Hour,
+	})
+
+	// Set exclude patterns if any configured
+	if len(cfg.ExcludePatterns) > 0 {
+		err := reader.SetExcludePatterns(cfg.ExcludePatterns)
+		if err != nil {
+			logging.Warnf("invalid exclude patterns: %v", err)
+		}
+	}
+
+	// Set include patterns if any configured
+	if len(cfg.IncludePatterns) > 0 {
+		err := reader.SetIncludePatterns(cfg.IncludePatterns)
+		if err != nil {
+			logging.Warnf("invalid include patterns: %v", err)
+		}
+	}
+
+	// Set keep patterns if any configured
+	if len(cfg.KeepPatterns) > 0 {
+		err := reader.SetKeepPatterns(cfg.KeepPatterns)
+		if err != nil {
+			logging.Warnf("invalid keep patterns: %v", err)
+		}
+	}
+
+	// "-" reads history piped to standard input
+	if slices.Contains(cfg.Sources, history.StdinSource) {
+		data, err := readStdin()
+		if err != nil {
+			logging.Warnf("failed to read history from stdin: %v", err)
+		} else {
+			reader.SetStdin(data)
+		}
+	}
+
+	return reader
+}
+
+// readStdin reads the history piped to standard input. It can only be read
+// once, so the result is kept for every reader.
+var readStdin = sync.OnceValues(func() ([]byte, error) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, errors.New("stdin is a terminal, pipe a history file into it instead")
+	}
+	return io.ReadAll(os.Stdin)
+})
+
+// loadHistory reads command history and stores it, appending only the new
+// commands if the reader read the history before or has a cache of it
 func loadHistory(reader *history.Reader, store storage.Storage) error {
-	commands, err := reader.ReadHistory()
+	cached, fromCache := reader.LoadCache()
+	if fromCache {
+		store.Store(cached)
+	}
+	commands, incremental, err := reader.ReadNew()
 	if err != nil {
 		return err
 	}
 
 	// Store commands
-	store.Store(commands)
+	if incremental {
+		store.Append(commands)
+	} else {
+		store.Store(commands)
+	}
+	store.StoreOccurrences(reader.Occurrences())
+
+	// Keep the cache up to date, so the next start reads less
+	if !fromCache || len(commands) > 0 {
+		if err := reader.SaveCache(); err != nil {
+			logging.Warnf("failed to save history cache: %v", err)
+		}
+	}
 	return nil
 }
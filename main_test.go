@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/4ndew/terminal-history-navigator/internal/config"
+	"github.com/4ndew/terminal-history-navigator/internal/history"
+	"github.com/4ndew/terminal-history-navigator/internal/storage"
+	"github.com/4ndew/terminal-history-navigator/internal/ui"
+)
+
+// readConfigured reads a history file of lines with a reader built from
+// the config settings and returns the command texts
+func readConfigured(t *testing.T, settings string, lines ...string) []string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ".zsh_history")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfgPath := writeConfig(t, fmt.Sprintf("sources: [%q]\nexclude_patterns: []\n%s", path, settings))
+	cfg, err := loadConfig(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commands, err := newReader(cfg).ReadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var texts []string
+	for _, cmd := range commands {
+		texts = append(texts, cmd.Text)
+	}
+	return texts
+}
+
+// TestFilterSettings checks each filter setting in the config changes
+// which commands are read
+func TestFilterSettings(t *testing.T) {
+	now := time.Now().Unix()
+	zsh := func(offset int64, text string) string { return fmt.Sprintf(": %d:0;%s", now+offset, text) }
+	spaced := zsh(0, " git push")
+	tests := []struct {
+		name     string
+		settings string
+		line     string
+		kept     bool
+	}{
+		{"single letter by default", "", zsh(0, "g"), true},
+		{"min_length", "filters:\n  min_length: 2\n", zsh(0, "g"), false},
+		{"number by default", "", zsh(0, "42"), false},
+		{"drop_numeric off", "filters:\n  drop_numeric: false\n", zsh(0, "42"), true},
+		{"leading space by default", "", spaced, false},
+		{"drop_spaced off", "filters:\n  drop_spaced: false\n", spaced, true},
+		{"future by default", "", zsh(2*3600, "make"), false},
+		{"future_skew", "filters:\n  future_skew: 3h\n", zsh(2*3600, "make"), true},
+		{"drop_future_timestamps off", "filters:\n  drop_future_timestamps: false\n", zsh(2*3600, "make"), true},
+		{"old by default", "", zsh(-40*24*3600, "make"), true},
+		{"max_age_days", "performance:\n  max_age_days: 30\n", zsh(-40*24*3600, "make"), false},
+	}
+	for _, tt := range tests {
+		texts := readConfigured(t, tt.settings, zsh(-10, "ls -la"), tt.line)
+		if kept := len(texts) == 2; kept != tt.kept {
+			t.Errorf("%s: commands = %q, want the second line kept %v", tt.name, texts, tt.kept)
+		}
+	}
+}
+
+// TestKeepPatternsSetting checks keep_patterns in the config show commands
+// the default exclude patterns would hide
+func TestKeepPatternsSetting(t *testing.T) {
+	lines := []string{": 1700000000:0;kubectl get secrets", ": 1700000001:0;export token=abc"}
+
+	texts := readConfigured(t, "", lines...)
+	if len(texts) != 2 {
+		t.Fatalf("commands without excludes = %q, want both", texts)
+	}
+
+	path := filepath.Join(t.TempDir(), ".zsh_history")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfgPath := writeConfig(t, fmt.Sprintf("sources: [%q]\nkeep_patterns: [\"^kubectl get\"]\n", path))
+	cfg, err := loadConfig(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commands, err := newReader(cfg).ReadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != 1 || commands[0].Text != "kubectl get secrets" {
+		t.Errorf("commands with the default excludes = %+v, want only kubectl get secrets", commands)
+	}
+	if !slices.Contains(cfg.ExcludePatterns, "secret") {
+		t.Errorf("default exclude patterns = %q, want secret among them", cfg.ExcludePatterns)
+	}
+}
+
+// terminal records what the TUI draws, safe to read while it is drawing
+type terminal struct {
+	mu  sync.Mutex
+	out bytes.Buffer
+}
+
+func (t *terminal) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.out.Write(p)
+}
+
+func (t *terminal) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.out.String()
+}
+
+// runPicker drives the TUI in picker mode over three commands, "git
+// status" newest, typing each key once the list is drawn. It returns what
+// was drawn on the terminal, what was printed to stdout and the exit code.
+func runPicker(t *testing.T, format string, keys ...string) (drawn, stdout string, code int) {
+	t.Helper()
+	store := storage.NewMemoryStorage()
+	store.Store([]history.Command{
+		{Text: "git status", Position: 2, Count: 1, Directory: "/srv/app"},
+		{Text: "make test", Position: 1, Count: 1, Directory: "/srv/app"},
+		{Text: "git push", Position: 0, Count: 1},
+	})
+	model := ui.NewModel(store, nil, config.DefaultConfig(), nil)
+	model.SetPickerMode(true)
+	sized, _ := model.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+
+	// Keys come from and the TUI is drawn on the terminal, as with
+	// /dev/tty, so stdout only gets what finish prints
+	input, typing := io.Pipe()
+	tty := &terminal{}
+	program := tea.NewProgram(sized, tea.WithInput(input), tea.WithOutput(tty))
+	done := make(chan struct{})
+	var final tea.Model
+	var err error
+	go func() {
+		defer close(done)
+		final, err = program.Run()
+		input.Close()
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(tty.String(), "git push") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Keys written together arrive as one message, so pause between them
+	for _, key := range keys {
+		typing.Write([]byte(key))
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		program.Kill()
+		<-done
+		t.Fatalf("the TUI didn't quit after keys %q", keys)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	code = finish(&out, final.(ui.Model), true, format)
+	return tty.String(), out.String(), code
+}
+
+// TestPickerOutput checks picker mode prints only the selection to
+// stdout, and nothing when cancelled, while the TUI goes to the terminal
+func TestPickerOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		keys   []string
+		stdout string
+		code   int
+	}{
+		{"enter", []string{"\r"}, "git status\n", 0},
+		{"down then enter", []string{"j", "\r"}, "make test\n", 0},
+		{"search then enter", []string{"/", "p", "u", "s", "h", "\r"}, "git push\n", 0},
+		{"q", []string{"j", "q"}, "", exitCancelled},
+		{"ctrl+c", []string{"\x03"}, "", exitCancelled},
+	}
+	for _, tt := range tests {
+		drawn, stdout, code := runPicker(t, "plain", tt.keys...)
+		if stdout != tt.stdout || code != tt.code {
+			t.Errorf("%s: printed %q and exited %d, want %q and %d", tt.name, stdout, code, tt.stdout, tt.code)
+		}
+		if !strings.Contains(drawn, "git status") || !strings.Contains(drawn, "make test") {
+			t.Errorf("%s: terminal shows %q, want the history list", tt.name, drawn)
+		}
+		if strings.Contains(stdout, "\x1b") {
+			t.Errorf("%s: stdout has escape sequences: %q", tt.name, stdout)
+		}
+	}
+}
+
+// TestPickerOutputJSON checks --output json prints the picked command as
+// a single JSON line
+func TestPickerOutputJSON(t *testing.T) {
+	_, stdout, code := runPicker(t, "json", "j", "\r")
+	if code != 0 || strings.Count(stdout, "\n") != 1 {
+		t.Fatalf("printed %q and exited %d, want one line", stdout, code)
+	}
+
+	var pick struct {
+		Command   string `json:"command"`
+		Directory string `json:"directory"`
+		Kind      string `json:"kind"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &pick); err != nil {
+		t.Fatal(err)
+	}
+	if pick.Command != "make test" || pick.Directory != "/srv/app" || pick.Kind != "history" {
+		t.Errorf("pick = %+v, want make test from /srv/app", pick)
+	}
+}
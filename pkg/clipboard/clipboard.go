@@ -1,111 +1,378 @@
 package clipboard
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	"runtime"
 	"strings"
+	"sync"
+	"time"
 )
 
-// Copy copies text to the system clipboard
-func Copy(text string) error {
-	switch runtime.GOOS {
-	case "darwin":
-		return copyMacOS(text)
-	case "linux":
-		return copyLinux(text)
-	case "windows":
-		return copyWindows(text)
-	default:
-		return fmt.Errorf("clipboard operations not supported on %s", runtime.GOOS)
+// lookPath, getenv and runCommand are variables so backend selection can be
+// tested without the actual clipboard binaries installed
+var (
+	lookPath   = exec.LookPath
+	getenv     = os.Getenv
+	runCommand = execCommand
+)
+
+// timeout bounds how long a clipboard utility may run before it is killed
+var timeout = 2 * time.Second
+
+// SetTimeout sets how long clipboard utilities may run before being killed
+func SetTimeout(d time.Duration) {
+	if d > 0 {
+		timeout = d
 	}
 }
 
-// copyMacOS copies text to clipboard on macOS using pbcopy
-func copyMacOS(text string) error {
-	cmd := exec.Command("pbcopy")
-	cmd.Stdin = strings.NewReader(text)
-	return cmd.Run()
+// execCommand runs a command with the given stdin and returns its stdout.
+// The command is killed if it runs longer than the configured timeout, and
+// errors include its stderr output.
+func execCommand(stdin string, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// xclip forks a child that keeps our pipes open while it serves the
+	// selection, so don't wait for its output once the command has exited
+	cmd.WaitDelay = 100 * time.Millisecond
+
+	err := cmd.Run()
+	if errors.Is(err, exec.ErrWaitDelay) {
+		err = nil
+	}
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("timed out after %s", timeout)
+		}
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return "", errors.New(detail)
+		}
+		return "", err
+	}
+
+	return stdout.String(), nil
 }
 
-// copyLinux copies text to clipboard on Linux using xclip or xsel
-func copyLinux(text string) error {
-	// Try xclip first
-	if _, err := exec.LookPath("xclip"); err == nil {
-		cmd := exec.Command("xclip", "-selection", "clipboard")
-		cmd.Stdin = strings.NewReader(text)
-		if err := cmd.Run(); err == nil {
-			return nil
+// backend is a clipboard implementation
+type backend interface {
+	Name() string
+	Available() bool
+	Copy(text string) error
+	Paste() (string, error)
+}
+
+// toolBackend is a backend that runs a command-line clipboard utility
+type toolBackend struct {
+	name     string
+	copyCmd  []string
+	pasteCmd []string
+}
+
+// Name returns the backend name
+func (t toolBackend) Name() string {
+	return t.name
+}
+
+// Available reports whether the utility is installed
+func (t toolBackend) Available() bool {
+	_, err := lookPath(t.copyCmd[0])
+	return err == nil
+}
+
+// Copy copies text by piping it to the utility
+func (t toolBackend) Copy(text string) error {
+	_, err := runCommand(text, t.copyCmd[0], t.copyCmd[1:]...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", t.name, err)
+	}
+	return nil
+}
+
+// Paste reads text from the utility's output
+func (t toolBackend) Paste() (string, error) {
+	if len(t.pasteCmd) == 0 {
+		return "", fmt.Errorf("paste not supported by %s", t.name)
+	}
+
+	output, err := runCommand("", t.pasteCmd[0], t.pasteCmd[1:]...)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", t.name, err)
+	}
+	return strings.TrimRight(output, "\n"), nil
+}
+
+var (
+	pbcopy = toolBackend{
+		name:     "pbcopy",
+		copyCmd:  []string{"pbcopy"},
+		pasteCmd: []string{"pbpaste"},
+	}
+	// Windows doesn't have a simple command-line paste utility
+	clip = toolBackend{
+		name:    "clip",
+		copyCmd: []string{"clip"},
+	}
+	wlClipboard = toolBackend{
+		name:     "wl-clipboard",
+		copyCmd:  []string{"wl-copy"},
+		pasteCmd: []string{"wl-paste", "--no-newline"},
+	}
+	xclip = toolBackend{
+		name:     "xclip",
+		copyCmd:  []string{"xclip", "-selection", "clipboard"},
+		pasteCmd: []string{"xclip", "-selection", "clipboard", "-out"},
+	}
+	xsel = toolBackend{
+		name:     "xsel",
+		copyCmd:  []string{"xsel", "--clipboard", "--input"},
+		pasteCmd: []string{"xsel", "--clipboard", "--output"},
+	}
+	tmux = toolBackend{
+		name:     "tmux",
+		copyCmd:  []string{"tmux", "load-buffer", "-"},
+		pasteCmd: []string{"tmux", "save-buffer", "-"},
+	}
+)
+
+// allBackends returns every known backend
+func allBackends() []backend {
+	return []backend{nativeBackend{}, pbcopy, clip, wlClipboard, xclip, xsel, wslBackend{}, tmux, osc52Backend{}, fileBackend{}}
+}
+
+// mu guards forced and lastBackend, as a delayed clear can run in the
+// background while the UI copies
+var mu sync.Mutex
+
+// forced is the explicitly selected backend name ("" means automatic)
+var forced string
+
+// lastBackend is the name of the backend used by the last successful Copy
+var lastBackend string
+
+// Backend detection runs once and is cached for the process lifetime
+var (
+	detectOnce sync.Once
+	detected   []backend
+)
+
+// SetBackend selects the clipboard backend by name, e.g. "xclip", "tmux",
+// "osc52" or "native" (Windows only). "auto" restores automatic detection.
+func SetBackend(name string) error {
+	switch {
+	case name == "" || name == "auto":
+		name = ""
+	case findBackend(name) == nil:
+		return fmt.Errorf("unknown clipboard backend %q", name)
+	case name == "native" && runtime.GOOS != "windows":
+		return errNativeUnsupported()
+	}
+
+	mu.Lock()
+	forced = name
+	mu.Unlock()
+	return nil
+}
+
+// findBackend returns the backend with the given name, or nil
+func findBackend(name string) backend {
+	for _, b := range allBackends() {
+		if b.Name() == name {
+			return b
 		}
 	}
+	return nil
+}
 
-	// Try xsel as fallback
-	if _, err := exec.LookPath("xsel"); err == nil {
-		cmd := exec.Command("xsel", "--clipboard", "--input")
-		cmd.Stdin = strings.NewReader(text)
-		return cmd.Run()
+// Backend returns the name of the backend Copy will try first, or "none"
+func Backend() string {
+	backends := candidates()
+	if len(backends) == 0 {
+		return "none"
 	}
+	return backends[0].Name()
+}
 
-	return fmt.Errorf("no clipboard utility found (install xclip or xsel)")
+// AvailableBackends returns the names of the detected backends in the
+// order Copy tries them
+func AvailableBackends() []string {
+	var names []string
+	for _, b := range detect() {
+		names = append(names, b.Name())
+	}
+	return names
 }
 
-// copyWindows copies text to clipboard on Windows using clip
-func copyWindows(text string) error {
-	cmd := exec.Command("clip")
-	cmd.Stdin = strings.NewReader(text)
-	return cmd.Run()
+// LastBackend returns the name of the backend used by the last successful
+// Copy, e.g. "xclip", "tmux" or "osc52"
+func LastBackend() string {
+	mu.Lock()
+	defer mu.Unlock()
+	return lastBackend
 }
 
-// Paste reads text from the system clipboard
-func Paste() (string, error) {
+// candidates returns the backends to try, in order of preference
+func candidates() []backend {
+	mu.Lock()
+	name := forced
+	mu.Unlock()
+
+	if b := findBackend(name); b != nil {
+		return []backend{b}
+	}
+	return detect()
+}
+
+// detect returns the installed backends for this platform in order of
+// preference, looking them up only on the first call
+func detect() []backend {
+	detectOnce.Do(func() {
+		detected = detectBackends()
+	})
+	return detected
+}
+
+// detectBackends looks up which backends are installed
+func detectBackends() []backend {
+	var ordered []backend
 	switch runtime.GOOS {
 	case "darwin":
-		return pasteMacOS()
-	case "linux":
-		return pasteLinux()
+		ordered = []backend{pbcopy}
 	case "windows":
-		return pasteWindows()
-	default:
-		return "", fmt.Errorf("clipboard operations not supported on %s", runtime.GOOS)
+		ordered = []backend{nativeBackend{}, clip}
+	case "linux":
+		ordered = linuxBackends()
+	}
+
+	// Writing to a file is the last resort on any platform
+	ordered = append(ordered, fileBackend{})
+
+	var available []backend
+	for _, b := range ordered {
+		if b.Available() {
+			available = append(available, b)
+		}
 	}
+	return available
 }
 
-// pasteMacOS reads text from clipboard on macOS using pbpaste
-func pasteMacOS() (string, error) {
-	cmd := exec.Command("pbpaste")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", err
+// linuxBackends returns the Linux backends to consider, in order of preference
+func linuxBackends() []backend {
+	var backends []backend
+	if getenv("WAYLAND_DISPLAY") != "" {
+		// XWayland setups may also have xclip/xsel, so keep them as fallbacks
+		backends = []backend{wlClipboard, xclip, xsel}
+	} else {
+		backends = []backend{xclip, xsel}
+	}
+
+	// Inside WSL prefer the Windows clipboard unless there is an X or
+	// Wayland display (WSLg), where the Linux tools work as well
+	if getenv("WAYLAND_DISPLAY") == "" && getenv("DISPLAY") == "" {
+		backends = append([]backend{wslBackend{}}, backends...)
+	} else {
+		backends = append(backends, wslBackend{})
+	}
+
+	// The tmux paste buffer is better than nothing on headless machines
+	if getenv("TMUX") != "" {
+		backends = append(backends, tmux)
 	}
-	return strings.TrimRight(string(output), "\n"), nil
+
+	// Over SSH no local utility can reach the user's clipboard, but
+	// the terminal itself may support OSC 52
+	if getenv("SSH_TTY") != "" {
+		backends = append(backends, osc52Backend{})
+	}
+
+	return backends
+}
+
+// errNoBackend returns the error reported when no backend is usable
+func errNoBackend() error {
+	if runtime.GOOS == "linux" {
+		return fmt.Errorf("no clipboard utility found (install wl-clipboard, xclip or xsel)")
+	}
+	return fmt.Errorf("clipboard operations not supported on %s", runtime.GOOS)
+}
+
+// errNativeUnsupported returns the error reported when the native backend
+// is used outside Windows
+func errNativeUnsupported() error {
+	return fmt.Errorf("native clipboard backend is only available on Windows, not %s", runtime.GOOS)
+}
+
+// Copy copies text to the system clipboard
+func Copy(text string) error {
+	name, err := copyText(text)
+
+	mu.Lock()
+	lastBackend = name
+	mu.Unlock()
+	return err
 }
 
-// pasteLinux reads text from clipboard on Linux using xclip or xsel
-func pasteLinux() (string, error) {
-	// Try xclip first
-	if _, err := exec.LookPath("xclip"); err == nil {
-		cmd := exec.Command("xclip", "-selection", "clipboard", "-out")
-		output, err := cmd.Output()
-		if err == nil {
-			return strings.TrimRight(string(output), "\n"), nil
+// copyText tries each backend until one copies text, returning its name
+func copyText(text string) (string, error) {
+	backends := candidates()
+	if len(backends) == 0 {
+		return "", errNoBackend()
+	}
+
+	var err error
+	for _, b := range backends {
+		if err = b.Copy(text); err == nil {
+			return b.Name(), nil
 		}
 	}
 
-	// Try xsel as fallback
-	if _, err := exec.LookPath("xsel"); err == nil {
-		cmd := exec.Command("xsel", "--clipboard", "--output")
-		output, err := cmd.Output()
-		if err == nil {
-			return strings.TrimRight(string(output), "\n"), nil
+	return "", err
+}
+
+// Paste reads text from the system clipboard
+func Paste() (string, error) {
+	backends := candidates()
+	if len(backends) == 0 {
+		return "", errNoBackend()
+	}
+
+	var err error
+	for _, b := range backends {
+		var text string
+		if text, err = b.Paste(); err == nil {
+			return text, nil
 		}
 	}
 
-	return "", fmt.Errorf("no clipboard utility found (install xclip or xsel)")
+	return "", err
 }
 
-// pasteWindows reads text from clipboard on Windows
-func pasteWindows() (string, error) {
-	// Windows doesn't have a simple command-line paste utility
-	// This would require Windows API calls or PowerShell
-	return "", fmt.Errorf("paste not implemented for Windows")
+// Clear empties the clipboard through the named backend, normally the one
+// LastBackend reported for copying text, rather than whichever backend Copy
+// would pick now. Text copied since is left alone, except on backends that
+// can't paste, which are cleared unconditionally. It reports whether the
+// clipboard was cleared.
+func Clear(name, text string) (bool, error) {
+	b := findBackend(name)
+	if b == nil {
+		return false, fmt.Errorf("unknown clipboard backend %q", name)
+	}
+
+	if current, err := b.Paste(); err == nil && current != text {
+		return false, nil
+	}
+	if err := b.Copy(""); err != nil {
+		return false, err
+	}
+	return true, nil
 }
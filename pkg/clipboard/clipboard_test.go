@@ -0,0 +1,364 @@
+package clipboard
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSystem stands in for the installed utilities and the environment
+type fakeSystem struct {
+	installed map[string]bool
+	env       map[string]string
+	lookups   []string   // Utilities looked up on the PATH, in order
+	runs      [][]string // Commands run, each with its arguments
+	stdin     []string   // Input of each command run
+	output    string     // Output of every command run
+	err       error      // Error of every command run
+}
+
+// fakeTools replaces the PATH lookups, environment and commands run by the
+// backends with a fakeSystem for the rest of the test, and starts backend
+// detection over
+func fakeTools(t *testing.T, installed []string, env map[string]string) *fakeSystem {
+	t.Helper()
+	if runtime.GOOS != "linux" {
+		t.Skip("backend order is tested with the Linux utilities")
+	}
+
+	fake := &fakeSystem{installed: make(map[string]bool), env: env}
+	for _, name := range installed {
+		fake.installed[name] = true
+	}
+
+	savedLookPath, savedGetenv, savedRun, savedReadFile := lookPath, getenv, runCommand, readFile
+	t.Cleanup(func() {
+		lookPath, getenv, runCommand, readFile = savedLookPath, savedGetenv, savedRun, savedReadFile
+		resetDetection()
+	})
+	lookPath = func(name string) (string, error) {
+		fake.lookups = append(fake.lookups, name)
+		if fake.installed[name] {
+			return "/usr/bin/" + name, nil
+		}
+		return "", exec.ErrNotFound
+	}
+	getenv = func(key string) string { return fake.env[key] }
+	runCommand = func(stdin string, name string, args ...string) (string, error) {
+		fake.runs = append(fake.runs, append([]string{name}, args...))
+		fake.stdin = append(fake.stdin, stdin)
+		return fake.output, fake.err
+	}
+	readFile = func(string) ([]byte, error) { return nil, errors.New("no /proc/version") }
+
+	resetDetection()
+	return fake
+}
+
+// resetDetection forgets the detected and selected backends
+func resetDetection() {
+	detectOnce = sync.Once{}
+	detected = nil
+	forced = ""
+	lastBackend = ""
+}
+
+// TestTmuxBackend checks the tmux paste buffer is used inside tmux when no
+// clipboard utility is installed
+func TestTmuxBackend(t *testing.T) {
+	fake := fakeTools(t, []string{"tmux"}, map[string]string{"TMUX": "/tmp/tmux-1000/default,1,0"})
+
+	if err := Copy("git status"); err != nil {
+		t.Fatal(err)
+	}
+	if LastBackend() != "tmux" {
+		t.Errorf("LastBackend() = %q, want tmux", LastBackend())
+	}
+	if len(fake.runs) != 1 || strings.Join(fake.runs[0], " ") != "tmux load-buffer -" || fake.stdin[0] != "git status" {
+		t.Fatalf("runs = %q with input %q, want tmux load-buffer - with the text", fake.runs, fake.stdin)
+	}
+
+	fake.output = "git status\n"
+	text, err := Paste()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "git status" || strings.Join(fake.runs[1], " ") != "tmux save-buffer -" {
+		t.Errorf("Paste() = %q running %q, want git status from tmux save-buffer -", text, fake.runs[1])
+	}
+}
+
+// TestTmuxBackendOrder checks tmux comes after the clipboard utilities, and
+// is only used inside tmux
+func TestTmuxBackendOrder(t *testing.T) {
+	fakeTools(t, []string{"xclip", "xsel", "tmux"}, map[string]string{"TMUX": "/tmp/tmux-1000/default,1,0", "DISPLAY": ":0"})
+	if got, want := AvailableBackends(), []string{"xclip", "xsel", "tmux"}; !slices.Equal(got, want) {
+		t.Errorf("backends inside tmux = %q, want %q", got, want)
+	}
+
+	fakeTools(t, []string{"tmux"}, map[string]string{"DISPLAY": ":0"})
+	if got := AvailableBackends(); len(got) != 0 {
+		t.Errorf("backends outside tmux = %q, want none", got)
+	}
+}
+
+// TestTmuxBackendForced checks tmux can be selected explicitly
+func TestTmuxBackendForced(t *testing.T) {
+	fake := fakeTools(t, []string{"xclip", "tmux"}, map[string]string{"DISPLAY": ":0"})
+	if err := SetBackend("tmux"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Copy("make"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.runs) != 1 || fake.runs[0][0] != "tmux" {
+		t.Errorf("runs = %q, want only tmux", fake.runs)
+	}
+}
+
+// TestNativeBackendUnavailable checks platforms without a native clipboard
+// keep using the utilities, and reject selecting native
+func TestNativeBackendUnavailable(t *testing.T) {
+	fake := fakeTools(t, []string{"xclip"}, map[string]string{"DISPLAY": ":0"})
+	if got := AvailableBackends(); !slices.Equal(got, []string{"xclip"}) {
+		t.Errorf("backends = %q, want xclip without native", got)
+	}
+
+	if err := SetBackend("native"); err == nil || !strings.Contains(err.Error(), "only available on Windows") {
+		t.Errorf("SetBackend(native) = %v, want only available on Windows", err)
+	}
+	if err := Copy("make"); err != nil || LastBackend() != "xclip" || len(fake.runs) != 1 {
+		t.Errorf("Copy after rejecting native = %v with %q, want xclip", err, LastBackend())
+	}
+}
+
+// BenchmarkCopySubprocess starts a utility for every copy, as the tool
+// backends do, with cat standing in for xclip
+func BenchmarkCopySubprocess(b *testing.B) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		b.Skip("cat is not installed")
+	}
+	for i := 0; i < b.N; i++ {
+		if _, err := execCommand("git status", "cat"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCopyNative copies with the native backend, where there is one
+func BenchmarkCopyNative(b *testing.B) {
+	native := nativeBackend{}
+	if !native.Available() {
+		b.Skipf("no native clipboard on %s", runtime.GOOS)
+	}
+	for i := 0; i < b.N; i++ {
+		if err := native.Copy("git status"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDetectionOrder(t *testing.T) {
+	tools := []string{"wl-copy", "xclip", "xsel", "tmux"}
+	tests := []struct {
+		env  map[string]string
+		want []string
+	}{
+		{map[string]string{"WAYLAND_DISPLAY": "wayland-0"}, []string{"wl-clipboard", "xclip", "xsel"}},
+		{map[string]string{"DISPLAY": ":0"}, []string{"xclip", "xsel"}},
+		{map[string]string{"DISPLAY": ":0", "TMUX": "/tmp/tmux", "SSH_TTY": "/dev/pts/1"}, []string{"xclip", "xsel", "tmux", "osc52"}},
+		{map[string]string{"SSH_TTY": "/dev/pts/1"}, []string{"xclip", "xsel", "osc52"}},
+	}
+	for _, tt := range tests {
+		fakeTools(t, tools, tt.env)
+		if got := AvailableBackends(); !slices.Equal(got, tt.want) {
+			t.Errorf("backends with %v = %q, want %q", tt.env, got, tt.want)
+		}
+	}
+}
+
+// TestDetectionCached checks utilities are looked up once, not on every
+// copy
+func TestDetectionCached(t *testing.T) {
+	fake := fakeTools(t, []string{"xsel"}, map[string]string{"DISPLAY": ":0"})
+	for i := 0; i < 3; i++ {
+		if err := Copy("make"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := strings.Join(fake.lookups, " "); got != "xclip xsel" {
+		t.Errorf("lookups = %q, want xclip and xsel once", got)
+	}
+	if len(fake.runs) != 3 || fake.runs[2][0] != "xsel" {
+		t.Errorf("runs = %q, want xsel three times", fake.runs)
+	}
+}
+
+// TestCopyFallsBack checks a failing backend is followed by the next one
+func TestCopyFallsBack(t *testing.T) {
+	fake := fakeTools(t, []string{"xclip", "xsel"}, map[string]string{"DISPLAY": ":0"})
+	run := runCommand
+	runCommand = func(stdin string, name string, args ...string) (string, error) {
+		run(stdin, name, args...)
+		if name == "xclip" {
+			return "", errors.New("Can't open display: :0")
+		}
+		return "", nil
+	}
+
+	if err := Copy("make"); err != nil {
+		t.Fatal(err)
+	}
+	if LastBackend() != "xsel" || len(fake.runs) != 2 {
+		t.Errorf("copied with %q after runs %q, want xsel after xclip", LastBackend(), fake.runs)
+	}
+}
+
+func TestSetBackend(t *testing.T) {
+	fake := fakeTools(t, []string{"xclip", "xsel"}, map[string]string{"DISPLAY": ":0"})
+
+	if err := SetBackend("xsel"); err != nil {
+		t.Fatal(err)
+	}
+	if Backend() != "xsel" {
+		t.Errorf("Backend() = %q, want xsel", Backend())
+	}
+	if err := Copy("make"); err != nil || fake.runs[0][0] != "xsel" {
+		t.Errorf("Copy ran %q, %v; want xsel", fake.runs, err)
+	}
+
+	if err := SetBackend("pbpaste"); err == nil {
+		t.Error("SetBackend accepted an unknown backend")
+	}
+	if err := SetBackend("auto"); err != nil || Backend() != "xclip" {
+		t.Errorf("Backend() after auto = %q, %v; want xclip", Backend(), err)
+	}
+}
+
+// TestClearUsesCopyBackend checks the clipboard is cleared through the
+// backend that copied the text, even when Copy would now pick another one
+func TestClearUsesCopyBackend(t *testing.T) {
+	fake := fakeTools(t, []string{"xclip", "tmux"}, map[string]string{"TMUX": "/tmp/tmux-1000/default,1,0", "DISPLAY": ":0"})
+	if err := SetBackend("tmux"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Copy("export TOKEN=abc"); err != nil {
+		t.Fatal(err)
+	}
+	backend := LastBackend()
+	if err := SetBackend("auto"); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.output = "export TOKEN=abc"
+	cleared, err := Clear(backend, "export TOKEN=abc")
+	if err != nil || !cleared {
+		t.Fatalf("Clear(%q) = %v, %v; want cleared", backend, cleared, err)
+	}
+	var runs []string
+	for _, run := range fake.runs[1:] {
+		runs = append(runs, strings.Join(run, " "))
+	}
+	if want := []string{"tmux save-buffer -", "tmux load-buffer -"}; !slices.Equal(runs, want) || fake.stdin[2] != "" {
+		t.Errorf("clearing ran %q with input %q, want %q with no input", runs, fake.stdin[2:], want)
+	}
+
+	fake.output = "git status"
+	if cleared, err := Clear(backend, "export TOKEN=abc"); err != nil || cleared || len(fake.runs) != 4 {
+		t.Errorf("Clear after another copy = %v, %v running %q; want the clipboard left alone", cleared, err, fake.runs[3:])
+	}
+
+	if _, err := Clear("pbpaste", "export TOKEN=abc"); err == nil {
+		t.Error("Clear accepted an unknown backend")
+	}
+}
+
+// TestClearWhileCopying checks a background clear can run while the UI
+// copies and reads the last backend; run with -race
+func TestClearWhileCopying(t *testing.T) {
+	saved := fallbackFile
+	t.Cleanup(func() {
+		fallbackFile = saved
+		resetDetection()
+	})
+	SetFallbackFile(filepath.Join(t.TempDir(), "clipboard.txt"))
+	if err := SetBackend("file"); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			Clear("file", "export TOKEN=abc")
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		if err := Copy("git status"); err != nil {
+			t.Error(err)
+		}
+		if LastBackend() != "file" {
+			t.Errorf("LastBackend() = %q, want file", LastBackend())
+		}
+		SetBackend("file")
+	}
+	<-done
+}
+
+// withTimeout sets the utility timeout for the rest of the test
+func withTimeout(t *testing.T, d time.Duration) {
+	saved := timeout
+	t.Cleanup(func() { timeout = saved })
+	SetTimeout(d)
+}
+
+// TestExecCommandTimeout checks a hanging utility is killed after the
+// timeout
+func TestExecCommandTimeout(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep is not installed")
+	}
+	withTimeout(t, 100*time.Millisecond)
+
+	start := time.Now()
+	_, err := execCommand("", "sleep", "10")
+	if err == nil || !strings.Contains(err.Error(), "timed out after 100ms") {
+		t.Errorf("err = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("sleep ran %s, want it killed after the timeout", elapsed)
+	}
+}
+
+// TestExecCommandStderr checks a failing utility's error is its stderr,
+// prefixed with the backend name by Copy
+func TestExecCommandStderr(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not installed")
+	}
+	_, err := execCommand("", "sh", "-c", "echo 'Error: Can'\\''t open display: :0' >&2; exit 1")
+	if err == nil || err.Error() != "Error: Can't open display: :0" {
+		t.Errorf("err = %v, want the stderr output", err)
+	}
+
+	fake := fakeTools(t, []string{"xclip"}, map[string]string{"DISPLAY": ":0"})
+	fake.err = err
+	if err := Copy("make"); err == nil || err.Error() != "xclip: Error: Can't open display: :0" {
+		t.Errorf("Copy() = %v, want the backend name and stderr", err)
+	}
+}
+
+func TestSetTimeoutIgnoresNonPositive(t *testing.T) {
+	withTimeout(t, time.Second)
+	SetTimeout(0)
+	SetTimeout(-time.Second)
+	if timeout != time.Second {
+		t.Errorf("timeout = %s, want 1s kept", timeout)
+	}
+}
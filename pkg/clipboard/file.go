@@ -0,0 +1,86 @@
+package clipboard
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// fallbackFile is the file used as a last-resort clipboard ("" disables it)
+var fallbackFile string
+
+// SetFallbackFile sets the file (or FIFO) selections are written to when
+// no other clipboard backend is usable. An empty path disables it.
+func SetFallbackFile(path string) {
+	fallbackFile = path
+}
+
+// FallbackFile returns the configured fallback file path
+func FallbackFile() string {
+	return fallbackFile
+}
+
+// fileBackend writes selections to a file so they can be read with cat or
+// picked up by a wrapper script
+type fileBackend struct{}
+
+// Name returns the backend name
+func (fileBackend) Name() string {
+	return "file"
+}
+
+// Available reports whether a fallback file is configured
+func (fileBackend) Available() bool {
+	return fallbackFile != ""
+}
+
+// Copy writes text to the fallback file, readable only by the user since
+// commands can be sensitive
+func (fileBackend) Copy(text string) error {
+	if fallbackFile == "" {
+		return fmt.Errorf("file: no fallback file configured")
+	}
+
+	if info, err := os.Stat(fallbackFile); err == nil && info.Mode()&os.ModeNamedPipe != 0 {
+		// Don't block the UI when nothing is reading from the FIFO
+		fifo, err := os.OpenFile(fallbackFile, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+		if err != nil {
+			return fmt.Errorf("file: no reader on %s", fallbackFile)
+		}
+		defer fifo.Close()
+		_, err = fifo.WriteString(text + "\n")
+		return err
+	}
+
+	err := os.MkdirAll(filepath.Dir(fallbackFile), 0700)
+	if err != nil {
+		return fmt.Errorf("file: %w", err)
+	}
+
+	err = os.WriteFile(fallbackFile, []byte(text), 0600)
+	if err != nil {
+		return fmt.Errorf("file: %w", err)
+	}
+
+	// WriteFile keeps the mode of an existing file
+	return os.Chmod(fallbackFile, 0600)
+}
+
+// Paste reads text back from the fallback file
+func (fileBackend) Paste() (string, error) {
+	if fallbackFile == "" {
+		return "", fmt.Errorf("file: no fallback file configured")
+	}
+
+	if info, err := os.Stat(fallbackFile); err == nil && info.Mode()&os.ModeNamedPipe != 0 {
+		return "", fmt.Errorf("file: paste not supported from a FIFO")
+	}
+
+	data, err := os.ReadFile(fallbackFile)
+	if err != nil {
+		return "", fmt.Errorf("file: %w", err)
+	}
+	return strings.TrimRight(string(data), "\n"), nil
+}
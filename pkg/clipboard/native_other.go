@@ -0,0 +1,30 @@
+//go:build !windows
+
+package clipboard
+
+// nativeBackend only exists on Windows. Reaching the macOS or X11
+// clipboard without running a utility would require cgo, which release
+// builds don't use, so other platforms always copy through a utility and
+// SetBackend rejects "native". The type is kept so the backend list is the
+// same on every platform.
+type nativeBackend struct{}
+
+// Name returns the backend name
+func (nativeBackend) Name() string {
+	return "native"
+}
+
+// Available reports false since there is no native implementation
+func (nativeBackend) Available() bool {
+	return false
+}
+
+// Copy always fails on this platform
+func (nativeBackend) Copy(text string) error {
+	return errNativeUnsupported()
+}
+
+// Paste always fails on this platform
+func (nativeBackend) Paste() (string, error) {
+	return "", errNativeUnsupported()
+}
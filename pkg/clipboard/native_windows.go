@@ -0,0 +1,136 @@
+//go:build windows
+
+package clipboard
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	cfUnicodeText = 13
+	gmemMoveable  = 0x0002
+)
+
+var (
+	user32           = windows.NewLazySystemDLL("user32.dll")
+	kernel32         = windows.NewLazySystemDLL("kernel32.dll")
+	openClipboard    = user32.NewProc("OpenClipboard")
+	closeClipboard   = user32.NewProc("CloseClipboard")
+	emptyClipboard   = user32.NewProc("EmptyClipboard")
+	getClipboardData = user32.NewProc("GetClipboardData")
+	setClipboardData = user32.NewProc("SetClipboardData")
+	globalAlloc      = kernel32.NewProc("GlobalAlloc")
+	globalFree       = kernel32.NewProc("GlobalFree")
+	globalLock       = kernel32.NewProc("GlobalLock")
+	globalUnlock     = kernel32.NewProc("GlobalUnlock")
+	lstrlenW         = kernel32.NewProc("lstrlenW")
+	moveMemory       = kernel32.NewProc("RtlMoveMemory")
+)
+
+// nativeBackend uses the Win32 clipboard API directly instead of running clip
+type nativeBackend struct{}
+
+// Name returns the backend name
+func (nativeBackend) Name() string {
+	return "native"
+}
+
+// Available reports whether the clipboard API could be loaded
+func (nativeBackend) Available() bool {
+	return openClipboard.Find() == nil
+}
+
+// Copy places text on the clipboard as CF_UNICODETEXT
+func (nativeBackend) Copy(text string) error {
+	// The clipboard is owned by the thread that opened it
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	data, err := windows.UTF16FromString(text)
+	if err != nil {
+		return fmt.Errorf("native: %w", err)
+	}
+
+	if err := openWithRetry(); err != nil {
+		return err
+	}
+	defer closeClipboard.Call()
+
+	if r, _, err := emptyClipboard.Call(); r == 0 {
+		return fmt.Errorf("native: EmptyClipboard: %w", err)
+	}
+
+	size := uintptr(len(data)) * 2
+	handle, _, err := globalAlloc.Call(gmemMoveable, size)
+	if handle == 0 {
+		return fmt.Errorf("native: GlobalAlloc: %w", err)
+	}
+
+	ptr, _, err := globalLock.Call(handle)
+	if ptr == 0 {
+		globalFree.Call(handle)
+		return fmt.Errorf("native: GlobalLock: %w", err)
+	}
+	moveMemory.Call(ptr, uintptr(unsafe.Pointer(&data[0])), size)
+	globalUnlock.Call(handle)
+
+	// On success the system owns the memory
+	if r, _, err := setClipboardData.Call(cfUnicodeText, handle); r == 0 {
+		globalFree.Call(handle)
+		return fmt.Errorf("native: SetClipboardData: %w", err)
+	}
+
+	return nil
+}
+
+// Paste reads CF_UNICODETEXT from the clipboard
+func (nativeBackend) Paste() (string, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if err := openWithRetry(); err != nil {
+		return "", err
+	}
+	defer closeClipboard.Call()
+
+	handle, _, err := getClipboardData.Call(cfUnicodeText)
+	if handle == 0 {
+		return "", fmt.Errorf("native: GetClipboardData: %w", err)
+	}
+
+	ptr, _, err := globalLock.Call(handle)
+	if ptr == 0 {
+		return "", fmt.Errorf("native: GlobalLock: %w", err)
+	}
+	defer globalUnlock.Call(handle)
+
+	length, _, _ := lstrlenW.Call(ptr)
+	if length == 0 {
+		return "", nil
+	}
+	data := make([]uint16, length)
+	moveMemory.Call(uintptr(unsafe.Pointer(&data[0])), ptr, length*2)
+
+	return windows.UTF16ToString(data), nil
+}
+
+// openWithRetry opens the clipboard, retrying briefly since another
+// program may be holding it
+func openWithRetry() error {
+	deadline := time.Now().Add(time.Second)
+	for {
+		r, _, err := openClipboard.Call(0)
+		if r != 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("native: OpenClipboard: %w", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,73 @@
+package clipboard
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// osc52MaxPayload caps the base64 payload sent in one OSC 52 sequence.
+// Many terminals silently drop larger sequences (xterm's default limit is
+// about 100KB), so bigger selections are rejected with an error instead.
+const osc52MaxPayload = 100000
+
+// screenChunkSize is the maximum length of a single DCS string for GNU screen
+const screenChunkSize = 768
+
+// osc52Backend sets the local terminal clipboard using the OSC 52 escape sequence
+type osc52Backend struct{}
+
+// Name returns the backend name
+func (osc52Backend) Name() string {
+	return "osc52"
+}
+
+// Available reports true since terminal support can't be detected reliably
+func (osc52Backend) Available() bool {
+	return true
+}
+
+// Paste is not supported since reading the clipboard back is rarely allowed
+func (osc52Backend) Paste() (string, error) {
+	return "", fmt.Errorf("paste not supported by osc52")
+}
+
+// Copy writes the OSC 52 sequence for text to the controlling terminal
+func (osc52Backend) Copy(text string) error {
+	encoded := base64.StdEncoding.EncodeToString([]byte(text))
+	if len(encoded) > osc52MaxPayload {
+		return fmt.Errorf("osc52: selection too large (%d bytes encoded, max %d)", len(encoded), osc52MaxPayload)
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("osc52: %w", err)
+	}
+	defer tty.Close()
+
+	_, err = tty.WriteString(osc52Sequence(encoded))
+	return err
+}
+
+// osc52Sequence builds the escape sequence for a base64 payload, wrapping it
+// for tmux or screen passthrough when running inside them
+func osc52Sequence(encoded string) string {
+	seq := "\x1b]52;c;" + encoded + "\a"
+
+	switch {
+	case getenv("TMUX") != "":
+		// tmux requires escapes inside the passthrough to be doubled
+		return "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	case getenv("STY") != "":
+		// screen limits the length of DCS strings, so send the sequence in chunks
+		var b strings.Builder
+		for i := 0; i < len(seq); i += screenChunkSize {
+			end := min(i+screenChunkSize, len(seq))
+			b.WriteString("\x1bP" + seq[i:end] + "\x1b\\")
+		}
+		return b.String()
+	}
+
+	return seq
+}
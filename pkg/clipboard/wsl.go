@@ -0,0 +1,76 @@
+package clipboard
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+// readFile is a variable so WSL detection can be tested
+var readFile = os.ReadFile
+
+// isWSL reports whether we are running inside Windows Subsystem for Linux
+func isWSL() bool {
+	if getenv("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+	version, err := readFile("/proc/version")
+	if err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(version)), "microsoft")
+}
+
+// wslBackend uses the Windows clipboard from inside WSL via clip.exe and
+// powershell.exe
+type wslBackend struct{}
+
+// Name returns the backend name
+func (wslBackend) Name() string {
+	return "clip.exe"
+}
+
+// Available reports whether we are in WSL and clip.exe is on the PATH
+func (wslBackend) Available() bool {
+	if !isWSL() {
+		return false
+	}
+	_, err := lookPath("clip.exe")
+	return err == nil
+}
+
+// Copy pipes text to clip.exe as UTF-16 with CRLF line endings, which it
+// needs to keep non-ASCII characters intact
+func (wslBackend) Copy(text string) error {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\n", "\r\n")
+
+	_, err := runCommand(encodeUTF16LE(text), "clip.exe")
+	if err != nil {
+		return fmt.Errorf("clip.exe: %w", err)
+	}
+	return nil
+}
+
+// Paste reads the Windows clipboard through PowerShell
+func (wslBackend) Paste() (string, error) {
+	output, err := runCommand("", "powershell.exe", "-NoProfile", "-NonInteractive", "-Command",
+		"[Console]::OutputEncoding = [System.Text.Encoding]::UTF8; Get-Clipboard -Raw")
+	if err != nil {
+		return "", fmt.Errorf("powershell.exe: %w", err)
+	}
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+	return strings.TrimRight(output, "\n"), nil
+}
+
+// encodeUTF16LE encodes text as UTF-16 little-endian with a byte order mark
+func encodeUTF16LE(text string) string {
+	units := utf16.Encode([]rune(text))
+	buf := make([]byte, 0, 2+len(units)*2)
+	buf = append(buf, 0xFF, 0xFE)
+	for _, unit := range units {
+		buf = append(buf, byte(unit), byte(unit>>8))
+	}
+	return string(buf)
+}